@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+
+	"github.com/richiejp/badger-cybertron-vector/server"
+	"github.com/richiejp/badger-cybertron-vector/vectorpb"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+// env is what a command runs against.
+type env struct {
+	store   *vectorstore.VectorStore
+	metrics prometheus.Gatherer
+	out     io.Writer
+	json    bool
+}
+
+type command func(ctx context.Context, e *env, args []string) error
+
+var commands = map[string]command{
+	"insert":      runInsert,
+	"insert-file": runInsertFile,
+	"search":      runSearch,
+	"similar":     runSimilar,
+	"stats":       runStats,
+	"dim-reduce":  runDimReduce,
+	"compact":     runCompact,
+	"backup":      runBackup,
+	"restore":     runRestore,
+	"serve":       runServe,
+	"demo":        runDemo,
+}
+
+// modelFree are the commands that never embed text, which main runs
+// without loading a model.
+var modelFree = map[string]bool{
+	"stats":   true,
+	"compact": true,
+	"backup":  true,
+	"restore": true,
+}
+
+var textChunks = []string{
+	"Hello, world!",
+	"The quick brown fox jumps over the lazy dog.",
+	"Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+	"Nulla facilisi. Sed ut imperdiet nunc.",
+	"Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Donec eget nunc.",
+	"Vivamus auctor, nunc nec lacinia tincidunt, nunc nunc fermentum nunc, nec fermentum nunc nunc nec nunc.",
+	"Error sit voluptatem accusantium doloremque laudantium, totam rem aperiam, eaque ipsa quae ab illo inventore veritatis et quasi architecto beatae vitae dicta sunt explicabo.",
+	"Error (2) Co-pilot, engage the hyperdrive!",
+	"Error (3) Co-pilot, engage the hyperdrive!",
+	"Error Co-pilot this is not sensible log messages!",
+}
+
+type insertOutput struct {
+	IDs []uint64 `json:"ids"`
+}
+
+type searchOutput struct {
+	ID        uint64  `json:"id"`
+	Score     float64 `json:"score"`
+	Relevance float64 `json:"relevance"`
+	Text      string  `json:"text"`
+}
+
+func runInsert(ctx context.Context, e *env, args []string) error {
+	text := strings.Join(args, " ")
+	if strings.TrimSpace(text) == "" {
+		return errors.New("usage: insert <text>")
+	}
+
+	id, err := e.store.Insert(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	return e.printIDs([]uint64{id})
+}
+
+func runInsertFile(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: insert-file <path>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var texts []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			texts = append(texts, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	ids, err := e.store.InsertBatch(ctx, texts, func(done, total int) {
+		log.Info().Msgf("Inserted %d/%d", done, total)
+	})
+	if err != nil {
+		return err
+	}
+
+	return e.printIDs(ids)
+}
+
+func runSearch(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	k := fs.Int("k", 5, "number of results")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	query := strings.Join(args, " ")
+	if strings.TrimSpace(query) == "" {
+		return errors.New("usage: search [-k n] <query>")
+	}
+
+	results, err := e.store.Nearest(ctx, query, *k)
+	if err != nil {
+		return err
+	}
+
+	return e.printResults(results)
+}
+
+func runSimilar(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("similar", flag.ContinueOnError)
+	k := fs.Int("k", 5, "number of results")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return errors.New("usage: similar [-k n] <id>")
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", args[0])
+	}
+
+	results, err := e.store.NearestByID(ctx, id, *k)
+	if err != nil {
+		return err
+	}
+
+	return e.printResults(results)
+}
+
+func runStats(ctx context.Context, e *env, args []string) error {
+	st, err := e.store.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	if e.json {
+		return json.NewEncoder(e.out).Encode(map[string]interface{}{
+			"documents": st.Documents,
+			"dimension": st.Dimension,
+			"dtype":     st.DType.String(),
+			"index":     st.Index.String(),
+			"lsm_size":  st.LSMSize,
+			"vlog_size": st.VLogSize,
+		})
+	}
+
+	tw := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "documents\t%d\n", st.Documents)
+	fmt.Fprintf(tw, "dimension\t%d\n", st.Dimension)
+	fmt.Fprintf(tw, "dtype\t%v\n", st.DType)
+	fmt.Fprintf(tw, "index\t%v\n", st.Index)
+	fmt.Fprintf(tw, "lsm size\t%d\n", st.LSMSize)
+	fmt.Fprintf(tw, "vlog size\t%d\n", st.VLogSize)
+	return tw.Flush()
+}
+
+func runDimReduce(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dim-reduce <components>")
+	}
+
+	components, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid number of components %q", args[0])
+	}
+
+	if err := e.store.FitPCA(ctx, components); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Reduced stored embeddings to %d dimensions", components)
+	return nil
+}
+
+func runCompact(ctx context.Context, e *env, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: compact")
+	}
+
+	if err := e.store.Compact(ctx); err != nil {
+		return err
+	}
+
+	log.Info().Msg("Compacted the database")
+	return nil
+}
+
+func runBackup(ctx context.Context, e *env, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: backup <path> [since]")
+	}
+
+	var since uint64
+	if len(args) == 2 {
+		var err error
+		if since, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+
+	version, err := e.store.Backup(f, since)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Backed up to %s, pass %d as since for an incremental backup", args[0], version)
+	return nil
+}
+
+func runRestore(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: restore <path>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := e.store.Restore(f); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Restored %s", args[0])
+	return nil
+}
+
+func runServe(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := fs.String("listen", "", "serve the HTTP API on this address")
+	grpcListen := fs.String("grpc-listen", "", "serve the gRPC API on this address")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) != 0 || *listen == "" && *grpcListen == "" {
+		return errors.New("serve needs -listen and/or -grpc-listen")
+	}
+
+	errs := make(chan error, 2)
+
+	var hs *http.Server
+	if *listen != "" {
+		hs = &http.Server{Addr: *listen, Handler: server.NewHTTPHandler(e.store).WithMetrics(e.metrics)}
+
+		log.Info().Msgf("Serving HTTP on %s", *listen)
+		go func() {
+			if err := hs.ListenAndServe(); err != http.ErrServerClosed {
+				errs <- err
+			}
+		}()
+	}
+
+	var gs *grpc.Server
+	if *grpcListen != "" {
+		lis, err := net.Listen("tcp", *grpcListen)
+		if err != nil {
+			return err
+		}
+
+		gs = grpc.NewServer()
+		vectorpb.RegisterVectorStoreServer(gs, server.NewGRPCServer(e.store))
+
+		log.Info().Msgf("Serving gRPC on %s", *grpcListen)
+		go func() {
+			errs <- gs.Serve(lis)
+		}()
+	}
+
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		log.Info().Msg("Shutting down")
+	}
+
+	// Let in-flight requests finish before the store is closed under them.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if hs != nil {
+		if serr := hs.Shutdown(shutdownCtx); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	if gs != nil {
+		gs.GracefulStop()
+	}
+
+	return err
+}
+
+func runDemo(ctx context.Context, e *env, args []string) error {
+	if _, err := e.store.InsertBatch(ctx, textChunks, nil); err != nil {
+		return err
+	}
+
+	results, err := e.store.Nearest(ctx, "A commonly used latin phrase as placeholder text", 3)
+	if err != nil {
+		return err
+	}
+
+	return e.printResults(results)
+}
+
+// parseArgs parses fs's flags wherever they are in args, where fs.Parse
+// stops at the first argument that isn't one, and returns the other
+// arguments in order. Everything after "--" is an argument.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		left := fs.Args()
+		if len(left) == 0 {
+			return rest, nil
+		}
+		if n := len(args) - len(left); n > 0 && args[n-1] == "--" {
+			return append(rest, left...), nil
+		}
+
+		rest, args = append(rest, left[0]), left[1:]
+	}
+}
+
+func (e *env) printIDs(ids []uint64) error {
+	if e.json {
+		return json.NewEncoder(e.out).Encode(insertOutput{IDs: ids})
+	}
+
+	for _, id := range ids {
+		if _, err := fmt.Fprintln(e.out, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *env) printResults(results []vectorstore.Result) error {
+	if e.json {
+		out := make([]searchOutput, len(results))
+		for i, r := range results {
+			out[i] = searchOutput{ID: r.ID, Score: r.Score, Relevance: r.Relevance, Text: r.Text}
+		}
+
+		return json.NewEncoder(e.out).Encode(out)
+	}
+
+	tw := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSCORE\tTEXT")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%.4f\t%s\n", r.ID, r.Score, r.Text)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// testEnv runs commands against a store embedding with e, writing JSON to
+// its buffer.
+func testEnv(t *testing.T, opts vectorstore.Options, e vectorstore.Embedder) (*env, *bytes.Buffer) {
+	t.Helper()
+
+	store, err := vectorstore.Open(opts, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	var out bytes.Buffer
+	return &env{store: store, out: &out, json: true}, &out
+}
+
+// memOptions are the options of an in-memory store.
+func memOptions() vectorstore.Options {
+	return vectorstore.DefaultOptions("").WithInMemory(true)
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		k    int
+		rest []string
+	}{
+		{[]string{"red", "apples"}, 5, []string{"red", "apples"}},
+		{[]string{"-k", "3", "red", "apples"}, 3, []string{"red", "apples"}},
+		{[]string{"red", "apples", "--k", "3"}, 3, []string{"red", "apples"}},
+		{[]string{"red", "-k=2", "apples"}, 2, []string{"red", "apples"}},
+		{[]string{"-k", "2", "--", "red", "-k", "3"}, 2, []string{"red", "-k", "3"}},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		k := fs.Int("k", 5, "")
+		rest, err := parseArgs(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", tt.args, err)
+			continue
+		}
+		if *k != tt.k || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("parseArgs(%q) = k %d, %q, want k %d, %q", tt.args, *k, rest, tt.k, tt.rest)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	if _, err := parseArgs(fs, []string{"red", "-unknown"}); err == nil {
+		t.Error("parseArgs accepted an unknown flag after an argument")
+	}
+}
+
+func TestInsertCommands(t *testing.T) {
+	ctx := context.Background()
+	e, out := testEnv(t, memOptions(), vectortest.NewHashEmbedder(16))
+
+	if err := runInsert(ctx, e, []string{"red", "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	var inserted insertOutput
+	if err := json.Unmarshal(out.Bytes(), &inserted); err != nil || len(inserted.IDs) != 1 {
+		t.Errorf("insert printed %s", out)
+	}
+
+	path := filepath.Join(t.TempDir(), "docs.txt")
+	if err := os.WriteFile(path, []byte("red pears\n\nblue sky\n  \ngreen grass\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runInsertFile(ctx, e, []string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &inserted); err != nil || len(inserted.IDs) != 3 {
+		t.Errorf("insert-file of 3 non-empty lines printed %s", out)
+	}
+
+	if err := runInsert(ctx, e, []string{" "}); err == nil {
+		t.Error("insert of blank text succeeded")
+	}
+	if err := runInsertFile(ctx, e, nil); err == nil {
+		t.Error("insert-file without a path succeeded")
+	}
+}
+
+func TestSearchCommand(t *testing.T) {
+	ctx := context.Background()
+	e, out := testEnv(t, memOptions(), vectortest.NewHashEmbedder(16))
+	for _, text := range []string{"red apples", "red pears", "blue sky"} {
+		if err := runInsert(ctx, e, []string{text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var results []searchOutput
+	for _, args := range [][]string{{"-k", "2", "red", "apples"}, {"red", "apples", "--k", "2"}} {
+		out.Reset()
+		if err := runSearch(ctx, e, args); err != nil {
+			t.Fatalf("search %q: %v", args, err)
+		}
+		results = nil
+		if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].Text != "red apples" {
+			t.Errorf("search %q = %s, want 2 results, red apples first", args, out)
+		}
+	}
+
+	out.Reset()
+	if err := runSimilar(ctx, e, []string{"1", "-k", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	results = nil
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Text != "red pears" {
+		t.Errorf("similar 1 -k 1 = %s, want red pears", out)
+	}
+	if err := runSimilar(ctx, e, []string{"1", "2"}); err == nil {
+		t.Error("similar with two IDs succeeded")
+	}
+
+	e.json = false
+	out.Reset()
+	if err := runSearch(ctx, e, []string{"-k", "1", "blue", "sky"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") || !strings.HasSuffix(lines[1], "blue sky") {
+		t.Errorf("search table = %q, want a header and blue sky", out)
+	}
+
+	if err := runSearch(ctx, e, nil); err == nil {
+		t.Error("search without a query succeeded")
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	ctx := context.Background()
+	e, out := testEnv(t, memOptions(), vectortest.NewHashEmbedder(16))
+	if err := runInsert(ctx, e, []string{"red apples"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if err := runStats(ctx, e, nil); err != nil {
+		t.Fatal(err)
+	}
+	var st map[string]any
+	if err := json.Unmarshal(out.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st["documents"] != 1.0 || st["dimension"] != 16.0 || st["index"] != "flat" {
+		t.Errorf("stats = %v, want 1 document of 16 dimensions in a flat index", st)
+	}
+
+	e.json = false
+	out.Reset()
+	if err := runStats(ctx, e, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "documents  1") {
+		t.Errorf("stats table = %q", out)
+	}
+}
+
+// TestModelFreeCommands runs the commands main runs without a model on a
+// store whose embedder has none.
+func TestModelFreeCommands(t *testing.T) {
+	ctx := context.Background()
+	opts := vectorstore.DefaultOptions(t.TempDir()).WithGCInterval(0)
+
+	full, _ := testEnv(t, opts, vectortest.NewHashEmbedder(16))
+	if err := runInsert(ctx, full, []string{"red apples"}); err != nil {
+		t.Fatal(err)
+	}
+	full.store.Close()
+
+	e, out := testEnv(t, opts, vectorstore.NewCybertronEmbedder(nil))
+	backup := filepath.Join(t.TempDir(), "backup")
+	for _, args := range [][]string{{"stats"}, {"compact"}, {"backup", backup}, {"restore", backup}} {
+		if !modelFree[args[0]] {
+			t.Errorf("%s isn't model free", args[0])
+		}
+		if err := commands[args[0]](ctx, e, args[1:]); err != nil {
+			t.Errorf("%s without a model: %v", args[0], err)
+		}
+	}
+
+	var st map[string]any
+	if err := json.NewDecoder(out).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st["documents"] != 1.0 || st["dimension"] != 16.0 {
+		t.Errorf("stats = %v, want 1 document of 16 dimensions", st)
+	}
+}
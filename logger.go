@@ -0,0 +1,13 @@
+package main
+
+import "github.com/rs/zerolog"
+
+// zerologLogger passes the vector store's log messages on to zerolog.
+type zerologLogger struct {
+	log zerolog.Logger
+}
+
+func (l zerologLogger) Debug(msg string, args ...any) { l.log.Debug().Fields(args).Msg(msg) }
+func (l zerologLogger) Info(msg string, args ...any)  { l.log.Info().Fields(args).Msg(msg) }
+func (l zerologLogger) Warn(msg string, args ...any)  { l.log.Warn().Fields(args).Msg(msg) }
+func (l zerologLogger) Error(msg string, args ...any) { l.log.Error().Fields(args).Msg(msg) }
@@ -1,251 +1,162 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
+	"flag"
+	"fmt"
 	"os"
-	"sort"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/nlpodyssey/cybertron/pkg/models/bert"
 	"github.com/nlpodyssey/cybertron/pkg/tasks"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
-	badger "github.com/dgraph-io/badger/v4"
+	"github.com/richiejp/badger-cybertron-vector/metrics"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
 )
 
-type badgerLogger struct {
-	log zerolog.Logger
-}
+const usage = `Usage: %s [flags] <command> [args]
+
+Commands:
+  insert <text>         embed and store a document
+  insert-file <path>    store each non-empty line of a file as a document
+  search [-k n] <query> print the documents nearest to query
+  similar [-k n] <id>   print the documents nearest to a stored one
+  stats                 print the number of documents and index settings
+  dim-reduce <n>        project stored embeddings onto n principal components
+  compact               merge the database and reclaim space from deletions
+  backup <path> [since] write the database, or what changed after version
+                        since, to a file
+  restore <path>        load a file written by backup
+  serve                 serve the HTTP and/or gRPC API
+  demo                  insert some sample text and search it
+
+Flags:
+`
+
+// shutdownTimeout bounds how long in-flight work may delay exit after the
+// command returns or a signal arrives.
+const shutdownTimeout = 30 * time.Second
 
-func (l *badgerLogger) Errorf(f string, v ...interface{}) {
-	l.log.Error().Msgf(f, v...)
-}
+func main() {
+	dbDir := flag.String("db", "./badger.db", "directory holding the Badger database")
+	modelsDir := flag.String("models", "./models", "directory to load models from")
+	normalized := flag.Bool("normalize", false, "L2-normalize embeddings before storing them")
+	dtype := flag.String("dtype", "float64", "element type to store embeddings as (float64, float32, float16, int8, sparse)")
+	pooling := flag.String("pooling", "mean", "how to pool BERT token outputs into an embedding (mean, cls, max)")
+	concurrency := flag.Int("concurrency", 1, "number of model instances to encode with in parallel")
+	output := flag.String("output", "table", "output format (table, json)")
+	readOnly := flag.Bool("read-only", false, "open the database for queries only")
+	syncWrites := flag.Bool("sync-writes", false, "sync every write to disk before it returns")
+	storeNorms := flag.Bool("store-norms", false, "store each embedding's norm to speed up cosine search without -normalize")
+	longText := flag.String("long-text", "reject", "what to do with text longer than the model takes (reject, warn, chunk, average)")
+	longTextWeighted := flag.Bool("long-text-weighted", false, "weight the parts averaged under -long-text average by their length")
+	preprocess := flag.String("preprocess", "", "comma separated steps to apply to text before embedding it (lowercase, nfc, collapse)")
+	maxK := flag.Int("max-k", 1000, "most results a search may ask for, 0 for no limit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-func (l *badgerLogger) Warningf(f string, v ...interface{}) {
-	l.log.Warn().Msgf(f, v...)
-}
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
-func (l *badgerLogger) Infof(f string, v ...interface{}) {
-	l.log.Info().Msgf(f, v...)
-}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
 
-func (l *badgerLogger) Debugf(f string, v ...interface{}) {
-	l.log.Debug().Msgf(f, v...)
-}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		log.Fatal().Msgf("Unknown command %q", flag.Arg(0))
+	}
 
-var textChunks = []string{
-	"Hello, world!",
-	"The quick brown fox jumps over the lazy dog.",
-	"Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
-	"Nulla facilisi. Sed ut imperdiet nunc.",
-	"Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Donec eget nunc.",
-	"Vivamus auctor, nunc nec lacinia tincidunt, nunc nunc fermentum nunc, nec fermentum nunc nunc nec nunc.",
-	"Error sit voluptatem accusantium doloremque laudantium, totam rem aperiam, eaque ipsa quae ab illo inventore veritatis et quasi architecto beatae vitae dicta sunt explicabo.",
-	"Error (2) Co-pilot, engage the hyperdrive!",
-	"Error (3) Co-pilot, engage the hyperdrive!",
-	"Error Co-pilot this is not sensible log messages!",
-}
+	if *output != "table" && *output != "json" {
+		log.Fatal().Msgf("Invalid -output %q", *output)
+	}
 
-func getEmbedding(m textencoding.Interface, text string) ([]float64, error) {
-	result, err := m.Encode(context.Background(), text, int(bert.MeanPooling))
+	storageType, err := vectorstore.ParseDType(*dtype)
 	if err != nil {
-		return nil, err
+		log.Fatal().Err(err).Msgf("Invalid -dtype")
 	}
 
-	return result.Vector.Data().F64(), nil
-}
-
-func makeEmbeddings(db *badger.DB, m textencoding.Interface) error {
-	if err := db.Update(func(txn *badger.Txn) error {
-
-		fn := func(text string) error {
-			embedding, err := getEmbedding(m, text)
-			if err != nil {
-				return err
-			}
-
-			buf := bytes.NewBuffer(make([]byte, 0, len(embedding)*8))
-			if err := binary.Write(buf, binary.LittleEndian, embedding); err != nil {
-				return err
-			}
-
-			return txn.Set(buf.Bytes(), []byte(text))
-		}
-
-		for _, chunk := range textChunks {
-			if err := fn(chunk); err != nil {
-				return err
-			}
-		}
-
-		return nil
-	}); err != nil {
-		return err
+	poolingMode, err := vectorstore.ParsePooling(*pooling)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Invalid -pooling")
 	}
 
-	return db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
-
-		for it.Rewind(); it.Valid(); it.Next() {
-			item := it.Item()
-
-			var valCopy []byte
-			if err := item.Value(func(val []byte) error {
-				valCopy = append([]byte{}, val...)
-				return nil
-			}); err != nil {
-				return err
-			}
-
-			buf := bytes.NewBuffer(item.KeyCopy(nil))
-			key := make([]float64, buf.Len()/8)
-			if err := binary.Read(buf, binary.LittleEndian, key); err != nil {
-				return err
-			}
-
-			log.Info().Msgf("Inserted key[:3]=%v, value=%s", key[:3], valCopy)
-		}
-
-		return nil
-	})
-
-}
-
-type Ranked struct {
-	Rank float64
-	Key  []float64
-}
-
-func cosineSimilarity(a, b []float64) float64 {
-	dotProduct := 0.0
-	magnitudeA := 0.0
-	magnitudeB := 0.0
-
-	for i := range a {
-		dotProduct += a[i] * b[i]
-		magnitudeA += a[i] * a[i]
-		magnitudeB += b[i] * b[i]
+	longTextPolicy, err := vectorstore.ParseLongTextPolicy(*longText)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Invalid -long-text")
 	}
 
-	magnitudeA = 1.0 / (magnitudeA * magnitudeB)
-	magnitudeB = 1.0 / (magnitudeA * magnitudeB)
-
-	return dotProduct * magnitudeA * magnitudeB
-}
-
-func rankNearest(db *badger.DB, m textencoding.Interface, query string) error {
-	target, err := getEmbedding(m, query)
+	preprocessor, err := vectorstore.ParsePreprocessor(*preprocess)
 	if err != nil {
-		return err
+		log.Fatal().Err(err).Msgf("Invalid -preprocess")
 	}
 
-	ranked := make([]Ranked, 0, len(textChunks))
-
-	if err := db.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		opts.PrefetchValues = false
-		it := txn.NewIterator(opts)
-		defer it.Close()
-
-		for it.Rewind(); it.Valid(); it.Next() {
-			item := it.Item()
-
-			buf := bytes.NewBuffer(item.KeyCopy(nil))
-			key := make([]float64, buf.Len()/8)
-			if err := binary.Read(buf, binary.LittleEndian, key); err != nil {
-				return err
-			}
-
-			ranked = append(ranked, Ranked{
-				Rank: cosineSimilarity(target, key),
-				Key:  key,
+	// Without a model, the embedder fails any text it's given, which the
+	// model free commands never give it.
+	var m textencoding.Interface
+	if !modelFree[flag.Arg(0)] {
+		models := make([]textencoding.Interface, max(*concurrency, 1))
+		for i := range models {
+			models[i], err = tasks.Load[textencoding.Interface](&tasks.Config{
+				ModelsDir: *modelsDir,
+				ModelName: textencoding.DefaultModel,
 			})
+			if err != nil {
+				log.Fatal().Err(err).Msgf("Error loading model")
+			}
 		}
-
-		return nil
-	}); err != nil {
-		return err
-	}
-
-	sort.Slice(ranked, func(i, j int) bool {
-		return ranked[i].Rank > ranked[j].Rank
-	})
-
-	var nearest []byte
-	if err := db.View(func(txn *badger.Txn) error {
-		buf := bytes.NewBuffer(make([]byte, 0, len(target)*8))
-		err := binary.Write(buf, binary.LittleEndian, ranked[0].Key)
-		if err != nil {
-			return err
-		}
-
-		item, err := txn.Get(buf.Bytes())
-		if err != nil {
-			return err
-		}
-
-		if err := item.Value(func(val []byte) error {
-			nearest = append([]byte{}, val...)
-			return nil
-		}); err != nil {
-			return err
-		}
-
-		return nil
-	}); err != nil {
-		return err
+		m = vectorstore.NewModelPool(models...)
 	}
 
-	log.Info().Msgf("Nearest to %s: %s", query, nearest)
-	for i, r := range ranked {
-		log.Info().Msgf("Rank %d: %f %v", i, r.Rank, r.Key[:4])
+	reg := prometheus.NewRegistry()
+	storeMetrics, err := metrics.NewPrometheus(reg)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Error registering metrics")
 	}
 
-	return nil
-}
-
-func main() {
-	zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
-
-	db, err := badger.Open(badger.DefaultOptions("./badger.db").WithLogger(&badgerLogger{log: log.Logger.With().Str("pkg", "badger").Logger()}))
+	opts := vectorstore.DefaultOptions(*dbDir).
+		WithNormalize(*normalized).
+		WithModelName(textencoding.DefaultModel).
+		WithDType(storageType).
+		WithConcurrency(max(*concurrency, 1)).
+		WithMetrics(storeMetrics).
+		WithReadOnly(*readOnly).
+		WithSyncWrites(*syncWrites).
+		WithStoreNorms(*storeNorms).
+		WithLongText(longTextPolicy).
+		WithLongTextWeighted(*longTextWeighted).
+		WithMaxK(*maxK).
+		WithPreprocessor(preprocessor).
+		WithLogger(zerologLogger{log: log.Logger})
+
+	store, err := vectorstore.Open(opts, vectorstore.NewCybertronEmbedder(m).WithPooling(poolingMode))
 	if err != nil {
-		log.Error().Err(err).Msgf("Error opening Badger database")
+		log.Fatal().Err(err).Msgf("Error opening vector store")
 	}
-	defer db.Close()
 
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	go func() {
-		for range ticker.C {
-		again:
-			err := db.RunValueLogGC(0.7)
-			if err == nil {
-				goto again
-			}
-		}
-	}()
+	env := &env{store: store, metrics: reg, out: os.Stdout, json: *output == "json"}
+	err = cmd(ctx, env, flag.Args()[1:])
 
-	m, err := tasks.Load[textencoding.Interface](&tasks.Config{
-		ModelsDir: "./models",
-		ModelName: textencoding.DefaultModel,
-	})
-	if err != nil {
-		log.Fatal().Err(err).Msgf("Error loading model")
-	}
-
-	if err := makeEmbeddings(db, m); err != nil {
-		log.Fatal().Err(err).Msgf("Error making embeddings")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if serr := store.Shutdown(shutdownCtx); serr != nil {
+		log.Error().Err(serr).Msg("Error closing vector store")
 	}
 
-	if err := rankNearest(db, m, "A commonly used latin phrase as placeholder text"); err != nil {
-		log.Fatal().Err(err).Msgf("Error ranking nearest")
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Error running %s", flag.Arg(0))
 	}
 }
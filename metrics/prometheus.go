@@ -0,0 +1,68 @@
+// Package metrics records VectorStore measurements in Prometheus.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "vectorstore"
+
+// Prometheus implements vectorstore.Metrics with counters for inserted
+// documents and searches, and histograms of encode and search durations.
+type Prometheus struct {
+	inserts        prometheus.Counter
+	searches       prometheus.Counter
+	encodeDuration prometheus.Histogram
+	searchDuration prometheus.Histogram
+}
+
+// NewPrometheus creates the collectors and registers them with reg.
+func NewPrometheus(reg prometheus.Registerer) (*Prometheus, error) {
+	p := &Prometheus{
+		inserts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "inserted_documents_total",
+			Help:      "Documents written to the store.",
+		}),
+		searches: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "searches_total",
+			Help:      "Searches run against the store.",
+		}),
+		encodeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "encode_duration_seconds",
+			Help:      "Time taken to embed one text, excluding cache hits.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}),
+		searchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "search_duration_seconds",
+			Help:      "Time taken by a search, including encoding the query.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{p.inserts, p.searches, p.encodeDuration, p.searchDuration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Prometheus) Inserted(n int) {
+	p.inserts.Add(float64(n))
+}
+
+func (p *Prometheus) Encoded(d time.Duration) {
+	p.encodeDuration.Observe(d.Seconds())
+}
+
+func (p *Prometheus) Searched(d time.Duration) {
+	p.searches.Inc()
+	p.searchDuration.Observe(d.Seconds())
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p, err := NewPrometheus(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Inserted(3)
+	p.Inserted(2)
+	p.Searched(10 * time.Millisecond)
+	p.Encoded(time.Millisecond)
+
+	if got := testutil.ToFloat64(p.inserts); got != 5 {
+		t.Errorf("inserted documents = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(p.searches); got != 1 {
+		t.Errorf("searches = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(reg); n != 4 {
+		t.Errorf("registry collects %d metrics, want 4", n)
+	}
+
+	if _, err := NewPrometheus(reg); err == nil {
+		t.Error("registering the collectors twice succeeded")
+	}
+}
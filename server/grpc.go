@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorpb"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+// GRPCServer implements vectorpb.VectorStoreServer on top of a store.
+type GRPCServer struct {
+	vectorpb.UnimplementedVectorStoreServer
+
+	store *vectorstore.VectorStore
+}
+
+func NewGRPCServer(store *vectorstore.VectorStore) *GRPCServer {
+	return &GRPCServer{store: store}
+}
+
+func (g *GRPCServer) Insert(ctx context.Context, req *vectorpb.InsertRequest) (*vectorpb.InsertResponse, error) {
+	if strings.TrimSpace(req.GetText()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "text must not be empty")
+	}
+
+	id, err := g.store.InsertWithMetadata(ctx, req.GetText(), req.GetMetadata())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &vectorpb.InsertResponse{Id: id}, nil
+}
+
+func (g *GRPCServer) Search(ctx context.Context, req *vectorpb.SearchRequest) (*vectorpb.SearchResponse, error) {
+	if strings.TrimSpace(req.GetQuery()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query must not be empty")
+	}
+
+	k := int(req.GetK())
+	if k == 0 {
+		k = defaultK
+	}
+
+	p := vectorstore.SearchParams{K: k, Fields: vectorstore.FieldText | vectorstore.FieldMetadata}
+	if req.GetMetric() != vectorpb.Metric_METRIC_UNSPECIFIED {
+		metric, err := metricFromProto(req.GetMetric())
+		if err != nil {
+			return nil, err
+		}
+		p.Metric = metric
+	}
+
+	results, err := g.store.Search(ctx, req.GetQuery(), p)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &vectorpb.SearchResponse{Results: make([]*vectorpb.SearchResult, len(results))}
+	for i, r := range results {
+		resp.Results[i] = &vectorpb.SearchResult{Id: r.ID, Text: r.Text, Score: r.Score, Metadata: r.Metadata, Parent: r.Parent, CreatedAt: r.CreatedAt, Relevance: r.Relevance}
+	}
+
+	return resp, nil
+}
+
+// grpcError maps an error from the store to a status error whose code
+// matches the HTTP status statusFor gives it.
+func grpcError(err error) error {
+	var code codes.Code
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, vectorstore.ErrTextTooLong), errors.Is(err, vectorstore.ErrEmptyQuery),
+		errors.Is(err, vectorstore.ErrEmptyText), errors.Is(err, vectorstore.ErrKTooLarge),
+		errors.Is(err, vectorstore.ErrInvalidParams), errors.Is(err, vectorstore.ErrDimensionMismatch):
+		code = codes.InvalidArgument
+	case errors.Is(err, vectorstore.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, vectorstore.ErrReadOnly):
+		code = codes.FailedPrecondition
+	case errors.Is(err, vectorstore.ErrClosed), errors.Is(err, vectorstore.ErrNotReady):
+		code = codes.Unavailable
+	default:
+		code = codes.Internal
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func metricFromProto(m vectorpb.Metric) (vectorstore.DistanceMetric, error) {
+	switch m {
+	case vectorpb.Metric_METRIC_COSINE:
+		return vectorstore.CosineSimilarity{}, nil
+	case vectorpb.Metric_METRIC_COSINE_DISTANCE:
+		return vectorstore.CosineDistance{}, nil
+	case vectorpb.Metric_METRIC_DOT_PRODUCT:
+		return vectorstore.DotProduct{}, nil
+	case vectorpb.Metric_METRIC_EUCLIDEAN:
+		return vectorstore.EuclideanDistance{}, nil
+	case vectorpb.Metric_METRIC_SQUARED_EUCLIDEAN:
+		return vectorstore.SquaredEuclideanDistance{}, nil
+	case vectorpb.Metric_METRIC_MANHATTAN:
+		return vectorstore.ManhattanDistance{}, nil
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown metric %v", m)
+	}
+}
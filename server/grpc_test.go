@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorpb"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+func TestGRPC(t *testing.T) {
+	ctx := context.Background()
+	g := NewGRPCServer(openStore(t, memOptions()))
+
+	if _, err := g.Insert(ctx, &vectorpb.InsertRequest{Text: "red apples", Metadata: map[string]string{"colour": "red"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for m := range vectorpb.Metric_name {
+		resp, err := g.Search(ctx, &vectorpb.SearchRequest{Query: "red apples", K: 1, Metric: vectorpb.Metric(m)})
+		if err != nil {
+			t.Fatalf("%v: %v", vectorpb.Metric(m), err)
+		}
+		if len(resp.Results) != 1 || resp.Results[0].Text != "red apples" || resp.Results[0].Metadata["colour"] != "red" {
+			t.Errorf("%v: results = %v", vectorpb.Metric(m), resp.Results)
+		} else if rel := resp.Results[0].Relevance; rel <= 0 || rel > 1 {
+			t.Errorf("%v: relevance = %v, want within (0, 1]", vectorpb.Metric(m), rel)
+		}
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	ctx := context.Background()
+	g := NewGRPCServer(openStore(t, memOptions().WithMaxK(2)))
+
+	check := func(name string, err error, want codes.Code) {
+		t.Helper()
+		if got := status.Code(err); got != want {
+			t.Errorf("%s returned %v, want %v", name, err, want)
+		}
+	}
+
+	_, err := g.Insert(ctx, &vectorpb.InsertRequest{Text: "one two three four five six seven eight nine"})
+	check("Insert of long text", err, codes.InvalidArgument)
+	_, err = g.Insert(ctx, &vectorpb.InsertRequest{Text: " "})
+	check("Insert of empty text", err, codes.InvalidArgument)
+	_, err = g.Search(ctx, &vectorpb.SearchRequest{Query: "red", K: 3})
+	check("Search beyond MaxK", err, codes.InvalidArgument)
+	_, err = g.Search(ctx, &vectorpb.SearchRequest{Query: "red", Metric: vectorpb.Metric(99)})
+	check("Search with an unknown metric", err, codes.InvalidArgument)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = g.Search(cancelled, &vectorpb.SearchRequest{Query: "red", K: 1})
+	check("Search with a cancelled context", err, codes.Canceled)
+}
+
+func TestGRPCReadOnly(t *testing.T) {
+	opts := vectorstore.DefaultOptions(t.TempDir()).WithGCInterval(0)
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	g := NewGRPCServer(openStore(t, opts.WithReadOnly(true)))
+	if _, err := g.Insert(context.Background(), &vectorpb.InsertRequest{Text: "red apples"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Insert to a read-only store returned %v, want FailedPrecondition", err)
+	}
+}
+
+func TestGRPCClosed(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	g := NewGRPCServer(s)
+	s.Close()
+
+	if _, err := g.Insert(ctx, &vectorpb.InsertRequest{Text: "red apples"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Insert to a closed store returned %v, want Unavailable", err)
+	}
+	if _, err := g.Search(ctx, &vectorpb.SearchRequest{Query: "red"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Search of a closed store returned %v, want Unavailable", err)
+	}
+}
+
+// dial serves a GRPCServer for store over an in-memory listener and
+// returns a client connected to it. Both are stopped when the test ends.
+func dial(t *testing.T, store *vectorstore.VectorStore) vectorpb.VectorStoreClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	vectorpb.RegisterVectorStoreServer(gs, NewGRPCServer(store))
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return vectorpb.NewVectorStoreClient(conn)
+}
+
+func TestGRPCIntegration(t *testing.T) {
+	ctx := context.Background()
+	c := dial(t, openStore(t, memOptions()))
+
+	var ids []uint64
+	for _, text := range []string{"red apples", "blue sky", "green grass"} {
+		resp, err := c.Insert(ctx, &vectorpb.InsertRequest{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, resp.Id)
+	}
+
+	for m := range vectorpb.Metric_name {
+		resp, err := c.Search(ctx, &vectorpb.SearchRequest{Query: "blue sky", K: 2, Metric: vectorpb.Metric(m)})
+		if err != nil {
+			t.Fatalf("%v: %v", vectorpb.Metric(m), err)
+		}
+		if len(resp.Results) != 2 || resp.Results[0].Id != ids[1] || resp.Results[0].Text != "blue sky" {
+			t.Errorf("%v: results = %v", vectorpb.Metric(m), resp.Results)
+		}
+		for _, r := range resp.Results {
+			if r.Relevance <= 0 || r.Relevance > 1 {
+				t.Errorf("%v: relevance %v is outside (0, 1]", vectorpb.Metric(m), r.Relevance)
+			}
+		}
+	}
+
+	if _, err := c.Insert(ctx, &vectorpb.InsertRequest{Text: " "}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Insert of empty text returned %v, want InvalidArgument", err)
+	}
+	if _, err := c.Search(ctx, &vectorpb.SearchRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Search without a query returned %v, want InvalidArgument", err)
+	}
+	if _, err := c.Search(ctx, &vectorpb.SearchRequest{Query: "red", Metric: vectorpb.Metric(99)}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Search with an unknown metric returned %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{vectorstore.ErrDimensionMismatch, codes.InvalidArgument},
+		{vectorstore.ErrEmptyQuery, codes.InvalidArgument},
+		{vectorstore.ErrInvalidParams, codes.InvalidArgument},
+		{vectorstore.ErrNotFound, codes.NotFound},
+		{vectorstore.ErrNotReady, codes.Unavailable},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{vectorstore.ErrCorruptRecord, codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := status.Code(grpcError(fmt.Errorf("wrapped: %w", tt.err))); got != tt.want {
+			t.Errorf("grpcError(%v) has code %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package server exposes a VectorStore over the network.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+const defaultK = 5
+
+type insertRequest struct {
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type insertResponse struct {
+	ID uint64 `json:"id"`
+}
+
+type searchResult struct {
+	ID        uint64            `json:"id"`
+	Text      string            `json:"text"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Parent    uint64            `json:"parent,omitempty"`
+	CreatedAt int64             `json:"created_at,omitempty"`
+	Score     float64           `json:"score"`
+	Relevance float64           `json:"relevance"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// HTTPHandler serves POST /documents, GET /search and GET /healthz on top
+// of a store.
+type HTTPHandler struct {
+	store *vectorstore.VectorStore
+	mux   *http.ServeMux
+}
+
+func NewHTTPHandler(store *vectorstore.VectorStore) *HTTPHandler {
+	h := &HTTPHandler{
+		store: store,
+		mux:   http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("/documents", h.documents)
+	h.mux.HandleFunc("/search", h.search)
+	h.mux.HandleFunc("/healthz", h.healthz)
+
+	return h
+}
+
+// WithMetrics serves the metrics gathered by g on GET /metrics.
+func (h *HTTPHandler) WithMetrics(g prometheus.Gatherer) *HTTPHandler {
+	h.mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	return h
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *HTTPHandler) documents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req insertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, "text must not be empty")
+		return
+	}
+
+	id, err := h.store.InsertWithMetadata(r.Context(), req.Text, req.Metadata)
+	if err != nil {
+		writeError(w, statusFor(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, insertResponse{ID: id})
+}
+
+func (h *HTTPHandler) search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+
+	query := q.Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeError(w, http.StatusBadRequest, "query parameter q must not be empty")
+		return
+	}
+
+	k := defaultK
+	if s := q.Get("k"); s != "" {
+		var err error
+		if k, err = strconv.Atoi(s); err != nil || k < 1 {
+			writeError(w, http.StatusBadRequest, "query parameter k must be a positive integer")
+			return
+		}
+	}
+
+	p := vectorstore.SearchParams{K: k, Fields: vectorstore.FieldText | vectorstore.FieldMetadata}
+	if s := q.Get("metric"); s != "" {
+		metric, ok := metricFromName(s)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "query parameter metric must be one of cosine, cosine_distance, dot_product, euclidean, squared_euclidean or manhattan")
+			return
+		}
+		p.Metric = metric
+	}
+
+	results, err := h.store.Search(r.Context(), query, p)
+	if err != nil {
+		writeError(w, statusFor(err), err.Error())
+		return
+	}
+
+	resp := make([]searchResult, len(results))
+	for i, res := range results {
+		resp[i] = searchResult{ID: res.ID, Text: res.Text, Metadata: res.Metadata, Parent: res.Parent, CreatedAt: res.CreatedAt, Score: res.Score, Relevance: res.Relevance}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// metricFromName maps the metric query parameter of GET /search, named as
+// in the gRPC Metric enum, to its metric.
+func metricFromName(name string) (vectorstore.DistanceMetric, bool) {
+	switch name {
+	case "cosine":
+		return vectorstore.CosineSimilarity{}, true
+	case "cosine_distance":
+		return vectorstore.CosineDistance{}, true
+	case "dot_product":
+		return vectorstore.DotProduct{}, true
+	case "euclidean":
+		return vectorstore.EuclideanDistance{}, true
+	case "squared_euclidean":
+		return vectorstore.SquaredEuclideanDistance{}, true
+	case "manhattan":
+		return vectorstore.ManhattanDistance{}, true
+	default:
+		return nil, false
+	}
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthz responds 200 once the store is ready and 503 otherwise, for load
+// balancer readiness checks.
+func (h *HTTPHandler) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if err := h.store.Ready(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// statusClientClosedRequest is nginx's status for a request the client
+// gave up on, which net/http has no constant for.
+const statusClientClosedRequest = 499
+
+// statusFor maps an error from the store to the HTTP status it is
+// reported with.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	case errors.Is(err, vectorstore.ErrTextTooLong):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, vectorstore.ErrEmptyQuery), errors.Is(err, vectorstore.ErrEmptyText),
+		errors.Is(err, vectorstore.ErrKTooLarge), errors.Is(err, vectorstore.ErrInvalidParams),
+		errors.Is(err, vectorstore.ErrDimensionMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, vectorstore.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, vectorstore.ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, vectorstore.ErrClosed), errors.Is(err, vectorstore.ErrNotReady):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+const testDim = 16
+
+// wordLimitedEmbedder is a HashEmbedder that takes at most max words.
+type wordLimitedEmbedder struct {
+	*vectortest.HashEmbedder
+	max int
+}
+
+func (e wordLimitedEmbedder) MaxTokens() int { return e.max }
+
+func (e wordLimitedEmbedder) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+// openStore opens an in-memory store with opts, closed when the test ends.
+func openStore(t *testing.T, opts vectorstore.Options) *vectorstore.VectorStore {
+	t.Helper()
+
+	s, err := vectorstore.Open(opts, wordLimitedEmbedder{vectortest.NewHashEmbedder(testDim), 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func memOptions() vectorstore.Options {
+	return vectorstore.DefaultOptions("").WithInMemory(true)
+}
+
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	return w
+}
+
+func TestHTTP(t *testing.T) {
+	h := NewHTTPHandler(openStore(t, memOptions()))
+
+	for _, body := range []string{`{"text": "red apples", "metadata": {"colour": "red"}}`, `{"text": "blue sky"}`} {
+		w := serve(h, http.MethodPost, "/documents", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST /documents = %d: %s", w.Code, w.Body)
+		}
+		var resp insertResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.ID == 0 {
+			t.Errorf("POST /documents = %s", w.Body)
+		}
+	}
+
+	w := serve(h, http.MethodGet, "/search?q=red+apples&k=1&metric=euclidean", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /search = %d: %s", w.Code, w.Body)
+	}
+	var results []searchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Text != "red apples" || results[0].ID != 1 || results[0].Metadata["colour"] != "red" || results[0].Relevance <= 0 {
+		t.Errorf("GET /search = %s", w.Body)
+	}
+
+	if w := serve(h, http.MethodGet, "/healthz", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /healthz = %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHealthzNotReady(t *testing.T) {
+	s, err := vectorstore.Open(vectorstore.DefaultOptions(t.TempDir()), vectorstore.NewCybertronEmbedder(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	w := serve(NewHTTPHandler(s), http.MethodGet, "/healthz", "")
+	var resp healthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusServiceUnavailable || resp.Status != "unavailable" || resp.Error == "" {
+		t.Errorf("GET /healthz without a model = %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	h := NewHTTPHandler(openStore(t, memOptions().WithMaxK(2)))
+
+	tests := []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodPost, "/documents", `{"text": "one two three four five six seven eight nine"}`, http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "/documents", `{"text": " "}`, http.StatusBadRequest},
+		{http.MethodPost, "/documents", `{`, http.StatusBadRequest},
+		{http.MethodGet, "/documents", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/search?q=red&k=3", "", http.StatusBadRequest},
+		{http.MethodGet, "/search?q=red&k=0", "", http.StatusBadRequest},
+		{http.MethodGet, "/search?q=red&k=many", "", http.StatusBadRequest},
+		{http.MethodGet, "/search?q=red&metric=hamming", "", http.StatusBadRequest},
+		{http.MethodGet, "/search", "", http.StatusBadRequest},
+		{http.MethodPost, "/search?q=red", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if w := serve(h, tt.method, tt.target, tt.body); w.Code != tt.want {
+			t.Errorf("%s %s %s = %d, want %d: %s", tt.method, tt.target, tt.body, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestHTTPMaxK(t *testing.T) {
+	h := NewHTTPHandler(openStore(t, memOptions().WithMaxK(2)))
+
+	if w := serve(h, http.MethodGet, "/search?q=red&k=3", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("GET /search with k above MaxK = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+	if w := serve(h, http.MethodGet, "/search?q=red&k=2", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /search with k at MaxK = %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHTTPReadOnly(t *testing.T) {
+	opts := vectorstore.DefaultOptions(t.TempDir()).WithGCInterval(0)
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	h := NewHTTPHandler(openStore(t, opts.WithReadOnly(true)))
+	if w := serve(h, http.MethodPost, "/documents", `{"text": "red apples"}`); w.Code != http.StatusForbidden {
+		t.Errorf("POST /documents to a read-only store = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+	}
+}
+
+func TestHTTPClosed(t *testing.T) {
+	s := openStore(t, memOptions())
+	h := NewHTTPHandler(s)
+	s.Close()
+
+	for _, req := range [][2]string{{http.MethodPost, "/documents"}, {http.MethodGet, "/search?q=red"}, {http.MethodGet, "/healthz"}} {
+		if w := serve(h, req[0], req[1], `{"text": "red apples"}`); w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s %s on a closed store = %d, want %d: %s", req[0], req[1], w.Code, http.StatusServiceUnavailable, w.Body)
+		}
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{vectorstore.ErrTextTooLong, http.StatusRequestEntityTooLarge},
+		{vectorstore.ErrDimensionMismatch, http.StatusBadRequest},
+		{vectorstore.ErrEmptyQuery, http.StatusBadRequest},
+		{vectorstore.ErrEmptyText, http.StatusBadRequest},
+		{vectorstore.ErrKTooLarge, http.StatusBadRequest},
+		{vectorstore.ErrInvalidParams, http.StatusBadRequest},
+		{vectorstore.ErrNotFound, http.StatusNotFound},
+		{vectorstore.ErrReadOnly, http.StatusForbidden},
+		{vectorstore.ErrClosed, http.StatusServiceUnavailable},
+		{vectorstore.ErrNotReady, http.StatusServiceUnavailable},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{context.Canceled, statusClientClosedRequest},
+		{vectorstore.ErrCorruptRecord, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := statusFor(fmt.Errorf("wrapped: %w", tt.err)); got != tt.want {
+			t.Errorf("statusFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
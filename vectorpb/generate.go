@@ -0,0 +1,4 @@
+// Package vectorpb holds the gRPC API definition and its generated stubs.
+package vectorpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative vectorstore.proto
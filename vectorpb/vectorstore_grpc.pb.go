@@ -0,0 +1,146 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: vectorstore.proto
+
+package vectorpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	VectorStore_Insert_FullMethodName = "/vectorstore.v1.VectorStore/Insert"
+	VectorStore_Search_FullMethodName = "/vectorstore.v1.VectorStore/Search"
+)
+
+// VectorStoreClient is the client API for VectorStore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VectorStoreClient interface {
+	Insert(ctx context.Context, in *InsertRequest, opts ...grpc.CallOption) (*InsertResponse, error)
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+}
+
+type vectorStoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVectorStoreClient(cc grpc.ClientConnInterface) VectorStoreClient {
+	return &vectorStoreClient{cc}
+}
+
+func (c *vectorStoreClient) Insert(ctx context.Context, in *InsertRequest, opts ...grpc.CallOption) (*InsertResponse, error) {
+	out := new(InsertResponse)
+	err := c.cc.Invoke(ctx, VectorStore_Insert_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorStoreClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, VectorStore_Search_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VectorStoreServer is the server API for VectorStore service.
+// All implementations must embed UnimplementedVectorStoreServer
+// for forward compatibility
+type VectorStoreServer interface {
+	Insert(context.Context, *InsertRequest) (*InsertResponse, error)
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	mustEmbedUnimplementedVectorStoreServer()
+}
+
+// UnimplementedVectorStoreServer must be embedded to have forward compatible implementations.
+type UnimplementedVectorStoreServer struct {
+}
+
+func (UnimplementedVectorStoreServer) Insert(context.Context, *InsertRequest) (*InsertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Insert not implemented")
+}
+func (UnimplementedVectorStoreServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedVectorStoreServer) mustEmbedUnimplementedVectorStoreServer() {}
+
+// UnsafeVectorStoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VectorStoreServer will
+// result in compilation errors.
+type UnsafeVectorStoreServer interface {
+	mustEmbedUnimplementedVectorStoreServer()
+}
+
+func RegisterVectorStoreServer(s grpc.ServiceRegistrar, srv VectorStoreServer) {
+	s.RegisterService(&VectorStore_ServiceDesc, srv)
+}
+
+func _VectorStore_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorStoreServer).Insert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorStore_Insert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorStoreServer).Insert(ctx, req.(*InsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorStore_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorStoreServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorStore_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorStoreServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VectorStore_ServiceDesc is the grpc.ServiceDesc for VectorStore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VectorStore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vectorstore.v1.VectorStore",
+	HandlerType: (*VectorStoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Insert",
+			Handler:    _VectorStore_Insert_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _VectorStore_Search_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "vectorstore.proto",
+}
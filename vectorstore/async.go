@@ -0,0 +1,182 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// AsyncInserter queues texts to be embedded and stored in the background,
+// so that producers needn't wait for the model. Queued texts are written
+// by InsertBatch, in batches of whatever has queued up, up to
+// Options.BatchSize.
+type AsyncInserter struct {
+	s     *VectorStore
+	queue chan string
+	errs  chan BatchFailure
+	done  chan struct{}
+
+	// mu is held for reading while a text is queued and for writing by
+	// Close, so that nothing is sent once the queue is closed.
+	mu       sync.RWMutex
+	shutting bool
+
+	// queued counts the texts enqueued, which numbers them. pending is
+	// how many of them are yet to be stored or reported, and idle is
+	// closed while it is zero.
+	count   sync.Mutex
+	queued  int
+	pending int
+	idle    chan struct{}
+}
+
+// NewAsyncInserter starts a background writer with room for size texts in
+// its queue. Close it to stop the writer once done.
+func (s *VectorStore) NewAsyncInserter(size int) *AsyncInserter {
+	a := &AsyncInserter{
+		s:     s,
+		queue: make(chan string, max(size, 1)),
+		errs:  make(chan BatchFailure, max(size, 1)),
+		done:  make(chan struct{}),
+		idle:  make(chan struct{}),
+	}
+	close(a.idle)
+
+	go a.run()
+
+	return a
+}
+
+// Enqueue queues text to be stored, waiting for room if the queue is full.
+// It returns ErrClosed once the inserter is closed. Whether the text was
+// stored is only known from Errors.
+func (a *AsyncInserter) Enqueue(ctx context.Context, text string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.shutting {
+		return ErrClosed
+	}
+
+	a.count.Lock()
+	if a.pending == 0 {
+		a.idle = make(chan struct{})
+	}
+	a.pending++
+	a.count.Unlock()
+
+	select {
+	case a.queue <- text:
+		return nil
+	case <-ctx.Done():
+		a.finished(1)
+		return ctx.Err()
+	}
+}
+
+// Errors returns the texts that failed to be stored, in a BatchFailure
+// whose Index is the order the text was enqueued in, counting from 0. Its
+// buffer is the size of the queue; if it is full, failures are logged
+// instead.
+func (a *AsyncInserter) Errors() <-chan BatchFailure {
+	return a.errs
+}
+
+// Wait blocks until every text enqueued so far is stored or reported as
+// failed, or ctx is done.
+func (a *AsyncInserter) Wait(ctx context.Context) error {
+	a.count.Lock()
+	idle := a.idle
+	a.count.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting texts and waits for those queued to be stored,
+// then closes Errors.
+func (a *AsyncInserter) Close() error {
+	a.mu.Lock()
+	if !a.shutting {
+		a.shutting = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+
+	return nil
+}
+
+func (a *AsyncInserter) run() {
+	defer close(a.done)
+	defer close(a.errs)
+
+	batch := make([]string, 0, max(a.s.opts.BatchSize, 1))
+	for text := range a.queue {
+		batch = append(batch[:0], text)
+	drain:
+		for len(batch) < cap(batch) {
+			select {
+			case text, ok := <-a.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, text)
+			default:
+				break drain
+			}
+		}
+
+		a.insert(batch)
+	}
+}
+
+// insert stores batch, reporting the texts that weren't stored.
+func (a *AsyncInserter) insert(batch []string) {
+	a.count.Lock()
+	first := a.queued
+	a.queued += len(batch)
+	a.count.Unlock()
+
+	ids, err := a.s.InsertBatch(context.Background(), batch, nil)
+
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		for _, f := range batchErr.Failed {
+			f.Index += first
+			a.report(f)
+		}
+	} else if err != nil {
+		for i := range batch {
+			// A store that is closed returns no IDs at all.
+			if i >= len(ids) || ids[i] == 0 {
+				a.report(BatchFailure{Index: first + i, Text: batch[i], Err: err})
+			}
+		}
+	}
+
+	a.finished(len(batch))
+}
+
+func (a *AsyncInserter) report(f BatchFailure) {
+	select {
+	case a.errs <- f:
+	default:
+		a.s.log.Error("Queued text failed to be stored", "index", f.Index, "error", f.Err)
+	}
+}
+
+// finished marks n texts as handled.
+func (a *AsyncInserter) finished(n int) {
+	a.count.Lock()
+	defer a.count.Unlock()
+
+	if a.pending -= n; a.pending == 0 {
+		close(a.idle)
+	}
+}
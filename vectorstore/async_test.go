@@ -0,0 +1,47 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestAsyncInserter(t *testing.T) {
+	ctx := context.Background()
+	s := openStoreWith(t, memOptions().WithBatchSize(4), newFailingEmbedder(0))
+
+	a := s.NewAsyncInserter(8)
+	texts := append(numbered(200, 4), "this will fail")
+	for _, text := range texts {
+		if err := a.Enqueue(ctx, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := s.Count(ctx); n != 200 {
+		t.Errorf("after Wait, Count = %d, want 200", n)
+	}
+	select {
+	case f := <-a.Errors():
+		if f.Index != 200 || f.Text != "this will fail" {
+			t.Errorf("failure = %+v, want text 200", f)
+		}
+	default:
+		t.Error("the failed text wasn't reported")
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Enqueue(ctx, "late"); !errors.Is(err, vectorstore.ErrClosed) {
+		t.Errorf("Enqueue after Close returned %v, want ErrClosed", err)
+	}
+	if _, ok := <-a.Errors(); ok {
+		t.Error("Errors isn't closed after Close")
+	}
+}
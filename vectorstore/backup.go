@@ -0,0 +1,63 @@
+package vectorstore
+
+import "io"
+
+// restorePendingWrites is how many batches Restore lets Badger have in
+// flight at once.
+const restorePendingWrites = 256
+
+// Backup writes every key changed after version since, documents and
+// indexes alike, to w in Badger's backup format, and returns the version
+// the backup runs up to. Pass 0 for a full backup, and the returned version
+// to the next call for an incremental one holding only what changed
+// since. On the store returned by Open it backs up the whole database,
+// named collections included; on a collection, only that collection.
+func (s *VectorStore) Backup(w io.Writer, since uint64) (uint64, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if s.root == nil {
+		return s.db.Backup(w, since)
+	}
+
+	stream := s.db.NewStream()
+	stream.Prefix = s.keys.prefix
+	stream.SinceTs = since
+	stream.LogPrefix = "VectorStore.Backup"
+
+	return stream.Backup(w, since)
+}
+
+// Restore loads a backup written by Backup, over whatever the database
+// holds, then checks it against the options like Open does. Restore full
+// backups into an empty store and incremental ones, in order, over the
+// store restored before them. A collection's backup restores into the
+// collection of the same name whichever store it is called on. Writes to
+// the database wait for the load to finish.
+func (s *VectorStore) Restore(r io.Reader) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := s.load(r); err != nil {
+		return err
+	}
+	s.log.Info("Restored backup")
+
+	return s.prepare()
+}
+
+// load reads a backup into the database, excluding writes while it does.
+func (s *VectorStore) load(r io.Reader) error {
+	s.life.writes.Lock()
+	defer s.life.writes.Unlock()
+
+	return s.db.Load(r, restorePendingWrites)
+}
@@ -0,0 +1,60 @@
+package vectorstore_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	src := openStore(t, memOptions())
+	ids := insertAll(t, src, "red apples", "blue sky")
+
+	var full bytes.Buffer
+	since, err := src.Backup(&full, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	late := insertAll(t, src, "green grass")[0]
+	var incr bytes.Buffer
+	if _, err := src.Backup(&incr, since); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := openStore(t, memOptions())
+	if err := dst.Restore(&full); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := dst.Count(ctx); n != 2 {
+		t.Errorf("after the full restore, Count = %d, want 2", n)
+	}
+
+	if err := dst.Restore(&incr); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range append(ids, late) {
+		if _, err := dst.Get(ctx, id); err != nil {
+			t.Errorf("after the incremental restore, Get(%d): %v", id, err)
+		}
+	}
+
+	for _, query := range []string{"green grass", "red apples", "blue"} {
+		want, err := src.Nearest(ctx, query, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := dst.Nearest(ctx, query, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resultIDs(got), resultIDs(want)) || got[0].Score != want[0].Score {
+			t.Errorf("Nearest(%q) on the restored store = %v, on the original %v", query, resultIDs(got), resultIDs(want))
+		}
+	}
+	if next := insertAll(t, dst, "after")[0]; next != late+1 {
+		t.Errorf("after restoring, next ID is %d, want %d", next, late+1)
+	}
+}
@@ -0,0 +1,169 @@
+package vectorstore
+
+import (
+	"context"
+	"sync"
+)
+
+type encoded struct {
+	index int
+	rec   record
+	err   error
+
+	// chunks, if set, are the parts a text too long for the embedder was
+	// split into under LongTextChunk, stored in place of rec.
+	chunks []record
+}
+
+// InsertBatch embeds and stores texts, returning their IDs in the same
+// order. Encoding is spread over Options.Concurrency goroutines and the
+// results are written in transactions of up to Options.BatchSize
+// documents. onProgress, if not nil, is called after each transaction with
+// the number of texts handled so far.
+//
+// Texts that fail to encode are skipped, left with an ID of 0 and reported
+// in a *BatchError once the rest of the batch has been stored. If storing
+// fails, the IDs of the texts stored so far are returned with the error.
+// Under LongTextChunk, a text split into chunks gets their parent's ID,
+// which Delete takes to remove them all.
+func (s *VectorStore) InsertBatch(ctx context.Context, texts []string, onProgress func(done, total int)) ([]uint64, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := s.encodeAll(ctx, texts)
+
+	ids := make([]uint64, len(texts))
+	var failed []BatchFailure
+	var pending []encoded
+	done := 0
+
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		recs := make([]record, 0, len(pending))
+		for _, p := range pending {
+			if p.chunks != nil {
+				recs = append(recs, p.chunks...)
+			} else {
+				recs = append(recs, p.rec)
+			}
+		}
+
+		written, err := s.writeRecords(ctx, recs)
+		for _, p := range pending {
+			if p.chunks == nil {
+				ids[p.index], written = written[0], written[1:]
+				continue
+			}
+
+			// Records are written in order, so the last chunk being stored
+			// means they all were.
+			if written[len(p.chunks)-1] != 0 {
+				ids[p.index] = p.chunks[0].Parent
+			}
+			written = written[len(p.chunks):]
+		}
+		if err != nil {
+			return err
+		}
+
+		done += len(pending)
+		pending = pending[:0]
+
+		if onProgress != nil {
+			onProgress(done+len(failed), len(texts))
+		}
+
+		return nil
+	}
+
+	for r := range results {
+		if r.err != nil {
+			failed = append(failed, BatchFailure{Index: r.index, Text: texts[r.index], Err: r.err})
+			continue
+		}
+
+		pending = append(pending, r)
+		if len(pending) >= max(s.opts.BatchSize, 1) {
+			if err := flush(); err != nil {
+				cancel()
+				for range results {
+				}
+				return ids, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return ids, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return ids, err
+	}
+
+	if len(failed) > 0 {
+		return ids, &BatchError{Failed: failed}
+	}
+
+	return ids, nil
+}
+
+// encodeAll embeds texts on a pool of workers. Results arrive in no
+// particular order and the channel is closed once every text is done or ctx
+// is cancelled.
+func (s *VectorStore) encodeAll(ctx context.Context, texts []string) <-chan encoded {
+	jobs := make(chan int)
+	results := make(chan encoded)
+
+	go func() {
+		defer close(jobs)
+		for i := range texts {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < max(s.opts.Concurrency, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := encoded{index: i, rec: record{Text: texts[i]}}
+				if _, _, long := s.tooLong(texts[i]); long && s.opts.LongText == LongTextChunk {
+					r.chunks, r.err = s.embedChunks(ctx, []string{texts[i]}, nil)
+				} else {
+					r.rec.Embedding, r.err = s.embedForStorage(ctx, texts[i])
+				}
+
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
@@ -0,0 +1,156 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+var errFlaky = errors.New("flaky model")
+
+// failingEmbedder is a HashEmbedder that fails texts containing "fail",
+// and the first failures of texts containing "flaky".
+type failingEmbedder struct {
+	*vectortest.HashEmbedder
+	failures atomic.Int32
+	flaky    int32
+}
+
+func newFailingEmbedder(flaky int) *failingEmbedder {
+	return &failingEmbedder{HashEmbedder: vectortest.NewHashEmbedder(testDim), flaky: int32(flaky)}
+}
+
+func (e *failingEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if strings.Contains(text, "fail") {
+		return nil, errFlaky
+	}
+	if strings.Contains(text, "flaky") && e.failures.Add(1) <= e.flaky {
+		return nil, errFlaky
+	}
+
+	return e.HashEmbedder.Embed(ctx, text)
+}
+
+func TestInsertBatch(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithBatchSize(3).WithConcurrency(2))
+
+	texts := numbered(10, 3)
+	var calls, last int
+	ids, err := s.InsertBatch(ctx, texts, func(done, total int) {
+		calls++
+		last = done
+		if total != len(texts) {
+			t.Errorf("progress total = %d, want %d", total, len(texts))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ten texts in batches of three take four transactions.
+	if calls != 4 || last != len(texts) {
+		t.Errorf("progress was reported %d times, last with %d done", calls, last)
+	}
+
+	results, err := s.Nearest(ctx, "document", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := make(map[uint64]string)
+	for _, r := range results {
+		stored[r.ID] = r.Text
+	}
+	if len(stored) != len(texts) {
+		t.Errorf("%d documents were stored, want %d", len(stored), len(texts))
+	}
+	for i, id := range ids {
+		if stored[id] != texts[i] {
+			t.Errorf("ids[%d] = %d holds %q, want %q", i, id, stored[id], texts[i])
+		}
+	}
+}
+
+func TestInsertBatchFailures(t *testing.T) {
+	ctx := context.Background()
+	s, err := vectorstore.Open(testOptions(t), newFailingEmbedder(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ids, err := s.InsertBatch(ctx, []string{"good one", "fail here", "good two", "also fail"}, nil)
+	var be *vectorstore.BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("InsertBatch returned %v, want a BatchError", err)
+	}
+	if len(be.Failed) != 2 || be.Failed[0].Index != 1 || be.Failed[1].Index != 3 {
+		t.Errorf("failures = %+v, want texts 1 and 3", be.Failed)
+	}
+	if !errors.Is(err, errFlaky) {
+		t.Errorf("BatchError %v doesn't wrap the encoding errors", err)
+	}
+
+	if ids[0] == 0 || ids[1] != 0 || ids[2] == 0 || ids[3] != 0 {
+		t.Errorf("ids = %v, want 0 for the failed texts alone", ids)
+	}
+	if results, _ := s.Nearest(ctx, "good", 10); len(results) != 2 {
+		t.Errorf("%d documents were stored, want the 2 good texts", len(results))
+	}
+}
+
+// TestInsertBatchLarge inserts more text than fits in one Badger
+// transaction, about 10MB by default, so the write has to be split.
+func TestInsertBatchLarge(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	texts := make([]string, 24)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("large document %d ", i) + strings.Repeat("filler words ", 1<<16)
+	}
+	ids, err := s.InsertBatch(ctx, texts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, id := range ids {
+		doc, err := s.Get(ctx, id)
+		if err != nil || doc.Text != texts[i] {
+			t.Errorf("text %d was stored as %d, %v", i, id, err)
+		}
+	}
+	if n, err := s.Count(ctx); err != nil || n != len(texts) {
+		t.Errorf("Count = %d, %v, want %d", n, err, len(texts))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	retry := vectorstore.RetryOptions{Attempts: 3, Backoff: time.Millisecond}
+
+	s := openStoreWith(t, memOptions().WithRetry(retry), newFailingEmbedder(2))
+	if _, err := s.Insert(ctx, "flaky text"); err != nil {
+		t.Errorf("Insert failing twice with 3 attempts: %v", err)
+	}
+
+	s = openStoreWith(t, memOptions().WithRetry(retry), newFailingEmbedder(3))
+	if _, err := s.Insert(ctx, "flaky text"); !errors.Is(err, vectorstore.ErrEncodeFailed) || !errors.Is(err, errFlaky) {
+		t.Errorf("Insert failing three times with 3 attempts returned %v, want ErrEncodeFailed wrapping the last error", err)
+	}
+
+	// Cancelling the insert cuts the backoff short.
+	retry.Backoff = time.Hour
+	s = openStoreWith(t, memOptions().WithRetry(retry), newFailingEmbedder(1))
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Insert(timeout, "flaky text"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Insert cancelled while backing off returned %v, want DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,165 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// BulkDocument is a document with a precomputed embedding, for BulkLoad.
+type BulkDocument struct {
+	Text      string
+	Metadata  map[string]string
+	Embedding []float64
+}
+
+// BulkIterator yields the documents for BulkLoad. Next returns io.EOF once
+// there are no more.
+type BulkIterator interface {
+	Next() (BulkDocument, error)
+}
+
+// BulkLoad stores every document from it in a new, empty index, without
+// calling the embedder. Documents are written through a Badger WriteBatch
+// rather than one transaction per batch, which is much faster for the
+// initial population of a large index, and the configured index is built
+// once they are all stored. It returns the number of documents loaded.
+//
+// BulkLoad is not safe to run alongside queries or other writes, which
+// may see a partially loaded index. If it fails part way, start again with
+// a fresh store.
+func (s *VectorStore) BulkLoad(ctx context.Context, it BulkIterator) (int, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	var next uint64
+	var want int
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		docs := txn.NewIterator(badger.IteratorOptions{Prefix: s.keys.docPrefix()})
+		docs.Rewind()
+		empty := !docs.Valid()
+		docs.Close()
+		if !empty {
+			return errors.New("BulkLoad needs an empty index")
+		}
+
+		if err := s.markNormalized(txn); err != nil {
+			return err
+		}
+
+		var err error
+		if want, err = storedDim(txn, s.keys); err != nil {
+			return err
+		}
+
+		next, err = allocIDs(txn, s.keys, 0)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	if want == 0 {
+		var err error
+		if want, err = s.storedEmbeddingDim(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, want, err := s.writeBulk(ctx, it, next, want)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		if err := s.checkDim(txn, want); err != nil {
+			return err
+		}
+
+		return txn.Set(s.keys.meta(metaNextID), binary.BigEndian.AppendUint64(nil, next+uint64(n)))
+	}); err != nil {
+		return 0, err
+	}
+	s.opts.Metrics.Inserted(n)
+
+	if err := s.BuildIndex(ctx); err != nil {
+		return n, err
+	}
+
+	if s.opts.Keywords {
+		if err := s.buildKeywords(ctx); err != nil {
+			return n, err
+		}
+	}
+
+	var packed bool
+	if err := s.db.View(func(txn *badger.Txn) (err error) {
+		packed, err = s.packedEnabled(txn)
+		return
+	}); err != nil || !packed {
+		return n, err
+	}
+
+	return n, s.RebuildPackedStore(ctx)
+}
+
+// writeBulk writes the documents from it through a WriteBatch under IDs
+// counting up from next, returning how many there were and their
+// dimension, which must be want unless that is 0.
+func (s *VectorStore) writeBulk(ctx context.Context, it BulkIterator, next uint64, want int) (int, int, error) {
+	s.life.writes.RLock()
+	defer s.life.writes.RUnlock()
+
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	now := time.Now().UnixNano()
+	n := 0
+	for {
+		if n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		doc, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, 0, fmt.Errorf("document %d: %w", n+1, err)
+		}
+
+		if want == 0 {
+			want = len(doc.Embedding)
+		}
+		if len(doc.Embedding) == 0 || len(doc.Embedding) != want {
+			return 0, 0, fmt.Errorf("document %d: %w: expected %d, got %d", n+1, ErrDimensionMismatch, want, len(doc.Embedding))
+		}
+
+		if s.opts.Normalize {
+			normalize(doc.Embedding)
+		}
+
+		rec := record{Text: doc.Text, Metadata: doc.Metadata, CreatedAt: now, Embedding: doc.Embedding}
+		if err := s.setRecord(wb, s.keys.doc(next+uint64(n)), rec); err != nil {
+			return 0, 0, err
+		}
+		n++
+	}
+
+	if n == 0 {
+		return 0, want, nil
+	}
+
+	return n, want, wb.Flush()
+}
@@ -0,0 +1,98 @@
+package vectorstore_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// sliceIterator is a BulkIterator over docs.
+type sliceIterator struct {
+	docs []vectorstore.BulkDocument
+}
+
+func (it *sliceIterator) Next() (vectorstore.BulkDocument, error) {
+	if len(it.docs) == 0 {
+		return vectorstore.BulkDocument{}, io.EOF
+	}
+
+	doc := it.docs[0]
+	it.docs = it.docs[1:]
+	return doc, nil
+}
+
+func TestBulkLoad(t *testing.T) {
+	ctx := context.Background()
+	h := vectortest.NewHashEmbedder(testDim)
+
+	texts := numbered(100, 5)
+	it := &sliceIterator{}
+	for _, text := range texts {
+		vec, _ := h.Embed(ctx, text)
+		it.docs = append(it.docs, vectorstore.BulkDocument{Text: text, Embedding: vec})
+	}
+
+	for _, index := range []vectorstore.IndexType{vectorstore.IndexFlat, vectorstore.IndexHNSW, vectorstore.IndexIVF} {
+		s := openStore(t, memOptions().WithIndex(index).WithIVF(vectorstore.IVFOptions{NList: 4, NProbe: 4, Iterations: 5}))
+		n, err := s.BulkLoad(ctx, &sliceIterator{docs: it.docs})
+		if err != nil {
+			t.Fatalf("%v: %v", index, err)
+		}
+		if n != len(texts) {
+			t.Errorf("%v: BulkLoad loaded %d documents, want %d", index, n, len(texts))
+		}
+
+		results, err := s.Nearest(ctx, texts[42], 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Score < 0.999 {
+			t.Errorf("%v: Nearest of a loaded text = %+v", index, results)
+		}
+
+		if id := insertAll(t, s, "after the load")[0]; id != uint64(len(texts)+1) {
+			t.Errorf("%v: the first insert after the load got ID %d", index, id)
+		}
+	}
+}
+
+// BenchmarkBulkLoad compares loading 5000 documents of 384 dimensions into
+// an empty store with BulkLoad and with InsertBatch.
+func BenchmarkBulkLoad(b *testing.B) {
+	ctx := context.Background()
+	h := vectortest.NewHashEmbedder(384)
+	texts := benchTexts(5000)
+	docs := make([]vectorstore.BulkDocument, len(texts))
+	for i, text := range texts {
+		vec, _ := h.Embed(ctx, text)
+		docs[i] = vectorstore.BulkDocument{Text: text, Embedding: vec}
+	}
+
+	load := map[string]func(s *vectorstore.VectorStore) error{
+		"BulkLoad": func(s *vectorstore.VectorStore) error {
+			_, err := s.BulkLoad(ctx, &sliceIterator{docs: docs})
+			return err
+		},
+		"InsertBatch": func(s *vectorstore.VectorStore) error {
+			_, err := s.InsertBatch(ctx, texts, nil)
+			return err
+		},
+	}
+	for _, name := range []string{"BulkLoad", "InsertBatch"} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				s := openStoreWith(b, memOptions(), h)
+				b.StartTimer()
+
+				if err := load[name](s); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(docs))/b.Elapsed().Seconds(), "docs/s")
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package vectorstore
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"strings"
+	"sync"
+	"time"
+)
+
+// embeddingCache is a concurrency safe LRU of embeddings keyed by a hash
+// of their text. Vectors are copied in and out, since callers normalize
+// them in place. If ttl is above zero, entries older than it are misses.
+type embeddingCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List
+	items map[[sha256.Size]byte]*list.Element
+}
+
+type cacheEntry struct {
+	key   [sha256.Size]byte
+	vec   []float64
+	added time.Time
+}
+
+func newEmbeddingCache(size int, ttl time.Duration) *embeddingCache {
+	return &embeddingCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[[sha256.Size]byte]*list.Element, size),
+	}
+}
+
+func (c *embeddingCache) get(text string) ([]float64, bool) {
+	key := sha256.Sum256([]byte(text))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(el.Value.(*cacheEntry).added) > c.ttl {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+
+	return append([]float64(nil), el.Value.(*cacheEntry).vec...), true
+}
+
+func (c *embeddingCache) put(text string, vec []float64) {
+	key := sha256.Sum256([]byte(text))
+	vec = append([]float64(nil), vec...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.vec, entry.added = vec, time.Now()
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, vec: vec, added: time.Now()})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// clear drops every entry.
+func (c *embeddingCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.items)
+}
+
+// queryCacheKey normalizes a query for the query cache, so that queries
+// differing only in case or whitespace share an entry.
+func queryCacheKey(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,118 @@
+package vectorstore_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// countingEmbedder is a HashEmbedder that counts its calls.
+type countingEmbedder struct {
+	*vectortest.HashEmbedder
+	calls atomic.Int32
+}
+
+func newCountingEmbedder() *countingEmbedder {
+	return &countingEmbedder{HashEmbedder: vectortest.NewHashEmbedder(testDim)}
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	e.calls.Add(1)
+	return e.HashEmbedder.Embed(ctx, text)
+}
+
+var _ vectorstore.Embedder = (*countingEmbedder)(nil)
+
+func TestEmbeddingCache(t *testing.T) {
+	ctx := context.Background()
+	e := newCountingEmbedder()
+	s := openStoreWith(t, memOptions().WithCacheSize(2), e)
+
+	insertAll(t, s, "red apples", "red apples", "blue sky", "red apples")
+	if n := e.calls.Load(); n != 2 {
+		t.Errorf("4 inserts of 2 texts embedded %d times, want 2", n)
+	}
+
+	if _, err := s.Nearest(ctx, "blue sky", 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := e.calls.Load(); n != 2 {
+		t.Errorf("a query for a cached text embedded it again: %d calls, want 2", n)
+	}
+
+	insertAll(t, s, "green grass", "yellow sun", "red apples")
+	if n := e.calls.Load(); n != 5 {
+		t.Errorf("after evicting it, a cached text wasn't embedded again: %d calls, want 5", n)
+	}
+}
+
+// TestEmbeddingCacheConcurrent searches from several goroutines with a
+// cache too small for their queries, so that they hit, miss and evict
+// concurrently. Run it with -race.
+func TestEmbeddingCacheConcurrent(t *testing.T) {
+	ctx := context.Background()
+	e := newCountingEmbedder()
+	s := openStoreWith(t, memOptions().WithCacheSize(8), e)
+	insertAll(t, s, numbered(20, 4)...)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				q := fmt.Sprintf("topic%d things", (g+i)%12)
+				if _, err := s.Nearest(ctx, q, 3); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestQueryCache(t *testing.T) {
+	ctx := context.Background()
+	e := newCountingEmbedder()
+	s := openStoreWith(t, memOptions().WithQueryCacheSize(4).WithQueryCacheTTL(time.Hour), e)
+	insertAll(t, s, "red apples")
+	base := e.calls.Load()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Nearest(ctx, "red apples", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := e.calls.Load() - base; n != 1 {
+		t.Errorf("3 searches for one query embedded it %d times, want 1", n)
+	}
+
+	base = e.calls.Load()
+	for _, q := range []string{" red  apples ", "Red apples"} {
+		if _, err := s.Nearest(ctx, q, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := e.calls.Load() - base; n != 0 {
+		t.Errorf("queries differing from a cached one in whitespace and case embedded %d times, want 0", n)
+	}
+
+	expiring := openStoreWith(t, memOptions().WithQueryCacheSize(4).WithQueryCacheTTL(time.Nanosecond), e)
+	base = e.calls.Load()
+	for i := 0; i < 2; i++ {
+		if _, err := expiring.Nearest(ctx, "red apples", 1); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := e.calls.Load() - base; n != 2 {
+		t.Errorf("with an expired entry, 2 searches embedded %d times, want 2", n)
+	}
+}
@@ -0,0 +1,56 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// slowEmbedder is a HashEmbedder that takes delay to embed and ignores its
+// context while it does, so that a search's deadline passes during it.
+type slowEmbedder struct {
+	*vectortest.HashEmbedder
+	delay time.Duration
+}
+
+func (e slowEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	time.Sleep(e.delay)
+
+	return e.HashEmbedder.Embed(context.Background(), text)
+}
+
+func TestNearestTimeout(t *testing.T) {
+	opts := testOptions(t)
+	s := openStore(t, opts)
+	if _, err := s.InsertBatch(context.Background(), numbered(5000, 50), nil); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	ivf := vectorstore.IVFOptions{NList: 8, NProbe: 8, Iterations: 5}
+	for _, index := range []vectorstore.IndexType{vectorstore.IndexFlat, vectorstore.IndexIVF} {
+		t.Run(index.String(), func(t *testing.T) {
+			s, err := vectorstore.Open(opts.WithIndex(index).WithIVF(ivf), slowEmbedder{vectortest.NewHashEmbedder(testDim), 20 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			if index == vectorstore.IndexIVF {
+				if err := s.BuildIndex(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			results, err := s.Nearest(ctx, "topic1 things", 10)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Nearest past its deadline returned %d results and %v, want DeadlineExceeded", len(results), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package vectorstore
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// ChunkUnit is what Chunk counts window sizes in.
+type ChunkUnit uint8
+
+const (
+	// ChunkSentences splits on sentence ends and blank lines.
+	ChunkSentences ChunkUnit = iota
+	// ChunkWords splits on whitespace.
+	ChunkWords
+)
+
+// ChunkOptions controls how Chunk splits text.
+type ChunkOptions struct {
+	Unit ChunkUnit
+
+	// Size is the number of units in a chunk.
+	Size int
+
+	// Overlap is the number of units each chunk shares with the one
+	// before it. It must be less than Size; Chunk clamps it to between 0
+	// and Size-1, so that no unit is skipped and every chunk moves on by
+	// at least one.
+	Overlap int
+}
+
+// DefaultChunkOptions returns windows of four sentences, each overlapping
+// the last by one.
+func DefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{Unit: ChunkSentences, Size: 4, Overlap: 1}
+}
+
+// Chunk splits text into windows of opts.Size units, each starting
+// opts.Size - opts.Overlap units after the last. The final window may be
+// shorter. Text that fits in one window is returned as a single chunk.
+func Chunk(text string, opts ChunkOptions) []string {
+	var units []string
+	sep := " "
+	if opts.Unit == ChunkWords {
+		units = strings.Fields(text)
+	} else {
+		units = splitSentences(text)
+	}
+
+	size := max(opts.Size, 1)
+	step := size - min(max(opts.Overlap, 0), size-1)
+
+	var chunks []string
+	for start := 0; start < len(units); start += step {
+		end := min(start+size, len(units))
+		chunks = append(chunks, strings.Join(units[start:end], sep))
+		if end == len(units) {
+			break
+		}
+	}
+
+	return chunks
+}
+
+// splitSentences splits text after '.', '!' or '?' followed by whitespace,
+// and at blank lines.
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+
+	emit := func(end int) {
+		if s := strings.Join(strings.Fields(string(runes[start:end])), " "); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+	}
+
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '.' || r == '!' || r == '?':
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				emit(i + 1)
+			}
+		case r == '\n' && i+1 < len(runes) && runes[i+1] == '\n':
+			emit(i)
+		}
+	}
+	emit(len(runes))
+
+	return sentences
+}
+
+// InsertChunked splits text with Chunk and stores each chunk as its own
+// document with a copy of metadata. The chunks share a newly allocated
+// parent ID, which is returned along with theirs and reported in search
+// results as Result.Parent; deleting it deletes the chunks. The chunks,
+// rather than text, are checked against Options.LongText, so under
+// LongTextReject only a chunk too long for the embedder fails the insert;
+// under LongTextChunk, it is split further.
+func (s *VectorStore) InsertChunked(ctx context.Context, text string, metadata map[string]string, opts ChunkOptions) (uint64, []uint64, error) {
+	if err := s.begin(); err != nil {
+		return 0, nil, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, nil, err
+	}
+
+	chunks := Chunk(text, opts)
+	if len(chunks) == 0 {
+		chunks = []string{text}
+	}
+
+	return s.insertChunks(ctx, chunks, metadata)
+}
+
+// insertChunks stores chunks as the children of a new parent.
+func (s *VectorStore) insertChunks(ctx context.Context, chunks []string, metadata map[string]string) (uint64, []uint64, error) {
+	recs, err := s.embedChunks(ctx, chunks, metadata)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ids, err := s.writeRecords(ctx, recs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return recs[0].Parent, ids, nil
+}
+
+// embedChunks embeds chunks, first split to fit the embedder under
+// LongTextChunk, and returns their records with a newly allocated parent
+// ID.
+func (s *VectorStore) embedChunks(ctx context.Context, chunks []string, metadata map[string]string) ([]record, error) {
+	recs, err := s.embedParts(ctx, chunks, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent uint64
+	if err := s.update(ctx, func(txn *badger.Txn) (err error) {
+		parent, err = allocIDs(txn, s.keys, 1)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := range recs {
+		recs[i].Parent = parent
+	}
+
+	return recs, nil
+}
+
+// embedParts is embedChunks without the parent.
+func (s *VectorStore) embedParts(ctx context.Context, chunks []string, metadata map[string]string) ([]record, error) {
+	if s.opts.LongText == LongTextChunk {
+		chunks = s.fitChunks(chunks)
+	}
+
+	recs := make([]record, len(chunks))
+	for i, chunk := range chunks {
+		embedding, err := s.embedForStorage(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		recs[i] = record{Text: chunk, Metadata: metadata, Embedding: embedding}
+	}
+
+	return recs, nil
+}
@@ -0,0 +1,135 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts vectorstore.ChunkOptions
+		want []string
+	}{
+		{
+			name: "sentences",
+			text: "One. Two!  Three?\nFour.\n\nFive",
+			opts: vectorstore.ChunkOptions{Unit: vectorstore.ChunkSentences, Size: 2, Overlap: 1},
+			want: []string{"One. Two!", "Two! Three?", "Three? Four.", "Four. Five"},
+		},
+		{
+			name: "words",
+			text: "a b c d e",
+			opts: vectorstore.ChunkOptions{Unit: vectorstore.ChunkWords, Size: 2},
+			want: []string{"a b", "c d", "e"},
+		},
+		{
+			name: "fits",
+			text: "Just one sentence. And a second.",
+			opts: vectorstore.DefaultChunkOptions(),
+			want: []string{"Just one sentence. And a second."},
+		},
+		{
+			name: "decimal points",
+			text: "It costs 3.50 today. Tomorrow more.",
+			opts: vectorstore.ChunkOptions{Size: 1},
+			want: []string{"It costs 3.50 today.", "Tomorrow more."},
+		},
+		{
+			name: "overlap not below size",
+			text: "a b c",
+			opts: vectorstore.ChunkOptions{Unit: vectorstore.ChunkWords, Size: 2, Overlap: 5},
+			want: []string{"a b", "b c"},
+		},
+		{
+			name: "negative overlap",
+			text: "a b c d e",
+			opts: vectorstore.ChunkOptions{Unit: vectorstore.ChunkWords, Size: 2, Overlap: -3},
+			want: []string{"a b", "c d", "e"},
+		},
+		{
+			name: "empty",
+			opts: vectorstore.DefaultChunkOptions(),
+		},
+	}
+	for _, tt := range tests {
+		if got := vectorstore.Chunk(tt.text, tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Chunk = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInsertChunked(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	text := "Red apples grow on trees. Blue skies are clear. Green grass is soft."
+	opts := vectorstore.ChunkOptions{Unit: vectorstore.ChunkSentences, Size: 1}
+	parent, ids, err := s.InsertChunked(ctx, text, map[string]string{"src": "t"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("InsertChunked stored %d chunks, want 3", len(ids))
+	}
+
+	for _, id := range ids {
+		doc, err := s.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Parent != parent || doc.Metadata["src"] != "t" {
+			t.Errorf("chunk %d = %+v, want parent %d and the metadata", id, doc, parent)
+		}
+	}
+
+	results, err := s.Nearest(ctx, "blue skies", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != ids[1] || results[0].Parent != parent {
+		t.Errorf("Nearest(blue skies) = %+v, want chunk %d of %d", results, ids[1], parent)
+	}
+
+	// Deleting the parent deletes the chunks left after deleting one.
+	if err := s.Delete(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	other := insertAll(t, s, "blue skies")[0]
+	if err := s.Delete(ctx, parent); err != nil {
+		t.Fatalf("Delete of the parent: %v", err)
+	}
+	if n, err := s.Count(ctx); err != nil || n != 1 {
+		t.Errorf("after deleting the parent, Count = %d, %v, want 1", n, err)
+	}
+	results, err = s.Nearest(ctx, "blue skies", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != other {
+		t.Errorf("after deleting the parent, Nearest(blue skies) = %+v, want only %d", results, other)
+	}
+	if err := s.Delete(ctx, parent); !errors.Is(err, vectorstore.ErrNotFound) {
+		t.Errorf("second Delete of the parent returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestInsertChunkedLongText(t *testing.T) {
+	ctx := context.Background()
+	s := openLimited(t, memOptions().WithLongText(vectorstore.LongTextReject), 5)
+	opts := vectorstore.ChunkOptions{Unit: vectorstore.ChunkWords, Size: 4}
+
+	if _, ids, err := s.InsertChunked(ctx, longText, nil, opts); err != nil || len(ids) != 3 {
+		t.Errorf("InsertChunked of long text in chunks that fit = %v, %v", ids, err)
+	}
+
+	opts.Size = 6
+	if _, _, err := s.InsertChunked(ctx, longText, nil, opts); !errors.Is(err, vectorstore.ErrTextTooLong) {
+		t.Errorf("InsertChunked in chunks that don't fit returned %v, want ErrTextTooLong", err)
+	}
+}
@@ -0,0 +1,49 @@
+package vectorstore
+
+import "context"
+
+// Clear deletes every document along with the indexes, settings and fitted
+// PCA stored with them, leaving the store as if newly created, without
+// removing the database files. On the store returned by Open it drops the
+// whole database, named collections included, and collections opened from
+// it before should be opened again. On a collection it drops only that
+// collection. Writes to the database wait for it to finish.
+func (s *VectorStore) Clear(ctx context.Context) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := s.drop(ctx); err != nil {
+		return err
+	}
+	s.log.Info("Cleared store")
+
+	return s.syncKeywords()
+}
+
+// drop deletes the store's keys, excluding writes while it does.
+func (s *VectorStore) drop(ctx context.Context) error {
+	s.life.writes.Lock()
+	defer s.life.writes.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.root == nil {
+		if err := s.db.DropAll(); err != nil {
+			return err
+		}
+	} else if err := s.db.DropPrefix(s.keys.prefix); err != nil {
+		return err
+	}
+
+	s.pca.Store(nil)
+
+	return nil
+}
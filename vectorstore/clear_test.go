@@ -0,0 +1,49 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestClear(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithIndex(vectorstore.IndexHNSW).WithKeywords(true))
+	insertAll(t, s, "red apples", "blue sky")
+
+	c, err := s.Collection("kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, c, "in a collection")
+
+	if err := c.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Count(ctx); n != 0 {
+		t.Errorf("after clearing the collection, it counts %d documents", n)
+	}
+	if n, _ := s.Count(ctx); n != 2 {
+		t.Errorf("clearing a collection left %d documents in the default one, want 2", n)
+	}
+
+	if err := s.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.Count(ctx); n != 0 {
+		t.Errorf("after Clear, Count = %d", n)
+	}
+	results, err := s.Nearest(ctx, "red apples", 5)
+	if err != nil || len(results) != 0 {
+		t.Errorf("after Clear, Nearest = %v, %v", resultIDs(results), err)
+	}
+	results, err = s.Search(ctx, "apples", vectorstore.SearchParams{K: 5, KeywordWeight: 1})
+	if err != nil || len(results) != 0 {
+		t.Errorf("after Clear, keyword search = %v, %v", resultIDs(results), err)
+	}
+
+	if id := insertAll(t, s, "a fresh start")[0]; id != 1 {
+		t.Errorf("after Clear, the first ID is %d, want 1", id)
+	}
+}
@@ -0,0 +1,96 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// Clustering is the result of Cluster.
+type Clustering struct {
+	// IDs are the documents clustered and Assignments[i] is the index in
+	// Centroids of the cluster IDs[i] is in.
+	IDs         []uint64
+	Assignments []int
+
+	// Centroids are the means of the clusters' embeddings.
+	Centroids [][]float64
+}
+
+// Cluster groups every stored embedding into k clusters by k-means, seeded
+// by k-means++ and run for at most maxIter iterations or until no
+// embedding changes cluster. Embeddings are assigned to the centroid
+// closest under the store's metric. Set Options.KMeansSeed for the same
+// clusters on every run. If fewer than k documents are stored, each gets
+// its own cluster.
+func (s *VectorStore) Cluster(ctx context.Context, k, maxIter int) (Clustering, error) {
+	if err := s.begin(); err != nil {
+		return Clustering{}, err
+	}
+	defer s.end()
+
+	if k < 1 {
+		return Clustering{}, errors.New("k must be at least 1")
+	}
+
+	ids, vecs, err := s.allVectors(ctx)
+	if err != nil {
+		return Clustering{}, err
+	}
+
+	metric := s.effectiveMetric(s.opts.Metric)
+	dist := func(a, b []float64) float64 { return scoreToDistance(metric, metric.Score(a, b)) }
+	centroids, assign := kmeans(vecs, k, max(maxIter, 1), dist, s.kmeansRand())
+
+	return Clustering{IDs: ids, Assignments: assign, Centroids: centroids}, ctx.Err()
+}
+
+// kmeansRand returns the random source for a k-means run, seeded by
+// Options.KMeansSeed if it is set.
+func (s *VectorStore) kmeansRand() *rand.Rand {
+	seed := s.opts.KMeansSeed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+
+	return rand.New(rand.NewSource(seed))
+}
+
+// allVectors reads the ID and embedding of every stored document, in ID
+// order, in one transaction.
+func (s *VectorStore) allVectors(ctx context.Context) ([]uint64, [][]float64, error) {
+	var ids []uint64
+	var vecs [][]float64
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.keys.docPrefix()
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if len(ids)%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			var vec storedVector
+			if err := it.Item().Value(func(val []byte) (err error) {
+				vec, err = s.format().decodeVector(val)
+				return
+			}); err != nil {
+				return err
+			}
+
+			ids = append(ids, s.keys.docID(it.Item().Key()))
+			vecs = append(vecs, vec.float64s())
+		}
+
+		return nil
+	})
+
+	return ids, vecs, err
+}
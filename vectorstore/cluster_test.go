@@ -0,0 +1,53 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCluster(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithKMeansSeed(1))
+	ids := insertAll(t, s, "red apples", "red apples pears", "red apples plums", "blue sky", "blue sky clouds", "blue sky sun")
+
+	c, err := s.Cluster(ctx, 2, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Centroids) != 2 || len(c.IDs) != len(ids) || len(c.Assignments) != len(ids) {
+		t.Fatalf("Cluster = %+v", c)
+	}
+	for _, centroid := range c.Centroids {
+		if len(centroid) != testDim {
+			t.Errorf("centroid has %d dimensions, want %d", len(centroid), testDim)
+		}
+	}
+	for i := range c.IDs {
+		if want := c.Assignments[i/3*3]; c.Assignments[i] != want {
+			t.Errorf("assignments = %v, want the red and blue texts apart", c.Assignments)
+			break
+		}
+	}
+	if c.Assignments[0] == c.Assignments[3] {
+		t.Errorf("assignments = %v, want the red and blue texts apart", c.Assignments)
+	}
+
+	again, err := s.Cluster(ctx, 2, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range c.Assignments {
+		if again.Assignments[i] != c.Assignments[i] {
+			t.Error("clustering with the same seed differed")
+			break
+		}
+	}
+
+	few, err := s.Cluster(ctx, 10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(few.Centroids) != len(ids) {
+		t.Errorf("10 clusters of %d documents made %d centroids, want one each", len(ids), len(few.Centroids))
+	}
+}
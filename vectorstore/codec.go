@@ -0,0 +1,177 @@
+package vectorstore
+
+import "fmt"
+
+// Codec encodes documents as stored values, for formats other than the
+// built-in one, such as gob or protobuf. Embedding is the embedding as it
+// is stored, after normalization and PCA. The document's ID is its key, so
+// Encode may ignore it and Decode needn't set it.
+//
+// A custom Codec gives up the built-in format's shortcuts: int8 and
+// sparse embeddings, stored norms, and reading a record's metadata or text
+// without its embedding. Records are tagged with the format they were
+// written in, so a store holding built-in records can be opened with a
+// Codec and they are still read, but records written by a Codec can't be
+// read without it.
+type Codec interface {
+	Encode(doc Document) ([]byte, error)
+	Decode(val []byte) (Document, error)
+}
+
+// BinaryCodec is the built-in record format as a Codec, for wrapping or
+// comparison. Leaving Options.Codec nil uses the same format without going
+// through the interface.
+type BinaryCodec struct {
+	// DType is the element type embeddings are written as, or float64 if
+	// it is 0.
+	DType DType
+
+	// StoreNorms is Options.StoreNorms.
+	StoreNorms bool
+}
+
+func (c BinaryCodec) Encode(doc Document) ([]byte, error) {
+	dtype := c.DType
+	if dtype == 0 {
+		dtype = DTypeFloat64
+	}
+
+	return encodeRecord(recordFromDocument(doc), dtype, c.StoreNorms), nil
+}
+
+func (c BinaryCodec) Decode(val []byte) (Document, error) {
+	rec, err := decodeRecord(val)
+	if err != nil {
+		return Document{}, err
+	}
+
+	return documentFromRecord(rec), nil
+}
+
+func recordFromDocument(doc Document) record {
+	return record{Text: doc.Text, Metadata: doc.Metadata, Parent: doc.Parent, CreatedAt: doc.CreatedAt, Embedding: doc.Embedding}
+}
+
+func documentFromRecord(rec record) Document {
+	return Document{Text: rec.Text, Metadata: rec.Metadata, Parent: rec.Parent, CreatedAt: rec.CreatedAt, Embedding: rec.Embedding}
+}
+
+// codecVersion is the first byte of records written by a custom Codec, in
+// place of the built-in format's version.
+const codecVersion = 0xff
+
+// recordFormat encodes and decodes records in the format the options ask
+// for: the built-in one, or Options.Codec's wrapped in a codecVersion tag.
+type recordFormat struct {
+	codec Codec
+	dtype DType
+	norms bool
+}
+
+func (s *VectorStore) format() recordFormat {
+	return recordFormat{codec: s.opts.Codec, dtype: s.opts.DType, norms: s.opts.StoreNorms}
+}
+
+// recordWriter is a transaction or write batch records are written to.
+type recordWriter interface {
+	Set(key, val []byte) error
+}
+
+// setRecord encodes r and writes it under key.
+func (s *VectorStore) setRecord(w recordWriter, key []byte, r record) error {
+	val, err := s.format().encode(r)
+	if err != nil {
+		return err
+	}
+
+	return w.Set(key, val)
+}
+
+func (f recordFormat) encode(r record) ([]byte, error) {
+	if f.codec == nil {
+		return encodeRecord(r, f.dtype, f.norms), nil
+	}
+
+	val, err := f.codec.Encode(documentFromRecord(r))
+	if err != nil {
+		return nil, fmt.Errorf("encoding record: %w", err)
+	}
+
+	return append([]byte{codecVersion}, val...), nil
+}
+
+// decodeCodec reads a record written by the custom Codec, reporting false
+// if it is in the built-in format.
+func (f recordFormat) decodeCodec(val []byte) (record, bool, error) {
+	if len(val) == 0 || val[0] != codecVersion {
+		return record{}, false, nil
+	}
+	if f.codec == nil {
+		return record{}, true, ErrNoCodec
+	}
+
+	doc, err := f.codec.Decode(val[1:])
+	if err != nil {
+		return record{}, true, fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+	}
+
+	return recordFromDocument(doc), true, nil
+}
+
+func (f recordFormat) decodeRecord(val []byte) (record, error) {
+	if rec, ok, err := f.decodeCodec(val); ok {
+		return rec, err
+	}
+
+	return decodeRecord(val)
+}
+
+// decodeDocument is decodeDocument in the format f.
+func (f recordFormat) decodeDocument(val []byte, r *record) (storedVector, error) {
+	rec, ok, err := f.decodeCodec(val)
+	if !ok {
+		return decodeDocument(val, r)
+	}
+
+	*r = rec
+	return storedVector{dense: rec.Embedding}, err
+}
+
+// decodeVector is decodeVector in the format f.
+func (f recordFormat) decodeVector(val []byte) (storedVector, error) {
+	rec, ok, err := f.decodeCodec(val)
+	if !ok {
+		return decodeVector(val)
+	}
+
+	return storedVector{dense: rec.Embedding}, err
+}
+
+// decodeText reads only the text of a record.
+func (f recordFormat) decodeText(val []byte) (string, error) {
+	rec, ok, err := f.decodeCodec(val)
+	if !ok {
+		text, _, err := decodeText(val)
+		return string(text), err
+	}
+
+	return rec.Text, err
+}
+
+// decodeFields reads the metadata, parent and creation time of a record
+// into r, leaving its text and embedding alone.
+func (f recordFormat) decodeFields(val []byte, r *record) error {
+	rec, ok, err := f.decodeCodec(val)
+	if !ok {
+		_, rest, err := decodeText(val)
+		if err != nil {
+			return err
+		}
+
+		_, err = decodeFields(val[0], rest, r)
+		return err
+	}
+
+	r.Metadata, r.Parent, r.CreatedAt = rec.Metadata, rec.Parent, rec.CreatedAt
+	return err
+}
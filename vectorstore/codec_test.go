@@ -0,0 +1,92 @@
+package vectorstore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// gobCodec stores documents with encoding/gob.
+type gobCodec struct{}
+
+func (gobCodec) Encode(doc vectorstore.Document) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(val []byte) (vectorstore.Document, error) {
+	var doc vectorstore.Document
+	err := gob.NewDecoder(bytes.NewReader(val)).Decode(&doc)
+	return doc, err
+}
+
+// brittleCodec is a BinaryCodec that fails to decode texts containing
+// "corrupt", as if their records were damaged.
+type brittleCodec struct {
+	vectorstore.BinaryCodec
+}
+
+func (c brittleCodec) Decode(val []byte) (vectorstore.Document, error) {
+	doc, err := c.BinaryCodec.Decode(val)
+	if err == nil && strings.Contains(doc.Text, "corrupt") {
+		return doc, errors.New("damaged")
+	}
+
+	return doc, err
+}
+
+func TestGobCodec(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions(t).WithGCInterval(0)
+
+	// Documents written in the built-in format stay readable after
+	// switching to a Codec.
+	s := openStore(t, opts)
+	old := insertAll(t, s, "red apples")
+	s.Close()
+
+	s = openStore(t, opts.WithCodec(gobCodec{}))
+	id, err := s.InsertWithMetadata(ctx, "blue sky", map[string]string{"colour": "blue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := vectortest.NewHashEmbedder(testDim).Embed(ctx, "blue sky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != id || doc.Text != "blue sky" || doc.Metadata["colour"] != "blue" || !reflect.DeepEqual(doc.Embedding, want) {
+		t.Errorf("Get of a gob record = %+v", doc)
+	}
+	if doc, err := s.Get(ctx, old[0]); err != nil || doc.Text != "red apples" {
+		t.Errorf("Get of a built-in record = %+v, %v", doc, err)
+	}
+
+	results, err := s.Nearest(ctx, "blue sky", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != id || results[0].Score < 0.999 {
+		t.Errorf("Nearest over gob records = %+v", results)
+	}
+	s.Close()
+
+	s = openStore(t, opts)
+	if _, err := s.Get(ctx, id); !errors.Is(err, vectorstore.ErrNoCodec) {
+		t.Errorf("Get of a gob record without the Codec returned %v, want ErrNoCodec", err)
+	}
+}
@@ -0,0 +1,69 @@
+package vectorstore
+
+import (
+	"context"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// compactRetryInterval is how long Compact waits for a value log GC run
+// already in progress, such as the background one, to finish.
+const compactRetryInterval = 100 * time.Millisecond
+
+// Compact merges the LSM tree down to one level, dropping the tombstones and
+// old versions left by deletes and updates, then rewrites value log files
+// until none has more than Options.GCDiscardRatio of stale data. Reads go on
+// as normal while it runs, but writes to the database, in any collection,
+// wait for it to finish.
+func (s *VectorStore) Compact(ctx context.Context) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	s.life.writes.Lock()
+	defer s.life.writes.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if err := s.db.Flatten(s.opts.NumGoroutines); err != nil {
+		return err
+	}
+
+	if s.opts.InMemory {
+		return nil
+	}
+
+	rewrites := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		switch err := s.db.RunValueLogGC(s.opts.GCDiscardRatio); err {
+		case nil:
+			rewrites++
+		case badger.ErrNoRewrite:
+			s.log.Info("Compacted database", "rewrites", rewrites, "duration", time.Since(start))
+			return nil
+		case badger.ErrRejected:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.closed:
+				return ErrClosed
+			case <-time.After(compactRetryInterval):
+			}
+		default:
+			return err
+		}
+	}
+}
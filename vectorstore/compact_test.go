@@ -0,0 +1,42 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, testOptions(t).WithGCInterval(0))
+
+	texts := numbered(50, 5)
+	ids := insertAll(t, s, texts...)
+	for _, id := range ids[:40] {
+		if err := s.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.Count(ctx); n != 10 {
+		t.Errorf("after Compact, Count = %d, want 10", n)
+	}
+	if _, err := s.Get(ctx, ids[45]); err != nil {
+		t.Errorf("after Compact, Get of a kept document: %v", err)
+	}
+
+	results, err := s.Nearest(ctx, texts[45], 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 10 || results[0].Score < 0.999 {
+		t.Errorf("after Compact, Nearest(%q) = %+v", texts[45], results)
+	}
+	for _, r := range results {
+		if r.ID <= ids[39] {
+			t.Errorf("after Compact, Nearest returned deleted document %d", r.ID)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// conflicting returns a transaction function that always conflicts, by
+// reading a key that another transaction writes before it commits, and
+// counts its runs.
+func conflicting(s *VectorStore, runs *int) func(txn *badger.Txn) error {
+	key := []byte("contended")
+
+	return func(txn *badger.Txn) error {
+		*runs++
+		if _, err := txn.Get(key); err != nil && err != badger.ErrKeyNotFound {
+			return err
+		}
+		if err := s.db.Update(func(other *badger.Txn) error { return other.Set(key, nil) }); err != nil {
+			return err
+		}
+
+		return txn.Set([]byte("loser"), nil)
+	}
+}
+
+func TestUpdateConflicts(t *testing.T) {
+	s, err := Open(DefaultOptions("").WithInMemory(true), NewCybertronEmbedder(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	runs := 0
+	if err := s.update(context.Background(), conflicting(s, &runs)); !errors.Is(err, badger.ErrConflict) {
+		t.Errorf("update of a transaction that always conflicts returned %v, want ErrConflict", err)
+	}
+	if runs != conflictAttempts {
+		t.Errorf("the transaction ran %d times, want %d", runs, conflictAttempts)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	runs = 0
+	if err := s.update(cancelled, conflicting(s, &runs)); !errors.Is(err, context.Canceled) {
+		t.Errorf("update with a cancelled context returned %v, want context.Canceled", err)
+	}
+	if runs != 1 {
+		t.Errorf("with a cancelled context, the transaction ran %d times, want 1", runs)
+	}
+}
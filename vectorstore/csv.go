@@ -0,0 +1,120 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// VectorFormat is the layout of a file read by ImportVectors.
+type VectorFormat uint8
+
+const (
+	// FormatCSV is comma separated: id, text, then one column per element.
+	FormatCSV VectorFormat = iota
+	// FormatTSV is tab separated: id, text, then the elements in one
+	// column separated by commas.
+	FormatTSV
+)
+
+// ImportVectors stores precomputed embeddings read from r without calling
+// the embedder. Each row holds a document ID, its text and its embedding,
+// which must have the index's dimension. A first row whose ID isn't a
+// number is taken as a header and skipped. Existing documents with the
+// same IDs are replaced. It returns the number of documents imported.
+func (s *VectorStore) ImportVectors(ctx context.Context, r io.Reader, format VectorFormat) (int, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	if format == FormatTSV {
+		cr.Comma = '\t'
+		cr.LazyQuotes = true
+	}
+
+	dim := s.e.Dim()
+	n := 0
+	var ids []uint64
+	var recs []record
+	for line := 1; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		row, err := cr.Read()
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+
+		if err == nil {
+			id, rec, perr := parseVectorRow(row)
+			if perr != nil && line == 1 {
+				continue
+			} else if perr != nil {
+				return n, fmt.Errorf("line %d: %w", line, perr)
+			}
+
+			if dim == 0 {
+				dim = len(rec.Embedding)
+			} else if len(rec.Embedding) != dim {
+				return n, fmt.Errorf("line %d: %w: expected %d dimensions, got %d", line, ErrDimensionMismatch, dim, len(rec.Embedding))
+			}
+
+			if s.opts.Normalize {
+				normalize(rec.Embedding)
+			}
+
+			ids = append(ids, id)
+			recs = append(recs, rec)
+		}
+
+		if len(ids) > 0 && (err == io.EOF || len(ids) >= max(s.opts.BatchSize, 1)) {
+			written, err := s.putRecords(ctx, ids, recs)
+			n += written
+			if err != nil {
+				return n, err
+			}
+			ids, recs = ids[:0], recs[:0]
+		}
+
+		if err == io.EOF {
+			return n, nil
+		}
+	}
+}
+
+func parseVectorRow(row []string) (uint64, record, error) {
+	if len(row) < 3 {
+		return 0, record{}, fmt.Errorf("expected id, text and embedding, got %d columns", len(row))
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(row[0]), 10, 64)
+	if err != nil || id == 0 {
+		return 0, record{}, fmt.Errorf("invalid id %q", row[0])
+	}
+
+	elems := row[2:]
+	if len(elems) == 1 {
+		elems = strings.Split(elems[0], ",")
+	}
+
+	rec := record{Text: row[1], Embedding: make([]float64, len(elems))}
+	for i, e := range elems {
+		if rec.Embedding[i], err = strconv.ParseFloat(strings.TrimSpace(e), 64); err != nil {
+			return 0, record{}, fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+
+	return id, rec, nil
+}
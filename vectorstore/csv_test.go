@@ -0,0 +1,66 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+// axisEmbedder embeds "x", "y" and "z" as the unit vectors along those
+// axes, for searching imported vectors.
+type axisEmbedder struct{}
+
+func (axisEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	i := strings.Index("xyz", text)
+	if len(text) != 1 || i < 0 {
+		return nil, fmt.Errorf("axisEmbedder can't embed %q", text)
+	}
+
+	vec := make([]float64, 3)
+	vec[i] = 1
+	return vec, nil
+}
+
+func (axisEmbedder) Dim() int { return 3 }
+
+func TestImportVectors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		format vectorstore.VectorFormat
+		in     string
+	}{
+		{vectorstore.FormatCSV, "id,text,x,y,z\n1,first,1,0,0\n5,fifth,0,1,0\n"},
+		{vectorstore.FormatTSV, "1\tfirst\t1,0,0\n5\tfifth\t0,1,0\n"},
+	}
+	for _, tt := range tests {
+		s := openStoreWith(t, memOptions(), axisEmbedder{})
+		n, err := s.ImportVectors(ctx, strings.NewReader(tt.in), tt.format)
+		if err != nil {
+			t.Fatalf("format %d: %v", tt.format, err)
+		}
+		if n != 2 {
+			t.Errorf("format %d: imported %d documents, want 2", tt.format, n)
+		}
+
+		results, err := s.Nearest(ctx, "y", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ID != 5 || results[0].Text != "fifth" {
+			t.Errorf("format %d: Nearest = %+v, want document 5", tt.format, results)
+		}
+	}
+
+	s := openStoreWith(t, memOptions(), axisEmbedder{})
+	if _, err := s.ImportVectors(ctx, strings.NewReader("1,short,1,0\n"), vectorstore.FormatCSV); !errors.Is(err, vectorstore.ErrDimensionMismatch) {
+		t.Errorf("importing a short vector returned %v, want ErrDimensionMismatch", err)
+	}
+	if _, err := s.ImportVectors(ctx, strings.NewReader("1,ok,1,0,0\n2,bad,1,zero,0\n"), vectorstore.FormatCSV); err == nil {
+		t.Error("importing an unparsable element succeeded")
+	}
+}
@@ -0,0 +1,38 @@
+package vectorstore
+
+import (
+	"context"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// findDuplicate returns the ID of the stored document most similar to vec
+// if its cosine similarity exceeds Options.DedupThreshold, or 0.
+func (s *VectorStore) findDuplicate(ctx context.Context, txn *badger.Txn, vec []float64) (uint64, error) {
+	metric := s.effectiveMetric(CosineSimilarity{})
+
+	q := newQuery(metric, vec, 1)
+	q.format = s.format()
+	ranked, err := s.indexSearch(ctx, txn, q)
+	if err != nil || len(ranked) == 0 {
+		return 0, err
+	}
+
+	if ranked[0].score <= s.opts.DedupThreshold {
+		return 0, nil
+	}
+
+	return ranked[0].id, nil
+}
+
+// duplicateIn returns the first of recs[keep] whose embedding has a cosine
+// similarity with vec above threshold, or -1.
+func duplicateIn(recs []record, keep []int, vec []float64, threshold float64) int {
+	for _, i := range keep {
+		if cosineSimilarity(recs[i].Embedding, vec) > threshold {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,11 @@
+//go:build !purego
+
+package vectorstore
+
+import "gonum.org/v1/gonum/floats"
+
+// dot uses gonum, which has assembly kernels on amd64 and falls back to
+// plain Go elsewhere. Build with the purego tag to avoid it entirely.
+func dot(a, b []float64) float64 {
+	return floats.Dot(a, b)
+}
@@ -0,0 +1,7 @@
+//go:build purego
+
+package vectorstore
+
+func dot(a, b []float64) float64 {
+	return dotScalar(a, b)
+}
@@ -0,0 +1,173 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	bertencoding "github.com/nlpodyssey/cybertron/pkg/tasks/textencoding/bert"
+)
+
+// Embedder turns text into the vectors stored and searched by a
+// VectorStore.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+
+	// Dim is the length of the vectors Embed returns, or 0 if it isn't
+	// known until the first one is produced.
+	Dim() int
+}
+
+// Pooling is how a BERT model's per token outputs are combined into one
+// embedding.
+type Pooling uint8
+
+const (
+	// PoolingMean averages the token outputs.
+	PoolingMean Pooling = iota + 1
+	// PoolingCLS uses the output for the leading [CLS] token.
+	PoolingCLS
+	// PoolingMax takes the element wise maximum of the token outputs.
+	PoolingMax
+)
+
+func (p Pooling) String() string {
+	switch p {
+	case PoolingMean:
+		return "mean"
+	case PoolingCLS:
+		return "cls"
+	case PoolingMax:
+		return "max"
+	default:
+		return fmt.Sprintf("Pooling(%d)", uint8(p))
+	}
+}
+
+// ParsePooling returns the Pooling named by s, as printed by
+// Pooling.String.
+func ParsePooling(s string) (Pooling, error) {
+	for _, p := range []Pooling{PoolingMean, PoolingCLS, PoolingMax} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown pooling %q", s)
+}
+
+func (p Pooling) strategy() bert.PoolingStrategyType {
+	switch p {
+	case PoolingCLS:
+		return bert.ClsTokenPooling
+	case PoolingMax:
+		return bert.MaxPooling
+	default:
+		return bert.MeanPooling
+	}
+}
+
+// pooler is implemented by embedders whose output depends on a Pooling,
+// which the store records so an index is always queried the way it was
+// built.
+type pooler interface {
+	Pooling() Pooling
+}
+
+// CybertronEmbedder embeds text with a cybertron text encoding model.
+type CybertronEmbedder struct {
+	m       textencoding.Interface
+	dim     int
+	pooling Pooling
+}
+
+var _ Embedder = (*CybertronEmbedder)(nil)
+
+// errNoModel is returned by a CybertronEmbedder without a model, which
+// retrying won't fix.
+var errNoModel = errors.New("no model loaded")
+
+// NewCybertronEmbedder returns an Embedder that mean pools the output of m.
+// m may be a ModelPool.
+func NewCybertronEmbedder(m textencoding.Interface) *CybertronEmbedder {
+	return &CybertronEmbedder{m: m, dim: modelDim(m), pooling: PoolingMean}
+}
+
+// WithPooling returns a copy of e that pools with p.
+func (e *CybertronEmbedder) WithPooling(p Pooling) *CybertronEmbedder {
+	c := *e
+	c.pooling = p
+	return &c
+}
+
+func (e *CybertronEmbedder) Pooling() Pooling {
+	return e.pooling
+}
+
+func (e *CybertronEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if e.m == nil {
+		return nil, errNoModel
+	}
+
+	result, err := e.m.Encode(ctx, text, int(e.pooling.strategy()))
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Vector.Data().F64(), nil
+}
+
+func (e *CybertronEmbedder) Dim() int {
+	return e.dim
+}
+
+// MaxTokens is the longest input, in tokens, a BERT model takes, or 0 if
+// the model isn't one.
+func (e *CybertronEmbedder) MaxTokens() int {
+	if m := bertModel(e.m); m != nil {
+		return m.Model.Bert.Config.MaxPositionEmbeddings
+	}
+
+	return 0
+}
+
+// CountTokens returns the number of tokens a BERT model sees in text,
+// including the [CLS] and [SEP] it adds, or 0 if the model isn't one.
+// Whether the model lower cases its input isn't exposed, so this counts
+// both ways and returns the larger.
+func (e *CybertronEmbedder) CountTokens(text string) int {
+	m := bertModel(e.m)
+	if m == nil {
+		return 0
+	}
+
+	n := max(len(m.Tokenizer.Tokenize(text)), len(m.Tokenizer.Tokenize(strings.ToLower(text))))
+	return n + 2
+}
+
+// bertModel returns the BERT model behind m, or nil if it isn't one.
+func bertModel(m textencoding.Interface) *bertencoding.TextEncoding {
+	switch m := m.(type) {
+	case *bertencoding.TextEncoding:
+		return m
+	case *ModelPool:
+		if len(m.models) > 0 {
+			return bertModel(m.models[0])
+		}
+	}
+
+	return nil
+}
+
+// modelDim returns the hidden size of BERT models, which is the length of
+// their pooled output, and 0 for anything else.
+func modelDim(m textencoding.Interface) int {
+	if m := bertModel(m); m != nil {
+		return m.Model.Bert.Config.HiddenSize
+	}
+
+	return 0
+}
@@ -0,0 +1,65 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/spago/mat"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+// poolingModel is a textencoding.Interface whose output records the
+// pooling strategy it was asked for.
+type poolingModel struct{}
+
+func (poolingModel) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	vec := []float64{float64(poolingStrategy), float64(len(text))}
+	return textencoding.Response{Vector: mat.NewDense[float64](mat.WithBacking(vec))}, nil
+}
+
+func TestPooling(t *testing.T) {
+	ctx := context.Background()
+	e := vectorstore.NewCybertronEmbedder(poolingModel{})
+
+	seen := make(map[float64]vectorstore.Pooling)
+	for _, p := range []vectorstore.Pooling{vectorstore.PoolingMean, vectorstore.PoolingCLS, vectorstore.PoolingMax} {
+		if got, err := vectorstore.ParsePooling(p.String()); err != nil || got != p {
+			t.Errorf("ParsePooling(%q) = %v, %v", p.String(), got, err)
+		}
+
+		vec, err := e.WithPooling(p).Embed(ctx, "red apples")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := seen[vec[0]]; ok {
+			t.Errorf("%v and %v pooling embed the same", p, other)
+		}
+		seen[vec[0]] = p
+	}
+	if e.Pooling() != vectorstore.PoolingMean {
+		t.Errorf("the default pooling is %v, want mean", e.Pooling())
+	}
+	if _, err := vectorstore.ParsePooling("median"); err == nil {
+		t.Error("ParsePooling of an unknown name succeeded")
+	}
+
+	// The pooling is recorded, so an index can't be queried with another.
+	opts := testOptions(t)
+	s, err := vectorstore.Open(opts, e.WithPooling(vectorstore.PoolingCLS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, "red apples")
+	s.Close()
+
+	if _, err := vectorstore.Open(opts, e); err == nil {
+		t.Error("Open of a cls pooled index with mean pooling succeeded")
+	}
+	s, err = vectorstore.Open(opts, e.WithPooling(vectorstore.PoolingCLS))
+	if err != nil {
+		t.Fatalf("reopening with the same pooling: %v", err)
+	}
+	s.Close()
+}
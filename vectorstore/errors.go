@@ -0,0 +1,99 @@
+package vectorstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned when no document has the requested ID.
+var ErrNotFound = errors.New("document not found")
+
+// ErrDimensionMismatch is returned when an embedding doesn't have the
+// dimension of those already in the index.
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// ErrEncodeFailed is returned when the embedder fails to encode a text. The
+// embedder's error is wrapped alongside it.
+var ErrEncodeFailed = errors.New("encoding text failed")
+
+// ErrEmptyQuery is returned by searches whose query is empty or only
+// whitespace.
+var ErrEmptyQuery = errors.New("query is empty")
+
+// ErrEmptyText is returned when inserting text that is empty or only
+// whitespace, unless Options.AllowEmptyText is set.
+var ErrEmptyText = errors.New("text is empty")
+
+// ErrTextTooLong is returned when inserting text with more tokens than the
+// embedder takes, unless Options.LongText says otherwise.
+var ErrTextTooLong = errors.New("text is too long for the model")
+
+// ErrClosed is returned by operations started after Shutdown or Close.
+var ErrClosed = errors.New("vector store is closed")
+
+// ErrReadOnly is returned by writes to a store opened with
+// Options.ReadOnly.
+var ErrReadOnly = errors.New("vector store is read-only")
+
+// ErrCorruptRecord is returned when a stored value can't be decoded.
+var ErrCorruptRecord = errors.New("corrupt record")
+
+// ErrModelMismatch is returned by Open when the index was built with a
+// different model than Options.ModelName.
+var ErrModelMismatch = errors.New("index was built with a different model")
+
+// ErrSnapshotMismatch is returned by LoadIndex when the snapshot wasn't
+// taken of the documents now in the store.
+var ErrSnapshotMismatch = errors.New("index snapshot doesn't match the stored documents")
+
+// ErrInvalidParams is returned by searches whose SearchParams are out of
+// range or conflict.
+var ErrInvalidParams = errors.New("invalid search parameters")
+
+// ErrKTooLarge is returned, along with ErrInvalidParams, by searches asking
+// for more results than Options.MaxK.
+var ErrKTooLarge = errors.New("k exceeds the maximum")
+
+// ErrNoCodec is returned when reading a record written by a custom Codec
+// from a store opened without Options.Codec.
+var ErrNoCodec = errors.New("record was written by a custom Codec, set Options.Codec to read it")
+
+// ErrNotReady is returned by Ready when the store can't serve requests.
+var ErrNotReady = errors.New("vector store is not ready")
+
+// BatchFailure is a single text that InsertBatch could not store.
+type BatchFailure struct {
+	Index int
+	Text  string
+	Err   error
+}
+
+// BatchError is returned by InsertBatch when some texts could not be
+// encoded. Every other text in the batch was stored.
+type BatchError struct {
+	Failed []BatchFailure
+}
+
+func (e *BatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d texts failed to encode", len(e.Failed))
+	for i, f := range e.Failed {
+		if i == 3 {
+			fmt.Fprintf(&b, "; and %d more", len(e.Failed)-i)
+			break
+		}
+		fmt.Fprintf(&b, "; text %d: %v", f.Index, f.Err)
+	}
+
+	return b.String()
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Err
+	}
+
+	return errs
+}
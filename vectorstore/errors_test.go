@@ -0,0 +1,39 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	id := insertAll(t, s, "red apples")[0]
+	failing := openStoreWith(t, memOptions(), newFailingEmbedder(0))
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"empty query", func() error { _, err := s.Nearest(ctx, " \t", 1); return err }(), vectorstore.ErrEmptyQuery},
+		{"get missing", func() error { _, err := s.Get(ctx, id+1); return err }(), vectorstore.ErrNotFound},
+		{"delete missing", s.Delete(ctx, id+1), vectorstore.ErrNotFound},
+		{"update missing", s.Update(ctx, id+1, "blue sky"), vectorstore.ErrNotFound},
+		{"insert encode", func() error { _, err := failing.Insert(ctx, "fail"); return err }(), vectorstore.ErrEncodeFailed},
+		{"search encode", func() error { _, err := failing.Nearest(ctx, "fail", 1); return err }(), vectorstore.ErrEncodeFailed},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s returned %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+
+	// The embedder's own error is kept alongside ErrEncodeFailed.
+	if _, err := failing.Insert(ctx, "fail"); !errors.Is(err, errFlaky) {
+		t.Errorf("a failed encode returned %v, which doesn't wrap the embedder's error", err)
+	}
+}
@@ -0,0 +1,37 @@
+package vectorstore
+
+import "math"
+
+// Explanation breaks down a result's vector score, for seeing why it
+// ranked where it did. Dot is the dot product of the query and document
+// embeddings and QueryNorm and DocNorm are their magnitudes, so that under
+// CosineSimilarity, VectorScore is Dot / (QueryNorm * DocNorm).
+type Explanation struct {
+	Dot       float64
+	QueryNorm float64
+	DocNorm   float64
+
+	// VectorScore is the document's score under the search's metric,
+	// before keyword fusion or a Reranker replaced Score. Embeddings stored
+	// as int8 are scored against a quantized query, so their Score can
+	// differ from it slightly.
+	VectorScore float64
+}
+
+// explain sets the Explanation of each result against q's target.
+func (q *query) explain(results []Result) {
+	queryNorm := math.Sqrt(q.targetSq)
+
+	for i, r := range results {
+		if len(r.Embedding) != len(q.target) {
+			continue
+		}
+
+		results[i].Explanation = &Explanation{
+			Dot:         dot(q.target, r.Embedding),
+			QueryNorm:   queryNorm,
+			DocNorm:     math.Sqrt(dot(r.Embedding, r.Embedding)),
+			VectorScore: q.metric.Score(q.target, r.Embedding),
+		}
+	}
+}
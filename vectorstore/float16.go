@@ -0,0 +1,67 @@
+package vectorstore
+
+import "math"
+
+// float16bits returns the IEEE 754 half precision encoding of f, rounding
+// to nearest even. Values beyond the float16 range become infinities and
+// ones below its smallest subnormal become zeros.
+func float16bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int32(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	switch {
+	case exp == 0xff:
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp-127 > 15:
+		return sign | 0x7c00
+	case exp-127 >= -14:
+		// Normal: round the 23 bit mantissa to 10 bits. A carry out of the
+		// mantissa correctly bumps the exponent, up to infinity.
+		h := uint32(exp-127+15)<<10 | mant>>13
+		if rest := mant & 0x1fff; rest > 0x1000 || (rest == 0x1000 && h&1 == 1) {
+			h++
+		}
+		return sign | uint16(h)
+	case exp-127 >= -25:
+		// Subnormal: shift in the implicit leading bit and round.
+		mant |= 0x800000
+		shift := uint32(-14-(exp-127)) + 13
+		h := mant >> shift
+		half := uint32(1) << (shift - 1)
+		if rest := mant & (half<<1 - 1); rest > half || (rest == half && h&1 == 1) {
+			h++
+		}
+		return sign | uint16(h)
+	default:
+		return sign
+	}
+}
+
+// float16frombits reverses float16bits.
+func float16frombits(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp != 0:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	case mant == 0:
+		return math.Float32frombits(sign)
+	default:
+		// Subnormal: normalize the mantissa for float32.
+		e := uint32(127 - 14)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	}
+}
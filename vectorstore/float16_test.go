@@ -0,0 +1,51 @@
+package vectorstore
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat16(t *testing.T) {
+	tests := []struct {
+		f    float32
+		bits uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff},
+		{6.1035156e-05, 0x0400},
+		{5.9604645e-08, 0x0001},
+		{float32(math.Inf(1)), 0x7c00},
+		{1e6, 0x7c00},
+		{1e-10, 0x0000},
+	}
+	for _, tt := range tests {
+		if got := float16bits(tt.f); got != tt.bits {
+			t.Errorf("float16bits(%v) = %#04x, want %#04x", tt.f, got, tt.bits)
+		}
+	}
+
+	if got := float16frombits(float16bits(1.0009766)); got != 1.0009766 {
+		t.Errorf("the smallest step above 1 round trips to %v", got)
+	}
+	if got := float16bits(1 + 1.0/4096); got != 0x3c00 {
+		t.Errorf("a value below half a step above 1 rounds to %#04x, want 0x3c00", got)
+	}
+	if !math.IsNaN(float64(float16frombits(float16bits(float32(math.NaN()))))) {
+		t.Error("NaN doesn't round trip")
+	}
+}
+
+func TestFloat16RoundTrip(t *testing.T) {
+	for h := 0; h <= math.MaxUint16; h++ {
+		f := float16frombits(uint16(h))
+		if math.IsNaN(float64(f)) {
+			continue
+		}
+		if got := float16bits(f); got != uint16(h) {
+			t.Fatalf("float16bits(float16frombits(%#04x)) = %#04x", h, got)
+		}
+	}
+}
@@ -0,0 +1,543 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// HNSWOptions tunes the hierarchical navigable small world graph index.
+type HNSWOptions struct {
+	// M is how many neighbours a node links to on each layer, twice that on
+	// the bottom layer. Higher values improve recall and cost memory and
+	// insert time.
+	M int
+
+	// EfConstruction is the size of the candidate list kept while linking
+	// a new node into the graph.
+	EfConstruction int
+
+	// EfSearch is the size of the candidate list kept while searching.
+	// Raising it trades query time for recall. It is never less than k.
+	EfSearch int
+}
+
+func DefaultHNSWOptions() HNSWOptions {
+	return HNSWOptions{
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       64,
+	}
+}
+
+// hnswNode is stored under its document's ID:
+//
+//	uvarint level
+//	for each layer 0..level: uvarint count, count big endian uint64 IDs
+type hnswNode struct {
+	level int
+	links [][]uint64
+}
+
+func encodeHNSWNode(n *hnswNode) []byte {
+	buf := binary.AppendUvarint(nil, uint64(n.level))
+	for _, links := range n.links {
+		buf = binary.AppendUvarint(buf, uint64(len(links)))
+		for _, id := range links {
+			buf = binary.BigEndian.AppendUint64(buf, id)
+		}
+	}
+
+	return buf
+}
+
+// hnswMaxLevel is above any level randomLevel gives, which the 53 bits of
+// a float64's mantissa keep below 54.
+const hnswMaxLevel = 64
+
+func decodeHNSWNode(val []byte) (*hnswNode, error) {
+	level, l := binary.Uvarint(val)
+	if l <= 0 || level > hnswMaxLevel {
+		return nil, ErrCorruptRecord
+	}
+	val = val[l:]
+
+	n := &hnswNode{level: int(level), links: make([][]uint64, level+1)}
+	for i := range n.links {
+		count, l := binary.Uvarint(val)
+		if l <= 0 || count > uint64(len(val)-l)/8 {
+			return nil, ErrCorruptRecord
+		}
+		val = val[l:]
+
+		n.links[i] = make([]uint64, count)
+		for j := range n.links[i] {
+			n.links[i][j] = binary.BigEndian.Uint64(val[j*8:])
+		}
+		val = val[count*8:]
+	}
+
+	return n, nil
+}
+
+type hnswItem struct {
+	id   uint64
+	dist float64
+}
+
+// closer reports whether a is closer than b, breaking ties in distance by
+// ID, lowest first, so that equidistant nodes always rank the same way.
+func (a hnswItem) closer(b hnswItem) bool {
+	if a.dist != b.dist {
+		return a.dist < b.dist
+	}
+
+	return a.id < b.id
+}
+
+// hnswHeap is a min heap on distance, or a max heap when max is set.
+type hnswHeap struct {
+	items []hnswItem
+	max   bool
+}
+
+func (h *hnswHeap) Len() int { return len(h.items) }
+func (h *hnswHeap) Less(i, j int) bool {
+	if h.max {
+		return h.items[j].closer(h.items[i])
+	}
+	return h.items[i].closer(h.items[j])
+}
+func (h *hnswHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *hnswHeap) Push(x interface{}) { h.items = append(h.items, x.(hnswItem)) }
+func (h *hnswHeap) Pop() interface{} {
+	x := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return x
+}
+func (h *hnswHeap) top() hnswItem { return h.items[0] }
+
+// hnswGraph reads and updates the graph within a single transaction. Nodes
+// and vectors are cached for the life of the transaction and modified nodes
+// are written back by flush.
+type hnswGraph struct {
+	txn    *badger.Txn
+	keys   keyspace
+	format recordFormat
+	opts   HNSWOptions
+	metric DistanceMetric
+
+	nodes map[uint64]*hnswNode
+	vecs  map[uint64][]float64
+	dirty map[uint64]bool
+}
+
+func newHNSWGraph(txn *badger.Txn, keys keyspace, format recordFormat, opts HNSWOptions, metric DistanceMetric) *hnswGraph {
+	return &hnswGraph{
+		txn:    txn,
+		keys:   keys,
+		format: format,
+		opts:   opts,
+		metric: metric,
+		nodes:  make(map[uint64]*hnswNode),
+		vecs:   make(map[uint64][]float64),
+		dirty:  make(map[uint64]bool),
+	}
+}
+
+// node returns the graph node for id, or nil if it isn't in the graph.
+func (g *hnswGraph) node(id uint64) (*hnswNode, error) {
+	if n, ok := g.nodes[id]; ok {
+		return n, nil
+	}
+
+	item, err := g.txn.Get(g.keys.hnswNode(id))
+	if err == badger.ErrKeyNotFound {
+		g.nodes[id] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var n *hnswNode
+	if err := item.Value(func(val []byte) (err error) {
+		n, err = decodeHNSWNode(val)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	g.nodes[id] = n
+	return n, nil
+}
+
+// vector returns the stored embedding for id, or nil if the document is
+// gone.
+func (g *hnswGraph) vector(id uint64) ([]float64, error) {
+	if v, ok := g.vecs[id]; ok {
+		return v, nil
+	}
+
+	item, err := g.txn.Get(g.keys.doc(id))
+	if err == badger.ErrKeyNotFound {
+		g.vecs[id] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var v []float64
+	if err := item.Value(func(val []byte) error {
+		sv, err := g.format.decodeVector(val)
+		v = sv.float64s()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	g.vecs[id] = v
+	return v, nil
+}
+
+// distance turns the metric's score into something where lower is closer.
+func (g *hnswGraph) distance(a, b []float64) float64 {
+	return scoreToDistance(g.metric, g.metric.Score(a, b))
+}
+
+func (g *hnswGraph) distanceTo(q []float64, id uint64) (float64, bool, error) {
+	v, err := g.vector(id)
+	if err != nil || v == nil || len(v) != len(q) {
+		return 0, false, err
+	}
+
+	return g.distance(q, v), true, nil
+}
+
+func (g *hnswGraph) entry() (id uint64, level int, ok bool, err error) {
+	item, err := g.txn.Get(g.keys.hnswEntry())
+	if err == badger.ErrKeyNotFound {
+		return 0, 0, false, nil
+	} else if err != nil {
+		return 0, 0, false, err
+	}
+
+	err = item.Value(func(val []byte) error {
+		if len(val) != 16 {
+			return ErrCorruptRecord
+		}
+		id = binary.BigEndian.Uint64(val)
+		level = int(binary.BigEndian.Uint64(val[8:]))
+		return nil
+	})
+
+	return id, level, err == nil, err
+}
+
+func (g *hnswGraph) setEntry(id uint64, level int) error {
+	val := binary.BigEndian.AppendUint64(nil, id)
+	val = binary.BigEndian.AppendUint64(val, uint64(level))
+
+	return g.txn.Set(g.keys.hnswEntry(), val)
+}
+
+func (g *hnswGraph) maxLinks(layer int) int {
+	if layer == 0 {
+		return 2 * g.opts.M
+	}
+
+	return g.opts.M
+}
+
+// searchLayer is the greedy beam search from the HNSW paper. It returns up
+// to ef items closest to q on the given layer, closest first.
+func (g *hnswGraph) searchLayer(q []float64, eps []hnswItem, ef, layer int) ([]hnswItem, error) {
+	visited := make(map[uint64]bool, ef*4)
+	candidates := &hnswHeap{}
+	results := &hnswHeap{max: true}
+
+	for _, ep := range eps {
+		visited[ep.id] = true
+		heap.Push(candidates, ep)
+		heap.Push(results, ep)
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswItem)
+		if results.Len() >= ef && c.dist > results.top().dist {
+			break
+		}
+
+		n, err := g.node(c.id)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil || layer > n.level {
+			continue
+		}
+
+		for _, nb := range n.links[layer] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+
+			d, ok, err := g.distanceTo(q, nb)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			if item := (hnswItem{id: nb, dist: d}); results.Len() < ef || item.closer(results.top()) {
+				heap.Push(candidates, item)
+				heap.Push(results, item)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := results.items
+	sort.Slice(out, func(i, j int) bool { return out[i].closer(out[j]) })
+
+	return out, nil
+}
+
+func (g *hnswGraph) randomLevel() int {
+	mL := 1 / math.Log(float64(max(g.opts.M, 2)))
+	return int(math.Floor(-math.Log(1-rand.Float64()) * mL))
+}
+
+func (g *hnswGraph) insert(id uint64, vec []float64) error {
+	g.vecs[id] = vec
+
+	level := g.randomLevel()
+	n := &hnswNode{level: level, links: make([][]uint64, level+1)}
+	g.nodes[id] = n
+	g.dirty[id] = true
+
+	epID, epLevel, ok, err := g.entry()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return g.setEntry(id, level)
+	}
+
+	d, ok, err := g.distanceTo(vec, epID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return g.setEntry(id, level)
+	}
+	eps := []hnswItem{{id: epID, dist: d}}
+
+	for layer := epLevel; layer > level; layer-- {
+		if eps, err = g.searchLayer(vec, eps, 1, layer); err != nil {
+			return err
+		}
+	}
+
+	for layer := min(epLevel, level); layer >= 0; layer-- {
+		found, err := g.searchLayer(vec, eps, g.opts.EfConstruction, layer)
+		if err != nil {
+			return err
+		}
+
+		neighbours := found
+		if len(neighbours) > g.opts.M {
+			neighbours = neighbours[:g.opts.M]
+		}
+
+		n.links[layer] = make([]uint64, len(neighbours))
+		for i, nb := range neighbours {
+			n.links[layer][i] = nb.id
+			if err := g.link(nb.id, id, layer); err != nil {
+				return err
+			}
+		}
+
+		eps = found
+	}
+
+	if level > epLevel {
+		return g.setEntry(id, level)
+	}
+
+	return nil
+}
+
+// link adds a link from one node to another on layer, dropping the
+// furthest links if from ends up with more than it is allowed.
+func (g *hnswGraph) link(from, to uint64, layer int) error {
+	n, err := g.node(from)
+	if err != nil || n == nil || layer > n.level {
+		return err
+	}
+
+	n.links[layer] = append(n.links[layer], to)
+	g.dirty[from] = true
+
+	if len(n.links[layer]) <= g.maxLinks(layer) {
+		return nil
+	}
+
+	vec, err := g.vector(from)
+	if err != nil || vec == nil {
+		return err
+	}
+
+	items := make([]hnswItem, 0, len(n.links[layer]))
+	for _, nb := range n.links[layer] {
+		d, ok, err := g.distanceTo(vec, nb)
+		if err != nil {
+			return err
+		}
+		if ok {
+			items = append(items, hnswItem{id: nb, dist: d})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].closer(items[j]) })
+	if len(items) > g.maxLinks(layer) {
+		items = items[:g.maxLinks(layer)]
+	}
+
+	n.links[layer] = n.links[layer][:0]
+	for _, it := range items {
+		n.links[layer] = append(n.links[layer], it.id)
+	}
+
+	return nil
+}
+
+// remove takes id out of the graph. Links back to it from its neighbours
+// are removed; any other dangling links are skipped during search.
+func (g *hnswGraph) remove(id uint64) error {
+	n, err := g.node(id)
+	if err != nil || n == nil {
+		return err
+	}
+
+	for layer, links := range n.links {
+		for _, nb := range links {
+			nn, err := g.node(nb)
+			if err != nil {
+				return err
+			}
+			if nn == nil || layer > nn.level {
+				continue
+			}
+
+			kept := nn.links[layer][:0]
+			for _, l := range nn.links[layer] {
+				if l != id {
+					kept = append(kept, l)
+				}
+			}
+			nn.links[layer] = kept
+			g.dirty[nb] = true
+		}
+	}
+
+	g.nodes[id] = nil
+	delete(g.dirty, id)
+	delete(g.vecs, id)
+	if err := g.txn.Delete(g.keys.hnswNode(id)); err != nil {
+		return err
+	}
+
+	epID, _, ok, err := g.entry()
+	if err != nil || !ok || epID != id {
+		return err
+	}
+
+	return g.replaceEntry()
+}
+
+// replaceEntry picks the highest level remaining node as the entry point.
+func (g *hnswGraph) replaceEntry() error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = g.keys.hnswNodePrefix()
+	it := g.txn.NewIterator(opts)
+	defer it.Close()
+
+	found := false
+	var best uint64
+	bestLevel := -1
+	for it.Rewind(); it.Valid(); it.Next() {
+		id := binary.BigEndian.Uint64(it.Item().Key()[len(opts.Prefix):])
+		if n, ok := g.nodes[id]; ok && n == nil {
+			continue
+		}
+
+		var level uint64
+		if err := it.Item().Value(func(val []byte) error {
+			level, _ = binary.Uvarint(val)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if int(level) > bestLevel {
+			found, best, bestLevel = true, id, int(level)
+		}
+	}
+
+	if !found {
+		return g.txn.Delete(g.keys.hnswEntry())
+	}
+
+	return g.setEntry(best, bestLevel)
+}
+
+// search returns up to k items closest to q, closest first.
+func (g *hnswGraph) search(q []float64, k, ef int) ([]hnswItem, error) {
+	epID, epLevel, ok, err := g.entry()
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	d, ok, err := g.distanceTo(q, epID)
+	if err != nil || !ok {
+		return nil, err
+	}
+	eps := []hnswItem{{id: epID, dist: d}}
+
+	for layer := epLevel; layer > 0; layer-- {
+		if eps, err = g.searchLayer(q, eps, 1, layer); err != nil {
+			return nil, err
+		}
+	}
+
+	found, err := g.searchLayer(q, eps, max(ef, k), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) > k {
+		found = found[:k]
+	}
+
+	return found, nil
+}
+
+func (g *hnswGraph) flush() error {
+	for id := range g.dirty {
+		if n := g.nodes[id]; n != nil {
+			if err := g.txn.Set(g.keys.hnswNode(id), encodeHNSWNode(n)); err != nil {
+				return err
+			}
+		}
+	}
+
+	g.dirty = make(map[uint64]bool)
+	return nil
+}
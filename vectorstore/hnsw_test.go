@@ -0,0 +1,32 @@
+package vectorstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeHNSWNode(t *testing.T) {
+	n := &hnswNode{level: 1, links: [][]uint64{{2, 3}, {3}}}
+	got, err := decodeHNSWNode(encodeHNSWNode(n))
+	if err != nil || !reflect.DeepEqual(got, n) {
+		t.Errorf("decodeHNSWNode of an encoded node = %+v, %v", got, err)
+	}
+
+	tests := []struct {
+		name string
+		val  []byte
+	}{
+		{"level above the maximum", binary.AppendUvarint(nil, hnswMaxLevel+1)},
+		{"huge level", binary.AppendUvarint(nil, 1<<62)},
+		{"count beyond the value", binary.BigEndian.AppendUint64(binary.AppendUvarint([]byte{0}, 2), 7)},
+		// 2^61 IDs are 0 bytes once multiplied out in 64 bits.
+		{"overflowing count", binary.AppendUvarint([]byte{0}, 1<<61)},
+	}
+	for _, tt := range tests {
+		if _, err := decodeHNSWNode(tt.val); !errors.Is(err, ErrCorruptRecord) {
+			t.Errorf("%s: decodeHNSWNode returned %v, want ErrCorruptRecord", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// IndexType selects the structure used to answer nearest neighbour queries.
+type IndexType uint8
+
+const (
+	// IndexFlat scores every document on every query. It is exact and needs
+	// no extra storage, but query time grows linearly with the index.
+	IndexFlat IndexType = iota
+
+	// IndexHNSW maintains a hierarchical navigable small world graph, which
+	// finds approximate neighbours in roughly logarithmic time. See
+	// HNSWOptions for its tunables.
+	IndexHNSW
+
+	// IndexIVF clusters documents around centroids with k-means and only
+	// scans the clusters nearest the query. The clusters are computed by
+	// BuildIndex; until it has run, queries fall back to a flat scan. See
+	// IVFOptions for its tunables.
+	IndexIVF
+
+	// IndexLSH hashes documents into buckets by which side of random
+	// hyperplanes they fall, and only scores those sharing a bucket with
+	// the query. It approximates angular distance, so it suits
+	// CosineSimilarity. The hyperplanes are drawn by BuildIndex; until it
+	// has run, queries fall back to a flat scan. See LSHOptions for its
+	// tunables.
+	IndexLSH
+)
+
+func (t IndexType) String() string {
+	switch t {
+	case IndexFlat:
+		return "flat"
+	case IndexHNSW:
+		return "hnsw"
+	case IndexIVF:
+		return "ivf"
+	case IndexLSH:
+		return "lsh"
+	default:
+		return fmt.Sprintf("IndexType(%d)", uint8(t))
+	}
+}
+
+const indexBuildBatchSize = 256
+
+// indexAdd links documents that were just written in txn into the index
+// and the packed store.
+func (s *VectorStore) indexAdd(txn *badger.Txn, ids []uint64, vecs [][]float64) error {
+	if packed, err := s.packedEnabled(txn); err != nil {
+		return err
+	} else if packed {
+		if err := packedAdd(txn, s.keys, ids, vecs); err != nil {
+			return err
+		}
+	}
+
+	switch s.opts.Index {
+	case IndexHNSW:
+		return s.hnswAdd(txn, ids, vecs)
+	case IndexIVF:
+		return ivfAdd(txn, s.keys, s.effectiveMetric(s.opts.Metric), ids, vecs)
+	case IndexLSH:
+		return lshAdd(txn, s.keys, ids, vecs)
+	default:
+		return nil
+	}
+}
+
+func (s *VectorStore) hnswAdd(txn *badger.Txn, ids []uint64, vecs [][]float64) error {
+	g := newHNSWGraph(txn, s.keys, s.format(), s.opts.HNSW, s.effectiveMetric(s.opts.Metric))
+	for i, id := range ids {
+		if err := g.insert(id, vecs[i]); err != nil {
+			return err
+		}
+	}
+
+	return g.flush()
+}
+
+// indexRemove drops a document from the index and the packed store.
+func (s *VectorStore) indexRemove(txn *badger.Txn, id uint64) error {
+	if err := packedRemove(txn, s.keys, id); err != nil {
+		return err
+	}
+
+	switch s.opts.Index {
+	case IndexHNSW:
+		g := newHNSWGraph(txn, s.keys, s.format(), s.opts.HNSW, s.effectiveMetric(s.opts.Metric))
+		if err := g.remove(id); err != nil {
+			return err
+		}
+
+		return g.flush()
+	case IndexIVF:
+		return ivfRemove(txn, s.keys, id)
+	case IndexLSH:
+		return lshRemove(txn, s.keys, id)
+	default:
+		return nil
+	}
+}
+
+// indexSearch returns up to q.k candidates, best first. Scans stop with
+// ctx.Err() once ctx is done.
+func (s *VectorStore) indexSearch(ctx context.Context, txn *badger.Txn, q *query) ([]candidate, error) {
+	switch {
+	case q.vector != "" || q.multi != MultiVectorNone:
+		return s.scanVectors(ctx, txn, q)
+	case s.opts.Index == IndexHNSW && q.filter == nil && q.exactness < 1:
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ef := s.opts.HNSW.EfSearch
+		if q.exactness > 0 {
+			ef = q.k + int(q.exactness*hnswExactnessScale*float64(s.opts.HNSW.EfSearch))
+		}
+
+		// The graph was built under the store's metric, so it is walked
+		// under it too and what it finds is ranked by the query's.
+		q.approximate = true
+		g := newHNSWGraph(txn, s.keys, s.format(), s.opts.HNSW, s.effectiveMetric(s.opts.Metric))
+		items, err := g.search(q.target, q.k, ef)
+		if err != nil {
+			return nil, err
+		}
+
+		best := newTopK(q.metric, q.k)
+		for _, it := range items {
+			vec := storedVector{dense: g.vecs[it.id]}
+			best.push(candidate{id: it.id, score: q.score(vec), vec: vec})
+		}
+
+		return best.sorted(), nil
+	case s.opts.Index == IndexIVF:
+		ranked, built, err := s.ivfSearch(ctx, txn, q, s.opts.IVF.NProbe)
+		if built || err != nil {
+			return ranked, err
+		}
+	case s.opts.Index == IndexLSH && q.exactness < 1:
+		ranked, built, err := s.lshSearch(ctx, txn, q)
+		if built || err != nil {
+			return ranked, err
+		}
+	}
+
+	if q.filter == nil {
+		if packed, err := s.packedEnabled(txn); err != nil {
+			return nil, err
+		} else if packed {
+			return s.scanPacked(ctx, txn, q)
+		}
+	}
+
+	return s.scanFlat(ctx, txn, q)
+}
+
+// indexEmpty reports whether the configured index has nothing in it.
+func (s *VectorStore) indexEmpty() (bool, error) {
+	if s.opts.Index != IndexHNSW {
+		return false, nil
+	}
+
+	empty := false
+	err := s.db.View(func(txn *badger.Txn) error {
+		_, _, ok, err := newHNSWGraph(txn, s.keys, s.format(), s.opts.HNSW, s.opts.Metric).entry()
+		empty = !ok
+		return err
+	})
+
+	return empty, err
+}
+
+// BuildIndex discards the configured index and rebuilds it from every
+// stored document; for IVF that means re-clustering and for LSH drawing new
+// hyperplanes. Writes to the database, in any collection, wait for it to
+// finish, so none is missed by the rebuilt index. Searches go on, and may
+// fall back to a flat scan or see a partly built index until it is done.
+func (s *VectorStore) BuildIndex(ctx context.Context) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	s.life.writes.Lock()
+	defer s.life.writes.Unlock()
+
+	switch s.opts.Index {
+	case IndexIVF:
+		return s.buildIVF(ctx)
+	case IndexLSH:
+		return s.buildLSH(ctx)
+	case IndexHNSW:
+		return s.buildHNSW(ctx)
+	default:
+		return nil
+	}
+}
+
+// buildHNSW drops the graph and links every stored document into a new
+// one. The caller holds life.writes.
+func (s *VectorStore) buildHNSW(ctx context.Context) error {
+	if err := s.db.DropPrefix(s.keys.hnswPrefix()); err != nil {
+		return err
+	}
+
+	return s.forEachVectorBatch(ctx, func(ids []uint64, vecs [][]float64) error {
+		return s.commit(ctx, func(txn *badger.Txn) error {
+			return s.hnswAdd(txn, ids, vecs)
+		})
+	})
+}
+
+// forEachVectorBatch calls fn with the IDs and vectors of every stored
+// document, in ID order and batches of up to indexBuildBatchSize.
+func (s *VectorStore) forEachVectorBatch(ctx context.Context, fn func(ids []uint64, vecs [][]float64) error) error {
+	var after uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var ids []uint64
+		var vecs [][]float64
+
+		if err := s.db.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.Prefix = s.keys.docPrefix()
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			for it.Seek(s.keys.doc(after + 1)); it.Valid() && len(ids) < indexBuildBatchSize; it.Next() {
+				var vec storedVector
+				if err := it.Item().Value(func(val []byte) (err error) {
+					vec, err = s.format().decodeVector(val)
+					return
+				}); err != nil {
+					return err
+				}
+
+				ids = append(ids, s.keys.docID(it.Item().Key()))
+				vecs = append(vecs, vec.float64s())
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := fn(ids, vecs); err != nil {
+			return err
+		}
+
+		after = ids[len(ids)-1]
+	}
+}
@@ -0,0 +1,492 @@
+package vectorstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// recall returns the fraction of the flat results that got scores at
+// least as good as the k-th of want, so that ties broken differently by an
+// index still count.
+func recall(m vectorstore.DistanceMetric, want, got []vectorstore.Result) float64 {
+	if len(want) == 0 {
+		return 1
+	}
+
+	worst := want[len(want)-1].Score
+	hits := 0
+	for _, r := range got {
+		if m.HigherIsBetter() && r.Score >= worst || !m.HigherIsBetter() && r.Score <= worst {
+			hits++
+		}
+	}
+
+	return float64(min(hits, len(want))) / float64(len(want))
+}
+
+// checkIndex fills a store opened with opts and a flat one with the same
+// documents and checks that the index finds most of the flat top 10.
+func checkIndex(t *testing.T, opts vectorstore.Options, build bool) *vectorstore.VectorStore {
+	t.Helper()
+	ctx := context.Background()
+
+	texts := numbered(300, 12)
+	flat := openStore(t, memOptions())
+	insertAll(t, flat, texts...)
+
+	s := openStore(t, opts)
+	insertAll(t, s, texts...)
+	if build {
+		if err := s.BuildIndex(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := vectorstore.CosineSimilarity{}
+	for _, query := range []string{"topic3 things", "document 42 about", "about topic11"} {
+		want, err := flat.Nearest(ctx, query, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.Nearest(ctx, query, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := recall(m, want, got); r < 0.8 {
+			t.Errorf("%v: recall for %q is %v", opts.Index, query, r)
+		}
+	}
+
+	return s
+}
+
+func TestHNSW(t *testing.T) {
+	ctx := context.Background()
+	s := checkIndex(t, memOptions().WithIndex(vectorstore.IndexHNSW), false)
+
+	if err := s.BuildIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.Nearest(ctx, "document 42 about topic6 things", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Score < 0.999 {
+		t.Errorf("after BuildIndex, Nearest of a stored text = %+v", results)
+	}
+}
+
+func TestHNSWDelete(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithIndex(vectorstore.IndexHNSW))
+	ids := insertAll(t, s, numbered(50, 5)...)
+
+	for _, id := range ids[:25] {
+		if err := s.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := s.Nearest(ctx, "topic1 things", 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 25 {
+		t.Errorf("after deleting half, Nearest returned %d of 25", len(results))
+	}
+	for _, r := range results {
+		if r.ID <= ids[24] {
+			t.Errorf("deleted document %d was returned", r.ID)
+		}
+	}
+}
+
+func TestIVF(t *testing.T) {
+	opts := memOptions().WithIndex(vectorstore.IndexIVF).WithIVF(vectorstore.IVFOptions{NList: 8, NProbe: 4, Iterations: 10})
+	s := checkIndex(t, opts, true)
+
+	// Documents inserted after the build are added to their closest
+	// cluster.
+	ctx := context.Background()
+	id := insertAll(t, s, "a late arrival")[0]
+	results, err := s.Nearest(ctx, "a late arrival", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != id {
+		t.Errorf("Nearest after the build = %v, want %d", resultIDs(results), id)
+	}
+}
+
+func TestIVFUnbuilt(t *testing.T) {
+	checkIndex(t, memOptions().WithIndex(vectorstore.IndexIVF), false)
+}
+
+func TestLSH(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions(t).WithIndex(vectorstore.IndexLSH).WithLSH(vectorstore.LSHOptions{Hyperplanes: 32, Bands: 16})
+	s := checkIndex(t, opts, true)
+
+	// Documents inserted after the build are hashed into the buckets.
+	id := insertAll(t, s, "entirely new words")[0]
+	results, err := s.Nearest(ctx, "entirely new words", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != id {
+		t.Errorf("Nearest of a text inserted after BuildIndex = %v, want %d", resultIDs(results), id)
+	}
+
+	if _, err := vectorstore.Open(testOptions(t).WithIndex(vectorstore.IndexLSH).WithLSH(vectorstore.LSHOptions{Hyperplanes: 30, Bands: 8}), nil); err == nil {
+		t.Error("Open with invalid LSH options succeeded")
+	}
+}
+
+// TestIVFNProbe reopens a built IVF index probing more clusters each
+// time, which must never lose recall and must match a flat scan once it
+// probes them all.
+// TestIndexMetrics runs searches with every metric at once over indexes
+// built under the store's, each of which must be ranked and scored by its
+// own metric.
+func TestIndexMetrics(t *testing.T) {
+	ctx := context.Background()
+	metrics := []vectorstore.DistanceMetric{
+		vectorstore.CosineSimilarity{},
+		vectorstore.CosineDistance{},
+		vectorstore.EuclideanDistance{},
+		vectorstore.SquaredEuclideanDistance{},
+		vectorstore.DotProduct{},
+		vectorstore.ManhattanDistance{},
+	}
+
+	for _, opts := range []vectorstore.Options{
+		memOptions().WithIndex(vectorstore.IndexHNSW),
+		memOptions().WithIndex(vectorstore.IndexIVF).WithIVF(vectorstore.IVFOptions{NList: 8, NProbe: 4, Iterations: 10}),
+	} {
+		s := openStore(t, opts)
+		insertAll(t, s, numbered(200, 10)...)
+		if err := s.BuildIndex(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		const query = "topic4 things"
+		target, err := vectortest.NewHashEmbedder(testDim).Embed(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4*len(metrics); i++ {
+			m := metrics[i%len(metrics)]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				results, err := s.Search(ctx, query, vectorstore.SearchParams{K: 10, Metric: m, Fields: vectorstore.FieldEmbedding})
+				if err != nil {
+					t.Errorf("%v, %T: %v", opts.Index, m, err)
+					return
+				}
+				if len(results) != 10 {
+					t.Errorf("%v, %T: %d results, want 10", opts.Index, m, len(results))
+				}
+				for j, r := range results {
+					if want := m.Score(target, r.Embedding); math.Abs(r.Score-want) > 1e-9 {
+						t.Errorf("%v, %T: scored %d %v, want %v", opts.Index, m, r.ID, r.Score, want)
+					}
+					if j > 0 && (m.HigherIsBetter() && r.Score > results[j-1].Score || !m.HigherIsBetter() && r.Score < results[j-1].Score) {
+						t.Errorf("%v, %T: results aren't best first: %v then %v", opts.Index, m, results[j-1].Score, r.Score)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestIVFNProbe(t *testing.T) {
+	ctx := context.Background()
+	// Random texts spread over the clusters, so that a query's neighbours
+	// aren't all in its closest one.
+	texts := benchTexts(500)
+	queries := []string{"w1 w2 w3", "w10 w20 w30 w40", "w7", "w500 w999"}
+
+	flat := openStore(t, testOptions(t))
+	insertAll(t, flat, texts...)
+	want := make([][]vectorstore.Result, len(queries))
+	for i, q := range queries {
+		var err error
+		if want[i], err = flat.Nearest(ctx, q, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const nlist = 8
+	ivf := vectorstore.IVFOptions{NList: nlist, NProbe: 1, Iterations: 10}
+	opts := testOptions(t).WithIndex(vectorstore.IndexIVF).WithIVF(ivf)
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, texts...)
+	if err := s.BuildIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	prev := 0.0
+	for nprobe := 1; nprobe <= nlist; nprobe++ {
+		ivf.NProbe = nprobe
+		s := openStore(t, opts.WithIVF(ivf))
+
+		total := 0.0
+		for i, q := range queries {
+			got, err := s.Nearest(ctx, q, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += recall(vectorstore.CosineSimilarity{}, want[i], got)
+		}
+		s.Close()
+
+		r := total / float64(len(queries))
+		if nprobe == 1 && r == 1 {
+			t.Error("a single probe finds every neighbour, so the documents don't exercise NProbe")
+		}
+		if r < prev {
+			t.Errorf("recall fell from %v to %v at NProbe %d", prev, r, nprobe)
+		}
+		prev = r
+	}
+	if prev != 1 {
+		t.Errorf("recall probing all %d clusters is %v, want 1", nlist, prev)
+	}
+}
+
+// TestExactness checks that recall of the flat top 10 rises with
+// Exactness under each index, up to all of it at 1.
+func TestExactness(t *testing.T) {
+	ctx := context.Background()
+	texts := benchTexts(500)
+	queries := []string{"w1 w2 w3", "w10 w20 w30 w40", "w7", "w500 w999"}
+
+	flat := openStore(t, memOptions())
+	insertAll(t, flat, texts...)
+	want := make([][]vectorstore.Result, len(queries))
+	for i, q := range queries {
+		var err error
+		if want[i], err = flat.Nearest(ctx, q, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A sparse graph and a short candidate list leave HNSW room to improve.
+	hnsw := vectorstore.HNSWOptions{M: 4, EfConstruction: 16, EfSearch: 2}
+	ivf := vectorstore.IVFOptions{NList: 8, NProbe: 8, Iterations: 10}
+	for _, index := range []vectorstore.IndexType{vectorstore.IndexFlat, vectorstore.IndexIVF, vectorstore.IndexHNSW} {
+		t.Run(index.String(), func(t *testing.T) {
+			s := openStore(t, memOptions().WithIndex(index).WithHNSW(hnsw).WithIVF(ivf))
+			insertAll(t, s, texts...)
+			if err := s.BuildIndex(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var recalls []float64
+			for _, exactness := range []float64{0.1, 0.3, 0.6, 1} {
+				total := 0.0
+				for i, q := range queries {
+					got, err := s.Search(ctx, q, vectorstore.SearchParams{K: 10, Exactness: exactness})
+					if err != nil {
+						t.Fatal(err)
+					}
+					total += recall(vectorstore.CosineSimilarity{}, want[i], got)
+				}
+				recalls = append(recalls, total/float64(len(queries)))
+			}
+
+			if recalls[0] == 1 {
+				t.Errorf("recall at Exactness 0.1 is already 1, so the documents don't exercise it")
+			}
+			for i := 1; i < len(recalls); i++ {
+				if recalls[i] < recalls[i-1] {
+					t.Errorf("recall fell as Exactness rose: %v", recalls)
+				}
+			}
+			if last := recalls[len(recalls)-1]; last != 1 {
+				t.Errorf("recall at Exactness 1 is %v, want 1", last)
+			}
+		})
+	}
+}
+
+// BenchmarkNearestIndex times queries as the store grows, where the flat
+// scan grows linearly and the HNSW search should stay roughly flat.
+func BenchmarkNearestIndex(b *testing.B) {
+	ctx := context.Background()
+	for _, index := range []vectorstore.IndexType{vectorstore.IndexFlat, vectorstore.IndexHNSW} {
+		for _, n := range []int{1000, 10000, 100000} {
+			b.Run(fmt.Sprintf("%v/%d", index, n), func(b *testing.B) {
+				s := openBench(b, memOptions().WithIndex(index), n)
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if _, err := s.Nearest(ctx, "w1 w2 w3", 10); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestIndexConcurrent inserts and searches while the index is rebuilt,
+// which is only safe if the index structures are guarded. Run it with
+// -race.
+func TestIndexConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	for _, opts := range []vectorstore.Options{
+		memOptions().WithIndex(vectorstore.IndexHNSW),
+		memOptions().WithIndex(vectorstore.IndexIVF).WithIVF(vectorstore.IVFOptions{NList: 8, NProbe: 2, Iterations: 5}),
+		memOptions().WithIndex(vectorstore.IndexLSH).WithLSH(vectorstore.LSHOptions{Hyperplanes: 32, Bands: 8}),
+	} {
+		s := openStore(t, opts)
+		insertAll(t, s, numbered(200, 10)...)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 6)
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					if _, err := s.Insert(ctx, fmt.Sprintf("writer %d text %d", w, i)); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(w)
+		}
+		for r := 0; r < 2; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					if _, err := s.Nearest(ctx, "topic4 things", 5); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+		for i := 0; i < 3; i++ {
+			if err := s.BuildIndex(ctx); err != nil {
+				t.Fatalf("%v: %v", opts.Index, err)
+			}
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("%v: during BuildIndex: %v", opts.Index, err)
+		}
+
+		st, err := s.Stats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Documents != 400 {
+			t.Errorf("%v: %d documents after the concurrent inserts, want 400", opts.Index, st.Documents)
+		}
+
+		// Every document must be reachable through the index: an IVF
+		// search probing every cluster finds each one in a posting list.
+		if opts.Index == vectorstore.IndexIVF {
+			results, err := s.Search(ctx, "writer text", vectorstore.SearchParams{K: 400, Exactness: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 400 {
+				t.Errorf("IVF search of every cluster found %d of 400 documents", len(results))
+			}
+		}
+	}
+}
+
+func TestBuildIndexCancelled(t *testing.T) {
+	for _, index := range []vectorstore.IndexType{vectorstore.IndexHNSW, vectorstore.IndexIVF, vectorstore.IndexLSH} {
+		s := openStore(t, memOptions().WithIndex(index))
+		insertAll(t, s, numbered(20, 4)...)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := s.BuildIndex(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("%v: BuildIndex with a cancelled context returned %v", index, err)
+		}
+	}
+}
+
+func TestSaveLoadIndex(t *testing.T) {
+	ctx := context.Background()
+	texts := numbered(100, 7)
+
+	// A small candidate list makes results depend on the graph searched.
+	hnsw := vectorstore.HNSWOptions{M: 4, EfConstruction: 16, EfSearch: 2}
+	src := openStore(t, memOptions().WithIndex(vectorstore.IndexHNSW).WithHNSW(hnsw))
+	insertAll(t, src, texts...)
+	var buf bytes.Buffer
+	if err := src.SaveIndex(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Populate the destination without an index, load the graph, then
+	// reopen it with one, which must use the graph rather than build its own.
+	opts := testOptions(t).WithGCInterval(0).WithHNSW(hnsw)
+	dst, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, dst, texts...)
+	if err := dst.LoadIndex(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	dst.Close()
+
+	h := newCaptureHandler()
+	dst = openStore(t, opts.WithIndex(vectorstore.IndexHNSW).WithLogger(slog.New(h)))
+	if _, ok := h.find(slog.LevelInfo, "Building index from existing documents"); ok {
+		t.Error("reopening with a loaded graph built the index again")
+	}
+
+	for _, query := range []string{"topic4 things", "document 17", "about topic2"} {
+		want, err := src.Nearest(ctx, query, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := dst.Nearest(ctx, query, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(resultIDs(got)) != fmt.Sprint(resultIDs(want)) {
+			t.Errorf("loaded graph results for %q = %v, saved graph results %v", query, resultIDs(got), resultIDs(want))
+		}
+		if len(got) == 0 || !got[0].Approximate {
+			t.Errorf("results for %q didn't come from the graph: %+v", query, got)
+		}
+	}
+
+	short := openStore(t, memOptions())
+	insertAll(t, short, texts[:50]...)
+	if err := short.LoadIndex(ctx, bytes.NewReader(buf.Bytes())); !errors.Is(err, vectorstore.ErrSnapshotMismatch) {
+		t.Errorf("loading into a store of other documents returned %v, want ErrSnapshotMismatch", err)
+	}
+}
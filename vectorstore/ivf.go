@@ -0,0 +1,252 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// IVFOptions tunes the inverted file index.
+type IVFOptions struct {
+	// NList is the number of clusters the documents are split into when
+	// the index is built. It is capped at the number of documents.
+	NList int
+
+	// NProbe is how many of the closest clusters are scanned per query.
+	// Raising it trades query time for recall; NProbe == NList is exact.
+	NProbe int
+
+	// Iterations caps the k-means iterations run by BuildIndex.
+	Iterations int
+}
+
+func DefaultIVFOptions() IVFOptions {
+	return IVFOptions{
+		NList:      100,
+		NProbe:     8,
+		Iterations: 25,
+	}
+}
+
+func encodeFloat64s(v []float64) []byte {
+	buf := make([]byte, 0, 8*len(v))
+	for _, x := range v {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(x))
+	}
+
+	return buf
+}
+
+func decodeFloat64s(val []byte) ([]float64, error) {
+	if len(val)%8 != 0 {
+		return nil, ErrCorruptRecord
+	}
+
+	v := make([]float64, len(val)/8)
+	for i := range v {
+		v[i] = math.Float64frombits(binary.LittleEndian.Uint64(val[i*8:]))
+	}
+
+	return v, nil
+}
+
+// ivfCentroids loads every centroid, in list order. It returns nil if the
+// index hasn't been built.
+func ivfCentroids(txn *badger.Txn, keys keyspace) ([][]float64, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = keys.ivfCentroidPrefix()
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var centroids [][]float64
+	for it.Rewind(); it.Valid(); it.Next() {
+		var c []float64
+		if err := it.Item().Value(func(val []byte) (err error) {
+			c, err = decodeFloat64s(val)
+			return
+		}); err != nil {
+			return nil, err
+		}
+
+		centroids = append(centroids, c)
+	}
+
+	return centroids, nil
+}
+
+func ivfAssign(txn *badger.Txn, keys keyspace, id uint64, list uint32) error {
+	if err := txn.Set(keys.ivfPosting(list, id), nil); err != nil {
+		return err
+	}
+
+	return txn.Set(keys.ivfAssign(id), binary.BigEndian.AppendUint32(nil, list))
+}
+
+func ivfAdd(txn *badger.Txn, keys keyspace, metric DistanceMetric, ids []uint64, vecs [][]float64) error {
+	centroids, err := ivfCentroids(txn, keys)
+	if err != nil || centroids == nil {
+		return err
+	}
+
+	dist := func(a, b []float64) float64 { return scoreToDistance(metric, metric.Score(a, b)) }
+	for i, id := range ids {
+		if err := ivfAssign(txn, keys, id, uint32(nearestCentroid(centroids, vecs[i], dist))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ivfRemove(txn *badger.Txn, keys keyspace, id uint64) error {
+	item, err := txn.Get(keys.ivfAssign(id))
+	if err == badger.ErrKeyNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var list uint32
+	if err := item.Value(func(val []byte) error {
+		if len(val) != 4 {
+			return ErrCorruptRecord
+		}
+		list = binary.BigEndian.Uint32(val)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := txn.Delete(keys.ivfPosting(list, id)); err != nil {
+		return err
+	}
+
+	return txn.Delete(keys.ivfAssign(id))
+}
+
+// ivfSearch scans the nprobe lists whose centroids are closest to the query.
+// It reports false if the index hasn't been built.
+func (s *VectorStore) ivfSearch(ctx context.Context, txn *badger.Txn, q *query, nprobe int) ([]candidate, bool, error) {
+	centroids, err := ivfCentroids(txn, s.keys)
+	if err != nil || centroids == nil {
+		return nil, false, err
+	}
+
+	// The lists were built under the store's metric, whatever q's is.
+	metric := s.effectiveMetric(s.opts.Metric)
+	lists := make([]hnswItem, len(centroids))
+	for i, c := range centroids {
+		lists[i] = hnswItem{id: uint64(i), dist: scoreToDistance(metric, metric.Score(q.target, c))}
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].dist < lists[j].dist })
+	if q.exactness > 0 {
+		nprobe = int(math.Ceil(q.exactness * float64(len(lists))))
+	}
+	if nprobe < len(lists) {
+		lists = lists[:max(nprobe, 1)]
+		q.approximate = true
+	}
+
+	best := newTopK(q.metric, q.k)
+	n := 0
+	for _, l := range lists {
+		opts := badger.IteratorOptions{Prefix: s.keys.ivfPostingPrefix(uint32(l.id))}
+		it := txn.NewIterator(opts)
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if n++; n%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					it.Close()
+					return nil, false, err
+				}
+			}
+
+			key := it.Item().Key()
+			id := binary.BigEndian.Uint64(key[len(key)-8:])
+			if q.skip(id) {
+				continue
+			}
+
+			item, err := txn.Get(s.keys.doc(id))
+			if err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				it.Close()
+				return nil, false, err
+			}
+
+			var vec storedVector
+			var ok bool
+			if err := item.Value(func(val []byte) (err error) {
+				vec, ok, err = q.decode(val)
+				return
+			}); err != nil {
+				it.Close()
+				return nil, false, err
+			}
+
+			if !ok || vec.dim() != len(q.target) {
+				continue
+			}
+
+			best.push(candidate{id: id, score: q.score(vec), vec: vec})
+		}
+
+		it.Close()
+	}
+
+	return best.sorted(), true, nil
+}
+
+// buildIVF clusters every stored vector and rewrites the centroids and
+// posting lists. The caller holds life.writes.
+func (s *VectorStore) buildIVF(ctx context.Context) error {
+	ids, vecs, err := s.allVectors(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.DropPrefix(s.keys.ivfPrefix()); err != nil {
+		return err
+	}
+
+	metric := s.effectiveMetric(s.opts.Metric)
+	dist := func(a, b []float64) float64 { return scoreToDistance(metric, metric.Score(a, b)) }
+	centroids, assign := kmeans(vecs, s.opts.IVF.NList, s.opts.IVF.Iterations, dist, s.kmeansRand())
+
+	if err := s.commit(ctx, func(txn *badger.Txn) error {
+		for i, c := range centroids {
+			if err := txn.Set(s.keys.ivfCentroid(uint32(i)), encodeFloat64s(c)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(ids); start += indexBuildBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := min(start+indexBuildBatchSize, len(ids))
+		if err := s.commit(ctx, func(txn *badger.Txn) error {
+			for i := start; i < end; i++ {
+				if err := ivfAssign(txn, s.keys, ids[i], uint32(assign[i])); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
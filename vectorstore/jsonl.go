@@ -0,0 +1,200 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// jsonlDocument is one line of an ExportJSONL file.
+type jsonlDocument struct {
+	ID        uint64            `json:"id"`
+	Text      string            `json:"text"`
+	Embedding []float64         `json:"embedding"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Parent    uint64            `json:"parent,omitempty"`
+	CreatedAt int64             `json:"created_at,omitempty"`
+}
+
+// ExportJSONL writes every document to w as one JSON object per line, with
+// its id, text, embedding, metadata, parent and creation time. Documents are
+// streamed in ID order from a single read transaction.
+func (s *VectorStore) ExportJSONL(ctx context.Context, w io.Writer) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	enc := json.NewEncoder(w)
+
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.keys.docPrefix()
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		n := 0
+		for it.Rewind(); it.Valid(); it.Next() {
+			if n++; n%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			var rec record
+			if err := it.Item().Value(func(val []byte) (err error) {
+				rec, err = s.format().decodeRecord(val)
+				return
+			}); err != nil {
+				return err
+			}
+
+			if err := enc.Encode(jsonlDocument{
+				ID:        s.keys.docID(it.Item().Key()),
+				Text:      rec.Text,
+				Embedding: rec.Embedding,
+				Metadata:  rec.Metadata,
+				Parent:    rec.Parent,
+				CreatedAt: rec.CreatedAt,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ImportJSONL reads documents written by ExportJSONL and stores them under
+// their original IDs, replacing any document that already has one. The
+// embeddings are used as they are, without calling the embedder. It returns
+// the number of documents imported.
+func (s *VectorStore) ImportJSONL(ctx context.Context, r io.Reader) (int, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	dec := json.NewDecoder(r)
+
+	n := 0
+	var ids []uint64
+	var recs []record
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		var doc jsonlDocument
+		err := dec.Decode(&doc)
+		if err != nil && err != io.EOF {
+			return n, fmt.Errorf("document %d: %w", n+len(ids)+1, err)
+		}
+
+		if err == nil {
+			if doc.ID == 0 {
+				return n, fmt.Errorf("document %d: missing id", n+len(ids)+1)
+			}
+			if s.opts.Normalize {
+				normalize(doc.Embedding)
+			}
+
+			ids = append(ids, doc.ID)
+			recs = append(recs, record{Text: doc.Text, Metadata: doc.Metadata, Parent: doc.Parent, CreatedAt: doc.CreatedAt, Embedding: doc.Embedding})
+		}
+
+		if len(ids) > 0 && (err == io.EOF || len(ids) >= max(s.opts.BatchSize, 1)) {
+			written, err := s.putRecords(ctx, ids, recs)
+			n += written
+			if err != nil {
+				return n, err
+			}
+			ids, recs = ids[:0], recs[:0]
+		}
+
+		if err == io.EOF {
+			return n, nil
+		}
+	}
+}
+
+// putRecords is putRecordsTxn, splitting recs in halves until each fits in
+// a transaction. It returns how many records were stored, which on error
+// are the first ones.
+func (s *VectorStore) putRecords(ctx context.Context, ids []uint64, recs []record) (int, error) {
+	err := s.putRecordsTxn(ctx, ids, recs)
+	if err == nil {
+		return len(recs), nil
+	}
+	if !errors.Is(err, badger.ErrTxnTooBig) || len(recs) < 2 {
+		return 0, err
+	}
+
+	half := len(recs) / 2
+	n, err := s.putRecords(ctx, ids[:half], recs[:half])
+	if err != nil {
+		return n, err
+	}
+
+	m, err := s.putRecords(ctx, ids[half:], recs[half:])
+	return n + m, err
+}
+
+// putRecordsTxn stores recs under the given IDs in one transaction, replacing
+// existing documents and moving the next ID past them and their parents.
+func (s *VectorStore) putRecordsTxn(ctx context.Context, ids []uint64, recs []record) error {
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		if err := s.markNormalized(txn); err != nil {
+			return err
+		}
+
+		next, err := allocIDs(txn, s.keys, 0)
+		if err != nil {
+			return err
+		}
+
+		vecs := make([][]float64, len(recs))
+		for i, rec := range recs {
+			if err := s.checkDim(txn, len(rec.Embedding)); err != nil {
+				return err
+			}
+
+			if err := s.removeDoc(txn, ids[i]); err != nil && err != ErrNotFound {
+				return err
+			}
+
+			if err := s.setRecord(txn, s.keys.doc(ids[i]), rec); err != nil {
+				return err
+			}
+			if err := s.keywordAdd(txn, ids[i], rec.Text); err != nil {
+				return err
+			}
+			if err := s.chunkAdd(txn, rec.Parent, ids[i]); err != nil {
+				return err
+			}
+
+			vecs[i] = rec.Embedding
+			next = max(next, ids[i]+1, rec.Parent+1)
+		}
+
+		if err := txn.Set(s.keys.meta(metaNextID), binary.BigEndian.AppendUint64(nil, next)); err != nil {
+			return err
+		}
+
+		return s.indexAdd(txn, ids, vecs)
+	}); err != nil {
+		return err
+	}
+	s.opts.Metrics.Inserted(len(recs))
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package vectorstore_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONLRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openStore(t, memOptions())
+	id, err := src.InsertWithMetadata(ctx, "red apples", map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, src, "blue sky")
+
+	var buf bytes.Buffer
+	if err := src.ExportJSONL(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("export has %d lines, want 2", lines)
+	}
+
+	dst := openStore(t, memOptions())
+	n, err := dst.ImportJSONL(ctx, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("ImportJSONL imported %d documents, want 2", n)
+	}
+
+	want, _ := src.Get(ctx, id)
+	got, err := dst.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imported document = %+v, want %+v", got, want)
+	}
+
+	results, err := dst.Nearest(ctx, "blue sky", 1)
+	if err != nil || len(results) != 1 || results[0].Text != "blue sky" {
+		t.Errorf("Nearest on the imported store = %+v, %v", results, err)
+	}
+
+	if ids := insertAll(t, dst, "green grass"); ids[0] != 3 {
+		t.Errorf("the first ID after importing is %d, want 3", ids[0])
+	}
+}
+
+func TestImportJSONLInvalid(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, "sets the dimension")
+
+	bad := []string{
+		"not json\n",
+		`{"id": 1, "text": "short", "embedding": [1, 2]}` + "\n",
+	}
+	for _, in := range bad {
+		if _, err := s.ImportJSONL(ctx, strings.NewReader(in)); err == nil {
+			t.Errorf("ImportJSONL(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package vectorstore
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Key layout, within a keyspace:
+//
+//	!meta/<name>     index wide settings and counters
+//	d/<id>           a document record, id is a big endian uint64
+//	h/entry          the HNSW entry point
+//	h/n/<id>         the HNSW node for a document
+//	i/c/<list>       an IVF centroid, list is a big endian uint32
+//	i/p/<list>/<id>  an IVF posting list entry
+//	i/a/<id>         the IVF list a document is assigned to
+//	l/h/<band>       the hyperplanes of an LSH band, band is a big endian uint32
+//	l/b/<band><bucket>/<id>
+//	                 an LSH bucket entry, bucket is a big endian uint32
+//	l/a/<id>         the LSH buckets a document is in
+//	k/t/<term>/<id>  a keyword posting, the term's count in the document
+//	                 and the document's length
+//	k/d/<id>         the terms of a document in the keyword index
+//	p/s/<segment>    a packed store segment, segment is a big endian uint32
+//	p/a/<id>         the packed segment holding a document's vector
+//	v/<id>/<name>    a named vector of a document, stored as a record
+//	r/<parent>/<id>  a chunk of a parent document, parent is a big endian
+//	                 uint64
+//
+// The default collection's keyspace has no prefix; a named collection's
+// keys are prefixed with c/<name>/.
+//
+// Anything else with a length that is a multiple of 8 is a legacy entry
+// that used the float64 embedding as its key and the text as its value.
+var collectionPrefix = []byte("c/")
+
+// Names of the meta keys.
+const (
+	metaNormalized = "normalized"
+	metaNextID     = "next_id"
+	metaModel      = "model"
+	metaDim        = "dim"
+	metaPooling    = "pooling"
+	metaPacked     = "packed"
+	metaReindex    = "reindex"
+	metaPCA        = "pca"
+	metaKeywords   = "keywords"
+)
+
+// keyspace builds the keys of one collection.
+type keyspace struct {
+	prefix []byte
+}
+
+func collectionKeyspace(name string) keyspace {
+	return keyspace{prefix: append(append(append([]byte{}, collectionPrefix...), name...), '/')}
+}
+
+func (k keyspace) key(suffix string) []byte {
+	return append(append([]byte{}, k.prefix...), suffix...)
+}
+
+func (k keyspace) meta(name string) []byte {
+	return k.key("!meta/" + name)
+}
+
+func (k keyspace) isMeta(key []byte) bool {
+	return bytes.HasPrefix(key, k.key("!meta/"))
+}
+
+func (k keyspace) docPrefix() []byte {
+	return k.key("d/")
+}
+
+func (k keyspace) doc(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.docPrefix(), id)
+}
+
+func (k keyspace) docID(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[len(key)-8:])
+}
+
+func (k keyspace) hnswPrefix() []byte {
+	return k.key("h/")
+}
+
+func (k keyspace) hnswEntry() []byte {
+	return k.key("h/entry")
+}
+
+func (k keyspace) hnswNodePrefix() []byte {
+	return k.key("h/n/")
+}
+
+func (k keyspace) hnswNode(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.hnswNodePrefix(), id)
+}
+
+func (k keyspace) ivfPrefix() []byte {
+	return k.key("i/")
+}
+
+func (k keyspace) ivfCentroidPrefix() []byte {
+	return k.key("i/c/")
+}
+
+func (k keyspace) ivfCentroid(list uint32) []byte {
+	return binary.BigEndian.AppendUint32(k.ivfCentroidPrefix(), list)
+}
+
+func (k keyspace) ivfPostingPrefix(list uint32) []byte {
+	return append(binary.BigEndian.AppendUint32(k.key("i/p/"), list), '/')
+}
+
+func (k keyspace) ivfPosting(list uint32, id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.ivfPostingPrefix(list), id)
+}
+
+func (k keyspace) ivfAssign(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.key("i/a/"), id)
+}
+
+func (k keyspace) lshPrefix() []byte {
+	return k.key("l/")
+}
+
+func (k keyspace) lshPlanesPrefix() []byte {
+	return k.key("l/h/")
+}
+
+func (k keyspace) lshPlanes(band uint32) []byte {
+	return binary.BigEndian.AppendUint32(k.lshPlanesPrefix(), band)
+}
+
+func (k keyspace) lshBucketPrefix(band, bucket uint32) []byte {
+	return append(binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(k.key("l/b/"), band), bucket), '/')
+}
+
+func (k keyspace) lshEntry(band, bucket uint32, id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.lshBucketPrefix(band, bucket), id)
+}
+
+func (k keyspace) lshAssign(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.key("l/a/"), id)
+}
+
+func (k keyspace) keywordPrefix() []byte {
+	return k.key("k/")
+}
+
+func (k keyspace) keywordPostingPrefix(term string) []byte {
+	return append(k.key("k/t/"+term), '/')
+}
+
+func (k keyspace) keywordPosting(term string, id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.keywordPostingPrefix(term), id)
+}
+
+func (k keyspace) keywordDoc(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.key("k/d/"), id)
+}
+
+func (k keyspace) packedPrefix() []byte {
+	return k.key("p/")
+}
+
+func (k keyspace) packedSegmentPrefix() []byte {
+	return k.key("p/s/")
+}
+
+func (k keyspace) packedSegment(index uint32) []byte {
+	return binary.BigEndian.AppendUint32(k.packedSegmentPrefix(), index)
+}
+
+func (k keyspace) packedAssign(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.key("p/a/"), id)
+}
+
+func (k keyspace) vectorPrefix() []byte {
+	return k.key("v/")
+}
+
+func (k keyspace) docVectorPrefix(id uint64) []byte {
+	return append(binary.BigEndian.AppendUint64(k.vectorPrefix(), id), '/')
+}
+
+func (k keyspace) docVector(id uint64, name string) []byte {
+	return append(k.docVectorPrefix(id), name...)
+}
+
+// docVectorKey splits a named vector's key into its document ID and name.
+func (k keyspace) docVectorKey(key []byte) (uint64, string) {
+	key = key[len(k.vectorPrefix()):]
+	return binary.BigEndian.Uint64(key), string(key[9:])
+}
+
+func (k keyspace) chunkPrefix() []byte {
+	return k.key("r/")
+}
+
+func (k keyspace) parentChunkPrefix(parent uint64) []byte {
+	return append(binary.BigEndian.AppendUint64(k.chunkPrefix(), parent), '/')
+}
+
+func (k keyspace) chunk(parent, id uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.parentChunkPrefix(parent), id)
+}
+
+// owns reports whether key belongs to this keyspace rather than to a named
+// collection nested under the default one.
+func (k keyspace) owns(key []byte) bool {
+	if len(k.prefix) == 0 {
+		return !bytes.HasPrefix(key, collectionPrefix)
+	}
+
+	return bytes.HasPrefix(key, k.prefix)
+}
+
+func isLegacyKey(key []byte) bool {
+	if len(key) == 0 || len(key)%8 != 0 {
+		return false
+	}
+
+	var root keyspace
+	for _, p := range [][]byte{root.key("!meta/"), root.docPrefix(), root.hnswPrefix(), root.ivfPrefix(), root.lshPrefix(), root.keywordPrefix(), root.packedPrefix(), root.vectorPrefix(), root.chunkPrefix(), collectionPrefix} {
+		if bytes.HasPrefix(key, p) {
+			return false
+		}
+	}
+
+	return true
+}
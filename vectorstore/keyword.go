@@ -0,0 +1,414 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// BM25 parameters: bm25K1 is how quickly repeats of a term stop adding to
+// a document's score and bm25B how much long documents are penalised.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// rrfK damps the lead the top ranks get in reciprocal rank fusion. 60 is
+// the value from the original paper.
+const rrfK = 60
+
+// hybridDepth is how many times K candidates each of the vector and
+// keyword rankings contributes to a hybrid search.
+const hybridDepth = 4
+
+// maxTermLen is the longest word, in bytes, the keyword index holds.
+const maxTermLen = 64
+
+// tokenize splits text into the lower case words the keyword index holds.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := words[:0]
+	for _, w := range words {
+		if len(w) <= maxTermLen {
+			terms = append(terms, w)
+		}
+	}
+
+	return terms
+}
+
+// keywordStats are the totals BM25 needs: how many documents are in the
+// keyword index and how many terms they hold between them. They are stored
+// as two uvarints.
+type keywordStats struct {
+	docs  uint64
+	terms uint64
+}
+
+func (st keywordStats) encode() []byte {
+	return binary.AppendUvarint(binary.AppendUvarint(nil, st.docs), st.terms)
+}
+
+// getKeywordStats reads the keyword index totals. It reports false if the
+// index hasn't been built.
+func getKeywordStats(txn *badger.Txn, keys keyspace) (keywordStats, bool, error) {
+	val, err := getMeta(txn, keys.meta(metaKeywords))
+	if err != nil || val == nil {
+		return keywordStats{}, false, err
+	}
+
+	docs, l := binary.Uvarint(val)
+	if l <= 0 {
+		return keywordStats{}, false, ErrCorruptRecord
+	}
+
+	terms, l2 := binary.Uvarint(val[l:])
+	if l2 <= 0 || l+l2 != len(val) {
+		return keywordStats{}, false, ErrCorruptRecord
+	}
+
+	return keywordStats{docs: docs, terms: terms}, true, nil
+}
+
+// keywordAdd indexes the words of text under id when Options.Keywords is
+// set.
+func (s *VectorStore) keywordAdd(txn *badger.Txn, id uint64, text string) error {
+	if !s.opts.Keywords {
+		return nil
+	}
+
+	stats, _, err := getKeywordStats(txn, s.keys)
+	if err != nil {
+		return err
+	}
+
+	words := tokenize(text)
+	counts := make(map[string]uint64)
+	for _, w := range words {
+		counts[w]++
+	}
+
+	terms := make([]string, 0, len(counts))
+	for t := range counts {
+		terms = append(terms, t)
+	}
+	sort.Strings(terms)
+
+	doc := binary.AppendUvarint(nil, uint64(len(words)))
+	for _, t := range terms {
+		posting := binary.AppendUvarint(binary.AppendUvarint(nil, counts[t]), uint64(len(words)))
+		if err := txn.Set(s.keys.keywordPosting(t, id), posting); err != nil {
+			return err
+		}
+
+		doc = appendString(doc, t)
+	}
+
+	if err := txn.Set(s.keys.keywordDoc(id), doc); err != nil {
+		return err
+	}
+
+	stats.docs++
+	stats.terms += uint64(len(words))
+	return txn.Set(s.keys.meta(metaKeywords), stats.encode())
+}
+
+// keywordRemove drops a document from the keyword index, if it is there.
+func (s *VectorStore) keywordRemove(txn *badger.Txn, id uint64) error {
+	if !s.opts.Keywords {
+		return nil
+	}
+
+	val, err := getMeta(txn, s.keys.keywordDoc(id))
+	if err != nil || val == nil {
+		return err
+	}
+
+	length, l := binary.Uvarint(val)
+	if l <= 0 {
+		return ErrCorruptRecord
+	}
+
+	for rest := val[l:]; len(rest) > 0; {
+		term, r, ok := readString(rest)
+		if !ok {
+			return ErrCorruptRecord
+		}
+		rest = r
+
+		if err := txn.Delete(s.keys.keywordPosting(string(term), id)); err != nil {
+			return err
+		}
+	}
+
+	if err := txn.Delete(s.keys.keywordDoc(id)); err != nil {
+		return err
+	}
+
+	stats, _, err := getKeywordStats(txn, s.keys)
+	if err != nil {
+		return err
+	}
+
+	stats.docs--
+	stats.terms -= min(length, stats.terms)
+	return txn.Set(s.keys.meta(metaKeywords), stats.encode())
+}
+
+// keywordSearch ranks the documents containing any word of text by BM25,
+// best first, and returns up to n of those passing filter.
+func (s *VectorStore) keywordSearch(ctx context.Context, txn *badger.Txn, text string, filter func(map[string]string) bool, n int) ([]candidate, error) {
+	stats, _, err := getKeywordStats(txn, s.keys)
+	if err != nil || stats.docs == 0 {
+		return nil, err
+	}
+
+	avgLen := float64(stats.terms) / float64(stats.docs)
+	scores := make(map[uint64]float64)
+	seen := make(map[string]bool)
+	read := 0
+	for _, term := range tokenize(text) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		type posting struct {
+			id          uint64
+			count, size float64
+		}
+		var postings []posting
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.keys.keywordPostingPrefix(term)
+		it := txn.NewIterator(opts)
+		for it.Rewind(); it.Valid(); it.Next() {
+			if read++; read%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					it.Close()
+					return nil, err
+				}
+			}
+
+			var p posting
+			if err := it.Item().Value(func(val []byte) error {
+				count, l := binary.Uvarint(val)
+				if l <= 0 {
+					return ErrCorruptRecord
+				}
+				size, l2 := binary.Uvarint(val[l:])
+				if l2 <= 0 {
+					return ErrCorruptRecord
+				}
+
+				p = posting{id: s.keys.docID(it.Item().Key()), count: float64(count), size: float64(size)}
+				return nil
+			}); err != nil {
+				it.Close()
+				return nil, err
+			}
+
+			postings = append(postings, p)
+		}
+		it.Close()
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (float64(stats.docs)-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			norm := bm25K1 * (1 - bm25B + bm25B*p.size/avgLen)
+			scores[p.id] += idf * p.count * (bm25K1 + 1) / (p.count + norm)
+		}
+	}
+
+	ranked := make([]candidate, 0, len(scores))
+	for id, score := range scores {
+		ranked = append(ranked, candidate{id: id, score: score})
+	}
+	sortFused(ranked)
+
+	if filter == nil {
+		return ranked[:min(n, len(ranked))], nil
+	}
+
+	kept := ranked[:0]
+	for _, c := range ranked {
+		if len(kept) == n {
+			break
+		}
+
+		item, err := txn.Get(s.keys.doc(c.id))
+		if err == badger.ErrKeyNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var rec record
+		if err := item.Value(func(val []byte) error {
+			return s.format().decodeFields(val, &rec)
+		}); err != nil {
+			return nil, err
+		}
+
+		if filter(rec.Metadata) {
+			kept = append(kept, c)
+		}
+	}
+
+	return kept, nil
+}
+
+// fuseRankings merges the vector and keyword rankings by reciprocal rank
+// fusion, weighting the keyword ranks by weight and the vector ranks by
+// 1-weight, and returns the best k.
+func fuseRankings(vector, keyword []candidate, weight float64, k int) []candidate {
+	fused := make(map[uint64]*candidate)
+	add := func(ranked []candidate, w float64) {
+		for i, c := range ranked {
+			f, ok := fused[c.id]
+			if !ok {
+				f = &candidate{id: c.id}
+				fused[c.id] = f
+			}
+			if f.vec.dim() == 0 {
+				f.vec = c.vec
+			}
+
+			f.score += w / float64(rrfK+i+1)
+		}
+	}
+	add(vector, 1-weight)
+	add(keyword, weight)
+
+	ranked := make([]candidate, 0, len(fused))
+	for _, c := range fused {
+		ranked = append(ranked, *c)
+	}
+	sortFused(ranked)
+
+	return ranked[:min(k, len(ranked))]
+}
+
+// fusedRelevance maps a fused score onto [0, 1]. The best possible score is
+// a first place in both rankings, 1/(rrfK+1).
+func fusedRelevance(score float64) float64 {
+	return clamp01(score * (rrfK + 1))
+}
+
+// sortFused sorts candidates by descending score, then ascending ID.
+func sortFused(ranked []candidate) {
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+
+		return ranked[i].id < ranked[j].id
+	})
+}
+
+// buildKeywords discards the keyword index and rebuilds it from every
+// stored document.
+func (s *VectorStore) buildKeywords(ctx context.Context) error {
+	if err := s.dropPrefix(s.keys.keywordPrefix()); err != nil {
+		return err
+	}
+
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		return txn.Set(s.keys.meta(metaKeywords), keywordStats{}.encode())
+	}); err != nil {
+		return err
+	}
+
+	var after uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var ids []uint64
+		var texts []string
+
+		if err := s.db.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.Prefix = s.keys.docPrefix()
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			for it.Seek(s.keys.doc(after + 1)); it.Valid() && len(ids) < indexBuildBatchSize; it.Next() {
+				if err := it.Item().Value(func(val []byte) error {
+					text, err := s.format().decodeText(val)
+					texts = append(texts, text)
+					return err
+				}); err != nil {
+					return err
+				}
+
+				ids = append(ids, s.keys.docID(it.Item().Key()))
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := s.update(ctx, func(txn *badger.Txn) error {
+			for i, id := range ids {
+				if err := s.keywordAdd(txn, id, texts[i]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		after = ids[len(ids)-1]
+	}
+}
+
+// syncKeywords builds the keyword index when Options.Keywords is switched
+// on, and drops it when it is switched off, since it would not be kept up
+// to date.
+func (s *VectorStore) syncKeywords() error {
+	if s.opts.ReadOnly {
+		return nil
+	}
+
+	var built bool
+	if err := s.db.View(func(txn *badger.Txn) (err error) {
+		_, built, err = getKeywordStats(txn, s.keys)
+		return
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case s.opts.Keywords && !built:
+		s.log.Info("Building keyword index from existing documents")
+		return s.buildKeywords(context.Background())
+	case !s.opts.Keywords && built:
+		s.log.Info("Dropping keyword index, Keywords is off")
+		if err := s.dropPrefix(s.keys.keywordPrefix()); err != nil {
+			return err
+		}
+
+		return s.update(context.Background(), func(txn *badger.Txn) error {
+			return txn.Delete(s.keys.meta(metaKeywords))
+		})
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package vectorstore_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+func TestHybridSearch(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithKeywords(true))
+
+	// "red" and "green" hash to the same bucket, so the vectors of the
+	// query and of "green" are the same, but only one document has the word.
+	texts := append(numbered(30, 3), "green", "red apples in the orchard")
+	ids := insertAll(t, s, texts...)
+	similar, lexical := ids[len(ids)-2], ids[len(ids)-1]
+
+	results, err := s.Search(ctx, "red", vectorstore.SearchParams{K: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].ID != similar {
+		t.Fatalf("vector search = %v, want the similar vector %d first", resultIDs(results), similar)
+	}
+
+	results, err = s.Search(ctx, "red", vectorstore.SearchParams{K: 3, KeywordWeight: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].ID != lexical {
+		t.Errorf("hybrid search = %v, want the keyword match %d first", resultIDs(results), lexical)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i-1].Score < results[i].Score {
+			t.Errorf("fused scores aren't best first: %v then %v", results[i-1].Score, results[i].Score)
+		}
+	}
+	checkRelevance(t, "hybrid", results)
+
+	if err := s.Delete(ctx, lexical); err != nil {
+		t.Fatal(err)
+	}
+	results, err = s.Search(ctx, "orchard", vectorstore.SearchParams{K: 3, KeywordWeight: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.ID == lexical {
+			t.Error("a deleted document was found by keyword")
+		}
+	}
+}
+
+func TestKeywordsOnOpen(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions(t).WithGCInterval(0)
+
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := insertAll(t, s, "red apples", "quokka island", "blue sky")
+	s.Close()
+
+	h := newCaptureHandler()
+	s, err = vectorstore.Open(opts.WithKeywords(true).WithLogger(slog.New(h)), vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.find(slog.LevelInfo, "Building keyword index from existing documents"); !ok {
+		t.Error("reopening with Keywords didn't log building the index")
+	}
+	results, err := s.Search(ctx, "quokka", vectorstore.SearchParams{K: 1, KeywordWeight: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != ids[1] {
+		t.Errorf("after building the keyword index on Open, search = %v, want %d", resultIDs(results), ids[1])
+	}
+	s.Close()
+
+	openStore(t, opts.WithLogger(slog.New(h)))
+	if _, ok := h.find(slog.LevelInfo, "Dropping keyword index, Keywords is off"); !ok {
+		t.Error("reopening without Keywords didn't log dropping the index")
+	}
+
+	if _, err := openStore(t, memOptions()).Search(ctx, "x", vectorstore.SearchParams{K: 1, KeywordWeight: 0.5}); err == nil {
+		t.Error("hybrid search without Options.Keywords succeeded")
+	}
+}
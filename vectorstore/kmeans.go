@@ -0,0 +1,138 @@
+package vectorstore
+
+import "math/rand"
+
+// kmeans clusters vecs into k groups with Lloyd's algorithm, seeding the
+// centroids by k-means++. It returns the centroids and the index of the
+// centroid each vector was assigned to.
+func kmeans(vecs [][]float64, k, iterations int, dist func(a, b []float64) float64, rng *rand.Rand) ([][]float64, []int) {
+	if k > len(vecs) {
+		k = len(vecs)
+	}
+	if k == 0 {
+		return nil, nil
+	}
+
+	dim := len(vecs[0])
+	centroids := kmeansPlusPlus(vecs, k, rng)
+
+	assign := make([]int, len(vecs))
+	for iter := 0; iter < iterations; iter++ {
+		if !reassign(centroids, vecs, assign, dist) && iter > 0 {
+			return centroids, assign
+		}
+
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for i := range sums {
+			sums[i] = make([]float64, dim)
+		}
+		for i, v := range vecs {
+			c := assign[i]
+			counts[c]++
+			for j, x := range v {
+				sums[c][j] += x
+			}
+		}
+
+		for c := range centroids {
+			// An empty cluster keeps its old centroid rather than collapsing
+			// to the origin.
+			if counts[c] == 0 {
+				continue
+			}
+			for j := range sums[c] {
+				centroids[c][j] = sums[c][j] / float64(counts[c])
+			}
+		}
+	}
+
+	reassign(centroids, vecs, assign, dist)
+
+	return centroids, assign
+}
+
+// kmeansPlusPlus picks k distinct vectors as initial centroids, the first
+// at random and each after with probability proportional to its squared
+// distance from the nearest one picked, which spreads them over the data.
+// Squared Euclidean distance is used whatever the metric, since the
+// weights must not be negative. Once every vector left duplicates a pick,
+// the rest are chosen at random.
+func kmeansPlusPlus(vecs [][]float64, k int, rng *rand.Rand) [][]float64 {
+	picked := make([]bool, len(vecs))
+	weights := make([]float64, len(vecs))
+	centroids := make([][]float64, 0, k)
+
+	next := rng.Intn(len(vecs))
+	for {
+		picked[next] = true
+		c := append([]float64(nil), vecs[next]...)
+		if centroids = append(centroids, c); len(centroids) == k {
+			return centroids
+		}
+
+		total := 0.0
+		for i, v := range vecs {
+			if picked[i] {
+				weights[i] = 0
+				continue
+			}
+
+			d := squaredEuclideanDistance(c, v)
+			if len(centroids) == 1 || d < weights[i] {
+				weights[i] = d
+			}
+			total += weights[i]
+		}
+
+		next = -1
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, w := range weights {
+				if w == 0 {
+					continue
+				}
+
+				// Rounding may leave target short of zero at the end, so
+				// the last candidate is kept.
+				if next = i; target-w < 0 {
+					break
+				}
+				target -= w
+			}
+		}
+		if next < 0 {
+			for _, i := range rng.Perm(len(vecs)) {
+				if !picked[i] {
+					next = i
+					break
+				}
+			}
+		}
+	}
+}
+
+// reassign moves every vector to its nearest centroid and reports whether
+// any of them changed cluster.
+func reassign(centroids, vecs [][]float64, assign []int, dist func(a, b []float64) float64) bool {
+	changed := false
+	for i, v := range vecs {
+		if c := nearestCentroid(centroids, v, dist); c != assign[i] {
+			assign[i] = c
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func nearestCentroid(centroids [][]float64, v []float64, dist func(a, b []float64) float64) int {
+	best, bestDist := 0, 0.0
+	for i, c := range centroids {
+		if d := dist(c, v); i == 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,62 @@
+package vectorstore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logger receives the store's log messages, each with optional alternating
+// keys and values. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// withLogger adds args to every message logged through it.
+type withLogger struct {
+	log  Logger
+	args []any
+}
+
+func (l withLogger) with(args []any) []any {
+	return append(append([]any{}, l.args...), args...)
+}
+
+func (l withLogger) Debug(msg string, args ...any) { l.log.Debug(msg, l.with(args)...) }
+func (l withLogger) Info(msg string, args ...any)  { l.log.Info(msg, l.with(args)...) }
+func (l withLogger) Warn(msg string, args ...any)  { l.log.Warn(msg, l.with(args)...) }
+func (l withLogger) Error(msg string, args ...any) { l.log.Error(msg, l.with(args)...) }
+
+// badgerLogger passes Badger's printf style messages on to a Logger.
+type badgerLogger struct {
+	log Logger
+}
+
+func badgerMessage(f string, v []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintf(f, v...), "\n")
+}
+
+func (l *badgerLogger) Errorf(f string, v ...interface{}) {
+	l.log.Error(badgerMessage(f, v))
+}
+
+func (l *badgerLogger) Warningf(f string, v ...interface{}) {
+	l.log.Warn(badgerMessage(f, v))
+}
+
+func (l *badgerLogger) Infof(f string, v ...interface{}) {
+	l.log.Info(badgerMessage(f, v))
+}
+
+func (l *badgerLogger) Debugf(f string, v ...interface{}) {
+	l.log.Debug(badgerMessage(f, v))
+}
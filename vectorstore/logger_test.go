@@ -0,0 +1,103 @@
+package vectorstore_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// captureHandler is a slog.Handler that keeps every record it handles.
+type captureHandler struct {
+	mu      *sync.Mutex
+	records *[]slog.Record
+}
+
+func newCaptureHandler() captureHandler {
+	return captureHandler{mu: &sync.Mutex{}, records: &[]slog.Record{}}
+}
+
+func (h captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	*h.records = append(*h.records, r.Clone())
+	return nil
+}
+
+func (h captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h captureHandler) WithGroup(string) slog.Handler      { return h }
+
+// find returns the attributes of the first record with msg at level, and
+// whether there was one.
+func (h captureHandler) find(level slog.Level, msg string) (map[string]string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range *h.records {
+		if r.Level != level || r.Message != msg {
+			continue
+		}
+
+		attrs := make(map[string]string)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.String()
+			return true
+		})
+		return attrs, true
+	}
+
+	return nil, false
+}
+
+// logged reports whether any record carries the attribute key=value.
+func (h captureHandler) logged(key, value string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range *h.records {
+		found := false
+		r.Attrs(func(a slog.Attr) bool {
+			found = a.Key == key && a.Value.String() == value
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestLogger(t *testing.T) {
+	h := newCaptureHandler()
+	opts := testOptions(t).WithGCInterval(0).WithNormalize(true).WithLogger(slog.New(h))
+
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, "red apples", "blue sky")
+
+	// Text without words embeds to zeros, which can't be normalized.
+	insertAll(t, s, "!!!")
+	if attrs, ok := h.find(slog.LevelWarn, "Embedding is a zero vector, storing it unnormalized"); !ok || attrs["text"] != "!!!" {
+		t.Errorf("the zero vector warning was %v with %v", ok, attrs)
+	}
+	s.Close()
+
+	// Reopening with an index builds it over the stored documents.
+	openStore(t, opts.WithIndex(vectorstore.IndexHNSW))
+	if attrs, ok := h.find(slog.LevelInfo, "Building index from existing documents"); !ok || attrs["index"] != "hnsw" {
+		t.Errorf("the index build message was %v with %v", ok, attrs)
+	}
+
+	if !h.logged("pkg", "badger") {
+		t.Error("none of Badger's messages reached the logger")
+	}
+}
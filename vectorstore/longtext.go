@@ -0,0 +1,191 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LongTextPolicy is what inserts do with text that has more tokens than
+// the embedder takes. Models either cut such text short or fail, so its
+// embedding doesn't represent all of it.
+type LongTextPolicy uint8
+
+const (
+	// LongTextReject fails the insert with ErrTextTooLong.
+	LongTextReject LongTextPolicy = iota
+	// LongTextWarn logs a warning and embeds the text anyway.
+	LongTextWarn
+	// LongTextChunk splits the text into parts that fit, on sentence ends
+	// where it can, and stores them like InsertChunked, returning the
+	// parent ID in place of the text's, which Delete takes to remove them
+	// all. Update keeps the first chunk under the document's ID.
+	LongTextChunk
+	// LongTextAverage splits the text the way LongTextChunk does, embeds
+	// each part and stores the text as one document with the mean of
+	// their embeddings, weighted by Options.LongTextWeighted.
+	LongTextAverage
+)
+
+func (p LongTextPolicy) String() string {
+	switch p {
+	case LongTextReject:
+		return "reject"
+	case LongTextWarn:
+		return "warn"
+	case LongTextChunk:
+		return "chunk"
+	case LongTextAverage:
+		return "average"
+	default:
+		return fmt.Sprintf("LongTextPolicy(%d)", uint8(p))
+	}
+}
+
+// ParseLongTextPolicy returns the LongTextPolicy named by s, as printed by
+// LongTextPolicy.String.
+func ParseLongTextPolicy(s string) (LongTextPolicy, error) {
+	for _, p := range []LongTextPolicy{LongTextReject, LongTextWarn, LongTextChunk, LongTextAverage} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown long text policy %q", s)
+}
+
+// tokenLimiter is implemented by embedders that take a limited number of
+// tokens.
+type tokenLimiter interface {
+	// MaxTokens is the longest input the embedder takes, or 0 for no
+	// limit.
+	MaxTokens() int
+
+	// CountTokens returns the length of text as the embedder sees it.
+	CountTokens(text string) int
+}
+
+// tooLong reports whether text has more tokens than the embedder takes,
+// returning its length and the limit.
+func (s *VectorStore) tooLong(text string) (n, limit int, long bool) {
+	t, ok := s.e.(tokenLimiter)
+	if !ok {
+		return 0, 0, false
+	}
+
+	if limit = t.MaxTokens(); limit <= 0 {
+		return 0, 0, false
+	}
+
+	n = t.CountTokens(text)
+	return n, limit, n > limit
+}
+
+// checkLength applies Options.LongText to text about to be embedded for
+// storage, where the caller hasn't split it.
+func (s *VectorStore) checkLength(text string) error {
+	n, limit, long := s.tooLong(text)
+	if !long {
+		return nil
+	}
+
+	if s.opts.LongText != LongTextWarn {
+		return fmt.Errorf("%w: %d tokens, the model takes %d", ErrTextTooLong, n, limit)
+	}
+
+	s.log.Warn("Text is longer than the model takes, its embedding may not cover all of it", "tokens", n, "max", limit)
+	return nil
+}
+
+// embedAveraged embeds text, which is too long for the embedder, as the
+// mean of the embeddings of the parts fitChunks splits it into, projected
+// through the PCA after averaging.
+func (s *VectorStore) embedAveraged(ctx context.Context, text string) ([]float64, error) {
+	t := s.e.(tokenLimiter)
+	overhead := t.CountTokens("")
+
+	var sum []float64
+	total := 0.0
+	for _, part := range s.fitChunks([]string{text}) {
+		vec, err := s.embedText(ctx, part)
+		if err != nil {
+			return nil, err
+		}
+
+		weight := 1.0
+		if s.opts.LongTextWeighted {
+			weight = float64(max(t.CountTokens(part)-overhead, 1))
+		}
+
+		if sum == nil {
+			sum = make([]float64, len(vec))
+		}
+		for i, x := range vec {
+			sum[i] += weight * x
+		}
+		total += weight
+	}
+
+	for i := range sum {
+		sum[i] /= total
+	}
+
+	if p := s.pca.Load(); p != nil {
+		return p.project(sum)
+	}
+
+	return sum, nil
+}
+
+// fitChunks returns chunks with every one that is too long for the
+// embedder split into parts that fit, packing whole sentences where it can
+// and falling back to words.
+func (s *VectorStore) fitChunks(chunks []string) []string {
+	t, ok := s.e.(tokenLimiter)
+	if !ok || t.MaxTokens() <= 0 {
+		return chunks
+	}
+
+	// The per text overhead, such as BERT's [CLS] and [SEP], is counted
+	// once per part rather than once per piece.
+	overhead := t.CountTokens("")
+	budget := t.MaxTokens() - overhead
+	size := func(text string) int { return t.CountTokens(text) - overhead }
+
+	var fitted []string
+	for _, chunk := range chunks {
+		if size(chunk) <= budget {
+			fitted = append(fitted, chunk)
+			continue
+		}
+
+		var pieces []string
+		for _, sentence := range splitSentences(chunk) {
+			if size(sentence) <= budget {
+				pieces = append(pieces, sentence)
+			} else {
+				pieces = append(pieces, strings.Fields(sentence)...)
+			}
+		}
+
+		var part []string
+		used := 0
+		for _, p := range pieces {
+			n := size(p)
+			if len(part) > 0 && used+n > budget {
+				fitted = append(fitted, strings.Join(part, " "))
+				part, used = part[:0], 0
+			}
+
+			// A single word too long to fit is kept whole, and rejected
+			// when it is embedded.
+			part = append(part, p)
+			used += n
+		}
+		if len(part) > 0 {
+			fitted = append(fitted, strings.Join(part, " "))
+		}
+	}
+
+	return fitted
+}
@@ -0,0 +1,213 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// limitedEmbedder is a HashEmbedder that takes at most max words, cutting
+// off the rest as a model would.
+type limitedEmbedder struct {
+	*vectortest.HashEmbedder
+	max int
+}
+
+func (e limitedEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if words := strings.Fields(text); len(words) > e.max {
+		text = strings.Join(words[:e.max], " ")
+	}
+
+	return e.HashEmbedder.Embed(ctx, text)
+}
+
+func (e limitedEmbedder) MaxTokens() int { return e.max }
+
+func (e limitedEmbedder) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func openLimited(t *testing.T, opts vectorstore.Options, max int) *vectorstore.VectorStore {
+	t.Helper()
+
+	return openStoreWith(t, opts, limitedEmbedder{vectortest.NewHashEmbedder(testDim), max})
+}
+
+const longText = "Red apples grow. Blue skies shine bright. Green grass grows tall today."
+
+func TestLongTextReject(t *testing.T) {
+	ctx := context.Background()
+	s := openLimited(t, memOptions(), 5)
+
+	if _, err := s.Insert(ctx, longText); !errors.Is(err, vectorstore.ErrTextTooLong) {
+		t.Errorf("Insert of long text returned %v, want ErrTextTooLong", err)
+	}
+	if _, err := s.Insert(ctx, "short enough"); err != nil {
+		t.Errorf("Insert of short text: %v", err)
+	}
+}
+
+func TestLongTextWarn(t *testing.T) {
+	h := newCaptureHandler()
+	s := openLimited(t, memOptions().WithLongText(vectorstore.LongTextWarn).WithLogger(slog.New(h)), 5)
+	insertAll(t, s, longText)
+
+	attrs, ok := h.find(slog.LevelWarn, "Text is longer than the model takes, its embedding may not cover all of it")
+	if !ok || attrs["tokens"] != "12" || attrs["max"] != "5" {
+		t.Errorf("the long text warning was %v with %v", ok, attrs)
+	}
+}
+
+func TestLongTextChunk(t *testing.T) {
+	ctx := context.Background()
+	s := openLimited(t, memOptions().WithLongText(vectorstore.LongTextChunk), 5)
+
+	parent := insertAll(t, s, longText)[0]
+	n, err := s.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("long text was stored as %d chunks, want 3", n)
+	}
+
+	results, err := s.Nearest(ctx, "blue skies", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Parent != parent || results[0].Text != "Blue skies shine bright." {
+		t.Errorf("Nearest(blue skies) = %+v, want the second chunk of %d", results, parent)
+	}
+
+	// Update keeps the first chunk under the document's ID and adds the
+	// rest to its parent.
+	if err := s.Update(ctx, results[0].ID, longText); err != nil {
+		t.Fatalf("Update with long text: %v", err)
+	}
+	if doc, err := s.Get(ctx, results[0].ID); err != nil || doc.Text != "Red apples grow." || doc.Parent != parent {
+		t.Errorf("the updated chunk = %+v, %v, want the first chunk of %d", doc, err, parent)
+	}
+	if n, err := s.Count(ctx); err != nil || n != 5 {
+		t.Errorf("after Update, Count = %d, %v, want 5", n, err)
+	}
+
+	// A document without a parent is given one.
+	id := insertAll(t, s, "short text")[0]
+	if err := s.Update(ctx, id, longText); err != nil {
+		t.Fatalf("Update of an unchunked document with long text: %v", err)
+	}
+	doc, err := s.Get(ctx, id)
+	if err != nil || doc.Parent == 0 {
+		t.Fatalf("the updated document = %+v, %v, want a parent", doc, err)
+	}
+
+	if err := s.Delete(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, doc.Parent); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Count(ctx); err != nil || n != 0 {
+		t.Errorf("after deleting both parents, Count = %d, %v, want 0", n, err)
+	}
+}
+
+func TestLongTextAverage(t *testing.T) {
+	ctx := context.Background()
+	s := openLimited(t, memOptions().WithLongText(vectorstore.LongTextAverage), 5)
+
+	id := insertAll(t, s, longText)[0]
+	doc, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != longText {
+		t.Errorf("averaged document has text %q", doc.Text)
+	}
+	if n, _ := s.Count(ctx); n != 1 {
+		t.Errorf("long text was stored as %d documents, want 1", n)
+	}
+
+	if err := s.Update(ctx, id, longText+" Again."); err != nil {
+		t.Errorf("Update with long text: %v", err)
+	}
+}
+
+// meanEmbedding is the mean of the HashEmbedder embeddings of parts,
+// weighted by weights.
+func meanEmbedding(t *testing.T, parts []string, weights []float64) []float64 {
+	t.Helper()
+
+	mean := make([]float64, testDim)
+	total := 0.0
+	for i, part := range parts {
+		vec, err := vectortest.NewHashEmbedder(testDim).Embed(context.Background(), part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, x := range vec {
+			mean[j] += weights[i] * x
+		}
+		total += weights[i]
+	}
+	for j := range mean {
+		mean[j] /= total
+	}
+
+	return mean
+}
+
+func TestLongTextAverageEmbedding(t *testing.T) {
+	ctx := context.Background()
+	parts := []string{"Red apples grow.", "Blue skies shine bright.", "Green grass grows tall today."}
+
+	embedding := func(opts vectorstore.Options) []float64 {
+		t.Helper()
+		s := openLimited(t, opts, 5)
+		doc, err := s.Get(ctx, insertAll(t, s, longText)[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return doc.Embedding
+	}
+	near := func(a, b []float64) bool {
+		for i := range a {
+			if math.Abs(a[i]-b[i]) > 1e-9 {
+				return false
+			}
+		}
+
+		return len(a) == len(b)
+	}
+
+	truncated := embedding(memOptions().WithLongText(vectorstore.LongTextWarn))
+	averaged := embedding(memOptions().WithLongText(vectorstore.LongTextAverage))
+	weighted := embedding(memOptions().WithLongText(vectorstore.LongTextAverage).WithLongTextWeighted(true))
+
+	if near(averaged, truncated) {
+		t.Error("the averaged embedding is the truncated one")
+	}
+	if want := meanEmbedding(t, parts, []float64{1, 1, 1}); !near(averaged, want) {
+		t.Errorf("averaged embedding = %v, want the mean of the parts' %v", averaged, want)
+	}
+	if want := meanEmbedding(t, parts, []float64{3, 4, 5}); !near(weighted, want) {
+		t.Errorf("weighted embedding = %v, want the parts' weighted by length %v", weighted, want)
+	}
+}
+
+func TestParseLongTextPolicy(t *testing.T) {
+	for _, p := range []vectorstore.LongTextPolicy{vectorstore.LongTextReject, vectorstore.LongTextWarn, vectorstore.LongTextChunk, vectorstore.LongTextAverage} {
+		got, err := vectorstore.ParseLongTextPolicy(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParseLongTextPolicy(%q) = %v, %v", p.String(), got, err)
+		}
+	}
+	if _, err := vectorstore.ParseLongTextPolicy("truncate"); err == nil {
+		t.Error("ParseLongTextPolicy of an unknown name succeeded")
+	}
+}
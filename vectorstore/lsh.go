@@ -0,0 +1,237 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// LSHOptions tunes the locality sensitive hashing index.
+type LSHOptions struct {
+	// Hyperplanes is the number of random hyperplanes each vector is
+	// hashed against, one signature bit per hyperplane.
+	Hyperplanes int
+
+	// Bands is how many groups the signature bits are split into. A
+	// document is a candidate for a query if all the bits of any band
+	// match, so for a given number of hyperplanes more bands raise recall
+	// and the number of documents scored. Hyperplanes must be a multiple
+	// of Bands, with at most 32 hyperplanes per band.
+	Bands int
+}
+
+func DefaultLSHOptions() LSHOptions {
+	return LSHOptions{
+		Hyperplanes: 128,
+		Bands:       16,
+	}
+}
+
+func (o LSHOptions) validate() error {
+	if o.Hyperplanes < 1 || o.Bands < 1 || o.Hyperplanes%o.Bands != 0 || o.Hyperplanes/o.Bands > 32 {
+		return fmt.Errorf("LSH needs a positive number of hyperplanes that is a multiple of bands, with at most 32 per band, got %d hyperplanes and %d bands", o.Hyperplanes, o.Bands)
+	}
+
+	return nil
+}
+
+// lshPlanes loads the hyperplanes of each band, in band order, each band's
+// flattened into one slice. It returns nil if the index hasn't been built.
+func lshPlanes(txn *badger.Txn, keys keyspace) ([][]float64, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = keys.lshPlanesPrefix()
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var bands [][]float64
+	for it.Rewind(); it.Valid(); it.Next() {
+		var planes []float64
+		if err := it.Item().Value(func(val []byte) (err error) {
+			planes, err = decodeFloat64s(val)
+			return
+		}); err != nil {
+			return nil, err
+		}
+
+		bands = append(bands, planes)
+	}
+
+	return bands, nil
+}
+
+// lshBuckets hashes vec into one bucket per band, setting a bit of the
+// bucket for each of the band's hyperplanes vec lies on the positive side of.
+func lshBuckets(bands [][]float64, vec []float64) ([]uint32, error) {
+	buckets := make([]uint32, len(bands))
+	for b, planes := range bands {
+		if len(planes)%len(vec) != 0 {
+			return nil, fmt.Errorf("%w: LSH hyperplanes don't have dimension %d", ErrDimensionMismatch, len(vec))
+		}
+
+		for r := 0; r*len(vec) < len(planes); r++ {
+			if dot(planes[r*len(vec):(r+1)*len(vec)], vec) >= 0 {
+				buckets[b] |= 1 << r
+			}
+		}
+	}
+
+	return buckets, nil
+}
+
+func lshAssign(txn *badger.Txn, keys keyspace, id uint64, buckets []uint32) error {
+	assign := make([]byte, 0, 4*len(buckets))
+	for b, bucket := range buckets {
+		if err := txn.Set(keys.lshEntry(uint32(b), bucket, id), nil); err != nil {
+			return err
+		}
+		assign = binary.BigEndian.AppendUint32(assign, bucket)
+	}
+
+	return txn.Set(keys.lshAssign(id), assign)
+}
+
+func lshAdd(txn *badger.Txn, keys keyspace, ids []uint64, vecs [][]float64) error {
+	bands, err := lshPlanes(txn, keys)
+	if err != nil || bands == nil {
+		return err
+	}
+
+	for i, id := range ids {
+		buckets, err := lshBuckets(bands, vecs[i])
+		if err != nil {
+			return err
+		}
+
+		if err := lshAssign(txn, keys, id, buckets); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func lshRemove(txn *badger.Txn, keys keyspace, id uint64) error {
+	val, err := getMeta(txn, keys.lshAssign(id))
+	if err != nil || val == nil {
+		return err
+	}
+	if len(val)%4 != 0 {
+		return ErrCorruptRecord
+	}
+
+	for b := 0; b < len(val)/4; b++ {
+		if err := txn.Delete(keys.lshEntry(uint32(b), binary.BigEndian.Uint32(val[4*b:]), id)); err != nil {
+			return err
+		}
+	}
+
+	return txn.Delete(keys.lshAssign(id))
+}
+
+// lshSearch scores the documents sharing a bucket with the query in any
+// band. It reports false if the index hasn't been built.
+func (s *VectorStore) lshSearch(ctx context.Context, txn *badger.Txn, q *query) ([]candidate, bool, error) {
+	bands, err := lshPlanes(txn, s.keys)
+	if err != nil || bands == nil {
+		return nil, false, err
+	}
+
+	buckets, err := lshBuckets(bands, q.target)
+	if err != nil {
+		return nil, false, err
+	}
+
+	q.approximate = true
+	best := newTopK(q.metric, q.k)
+	seen := make(map[uint64]bool)
+	n := 0
+	for b, bucket := range buckets {
+		opts := badger.IteratorOptions{Prefix: s.keys.lshBucketPrefix(uint32(b), bucket)}
+		it := txn.NewIterator(opts)
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if n++; n%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					it.Close()
+					return nil, false, err
+				}
+			}
+
+			key := it.Item().Key()
+			id := binary.BigEndian.Uint64(key[len(key)-8:])
+			if seen[id] || q.skip(id) {
+				continue
+			}
+			seen[id] = true
+
+			item, err := txn.Get(s.keys.doc(id))
+			if err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				it.Close()
+				return nil, false, err
+			}
+
+			var vec storedVector
+			var ok bool
+			if err := item.Value(func(val []byte) (err error) {
+				vec, ok, err = q.decode(val)
+				return
+			}); err != nil {
+				it.Close()
+				return nil, false, err
+			}
+
+			if !ok || vec.dim() != len(q.target) {
+				continue
+			}
+
+			best.push(candidate{id: id, score: q.score(vec), vec: vec})
+		}
+
+		it.Close()
+	}
+
+	return best.sorted(), true, nil
+}
+
+// buildLSH draws new hyperplanes and rehashes every stored vector into the
+// buckets. The hyperplanes take the dimension of the first vector. The
+// caller holds life.writes.
+func (s *VectorStore) buildLSH(ctx context.Context) error {
+	if err := s.db.DropPrefix(s.keys.lshPrefix()); err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewSource(rand.Int63()))
+	rows := s.opts.LSH.Hyperplanes / s.opts.LSH.Bands
+	drawn := false
+
+	return s.forEachVectorBatch(ctx, func(ids []uint64, vecs [][]float64) error {
+		return s.commit(ctx, func(txn *badger.Txn) error {
+			if !drawn {
+				dim := len(vecs[0])
+				for b := 0; b < s.opts.LSH.Bands; b++ {
+					planes := make([]float64, rows*dim)
+					for i := range planes {
+						planes[i] = rng.NormFloat64()
+					}
+
+					if err := txn.Set(s.keys.lshPlanes(uint32(b)), encodeFloat64s(planes)); err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := lshAdd(txn, s.keys, ids, vecs); err != nil {
+				return err
+			}
+
+			drawn = true
+			return nil
+		})
+	})
+}
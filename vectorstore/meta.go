@@ -0,0 +1,177 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// getMeta returns the value of a meta key, or nil if it isn't set.
+func getMeta(txn *badger.Txn, key []byte) ([]byte, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return item.ValueCopy(nil)
+}
+
+// storedDim returns the recorded dimension of the stored embeddings, or 0
+// if none have been stored.
+func storedDim(txn *badger.Txn, keys keyspace) (int, error) {
+	val, err := getMeta(txn, keys.meta(metaDim))
+	if err != nil || val == nil {
+		return 0, err
+	}
+	if len(val) != 4 {
+		return 0, ErrCorruptRecord
+	}
+
+	return int(binary.BigEndian.Uint32(val)), nil
+}
+
+// checkSchema verifies the index was built with the configured model and
+// embedding dimension, and records the dimension of stores that predate
+// it.
+//
+// While a Reindex is unfinished, the schema is only checked against the
+// model being reindexed to, if the store is opened with it.
+func (s *VectorStore) checkSchema() error {
+	run := func(fn func(txn *badger.Txn) error) error {
+		return s.update(context.Background(), fn)
+	}
+	if s.opts.ReadOnly {
+		run = s.db.View
+	}
+
+	return run(func(txn *badger.Txn) error {
+		pending, err := pendingReindex(txn, s.keys)
+		if err != nil {
+			return err
+		}
+
+		if pending != nil && pending.model == s.opts.ModelName {
+			if pending.dim != 0 && s.e.Dim() != 0 && s.e.Dim() != pending.dim {
+				return fmt.Errorf("%w: reindexing to %d dimensions, got %d", ErrDimensionMismatch, pending.dim, s.e.Dim())
+			}
+
+			return nil
+		}
+
+		model, err := getMeta(txn, s.keys.meta(metaModel))
+		if err != nil {
+			return err
+		}
+
+		if model != nil && s.opts.ModelName != "" && string(model) != s.opts.ModelName {
+			return fmt.Errorf("%w: built with %q, opened with %q", ErrModelMismatch, model, s.opts.ModelName)
+		}
+
+		dim, err := getMeta(txn, s.keys.meta(metaDim))
+		if err != nil {
+			return err
+		}
+
+		if err := s.checkPooling(txn, dim != nil); err != nil {
+			return err
+		}
+
+		if dim != nil {
+			want, err := s.storedEmbeddingDim()
+			if err != nil || want == 0 {
+				return err
+			}
+
+			return s.checkDim(txn, want)
+		}
+
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: s.keys.docPrefix()})
+		defer it.Close()
+
+		it.Rewind()
+		if !it.Valid() {
+			return nil
+		}
+
+		var vec storedVector
+		if err := it.Item().Value(func(val []byte) (err error) {
+			vec, err = s.format().decodeVector(val)
+			return
+		}); err != nil {
+			return err
+		}
+
+		return s.checkDim(txn, vec.dim())
+	})
+}
+
+// checkDim returns ErrDimensionMismatch unless dim matches the embeddings
+// already stored. The first call on an empty index records dim, along
+// with the model name.
+func (s *VectorStore) checkDim(txn *badger.Txn, dim int) error {
+	val, err := getMeta(txn, s.keys.meta(metaDim))
+	if err != nil {
+		return err
+	}
+
+	if val == nil {
+		if p, ok := s.e.(pooler); ok {
+			if err := txn.Set(s.keys.meta(metaPooling), []byte{byte(p.Pooling())}); err != nil {
+				return err
+			}
+		}
+
+		if s.opts.ModelName != "" {
+			if err := txn.Set(s.keys.meta(metaModel), []byte(s.opts.ModelName)); err != nil {
+				return err
+			}
+		}
+
+		return txn.Set(s.keys.meta(metaDim), binary.BigEndian.AppendUint32(nil, uint32(dim)))
+	}
+
+	if len(val) != 4 {
+		return ErrCorruptRecord
+	}
+
+	if want := int(binary.BigEndian.Uint32(val)); dim != want {
+		return fmt.Errorf("%w: index has %d dimensions, got %d", ErrDimensionMismatch, want, dim)
+	}
+
+	return nil
+}
+
+// checkPooling refuses an embedder that pools differently from the one the
+// index was built with. Indexes that predate the pooling key were always
+// mean pooled.
+func (s *VectorStore) checkPooling(txn *badger.Txn, populated bool) error {
+	p, ok := s.e.(pooler)
+	if !ok {
+		return nil
+	}
+
+	val, err := getMeta(txn, s.keys.meta(metaPooling))
+	if err != nil {
+		return err
+	}
+
+	stored := PoolingMean
+	switch {
+	case len(val) == 1:
+		stored = Pooling(val[0])
+	case val != nil:
+		return ErrCorruptRecord
+	case !populated:
+		return nil
+	}
+
+	if stored != p.Pooling() {
+		return fmt.Errorf("index was built with %v pooling, cannot open it with %v", stored, p.Pooling())
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package vectorstore
+
+import "math"
+
+// DistanceMetric scores how close two embeddings are. HigherIsBetter reports
+// whether a larger score means a closer match (similarities) or a smaller
+// one does (distances).
+type DistanceMetric interface {
+	Score(a, b []float64) float64
+	HigherIsBetter() bool
+}
+
+type CosineSimilarity struct{}
+
+func (CosineSimilarity) Score(a, b []float64) float64 { return cosineSimilarity(a, b) }
+func (CosineSimilarity) HigherIsBetter() bool         { return true }
+
+// CosineDistance is 1 - CosineSimilarity, from 0 for the same direction to
+// 2 for opposite ones, for consumers that expect a distance. It ranks
+// results the same as CosineSimilarity, lowest first.
+type CosineDistance struct{}
+
+func (CosineDistance) Score(a, b []float64) float64 { return 1 - cosineSimilarity(a, b) }
+func (CosineDistance) HigherIsBetter() bool         { return false }
+
+type EuclideanDistance struct{}
+
+func (EuclideanDistance) Score(a, b []float64) float64 { return euclideanDistance(a, b) }
+func (EuclideanDistance) HigherIsBetter() bool         { return false }
+
+// SquaredEuclideanDistance orders results the same as EuclideanDistance but
+// skips the square root, so prefer it when only the ranking matters.
+type SquaredEuclideanDistance struct{}
+
+func (SquaredEuclideanDistance) Score(a, b []float64) float64 { return squaredEuclideanDistance(a, b) }
+func (SquaredEuclideanDistance) HigherIsBetter() bool         { return false }
+
+type DotProduct struct{}
+
+func (DotProduct) Score(a, b []float64) float64 { return dotProduct(a, b) }
+func (DotProduct) HigherIsBetter() bool         { return true }
+
+type ManhattanDistance struct{}
+
+func (ManhattanDistance) Score(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += math.Abs(a[i] - b[i])
+	}
+
+	return sum
+}
+func (ManhattanDistance) HigherIsBetter() bool { return false }
+
+// scoreToDistance maps a score onto a scale where lower is always closer.
+// It is its own inverse.
+func scoreToDistance(m DistanceMetric, score float64) float64 {
+	if m.HigherIsBetter() {
+		return -score
+	}
+
+	return score
+}
+
+// relevance maps a score under m onto [0, 1], where 1 is the closest
+// match: a cosine similarity s as (s+1)/2, a cosine distance d as 1-d/2,
+// other distances as 1/(1+d) and other similarities, which are unbounded,
+// through the logistic function.
+func relevance(m DistanceMetric, score float64) float64 {
+	switch m.(type) {
+	case CosineSimilarity:
+		return clamp01((score + 1) / 2)
+	case CosineDistance:
+		return clamp01(1 - score/2)
+	}
+
+	if m.HigherIsBetter() {
+		return logistic(score)
+	}
+
+	return 1 / (1 + max(score, 0))
+}
+
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// clamp01 limits x to [0, 1], absorbing rounding in scores that should
+// already be within it.
+func clamp01(x float64) float64 {
+	return min(max(x, 0), 1)
+}
+
+// better reports whether score a ranks ahead of score b under m.
+func better(m DistanceMetric, a, b float64) bool {
+	if m.HigherIsBetter() {
+		return a > b
+	}
+
+	return a < b
+}
+
+func dotProduct(a, b []float64) float64 {
+	return dot(a, b)
+}
+
+// dotScalar is the portable fallback for dot.
+func dotScalar(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+
+	return sum
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	dotProduct := dot(a, b)
+	magnitudeA := dot(a, a)
+	magnitudeB := dot(b, b)
+
+	if magnitudeA == 0 || magnitudeB == 0 {
+		return 0
+	}
+
+	return dotProduct / (math.Sqrt(magnitudeA) * math.Sqrt(magnitudeB))
+}
+
+func squaredEuclideanDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+
+	return sum
+}
+
+func euclideanDistance(a, b []float64) float64 {
+	return math.Sqrt(squaredEuclideanDistance(a, b))
+}
+
+// normalize scales v to unit length in place. Zero vectors have no
+// direction, so they are left as they are.
+func normalize(v []float64) bool {
+	sum := 0.0
+	for _, x := range v {
+		sum += x * x
+	}
+
+	if sum == 0 {
+		return false
+	}
+
+	inv := 1.0 / math.Sqrt(sum)
+	for i := range v {
+		v[i] *= inv
+	}
+
+	return true
+}
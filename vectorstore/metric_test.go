@@ -0,0 +1,220 @@
+package vectorstore
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"orthogonal", []float64{1, 0}, []float64{0, 3}, 0},
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{"scaled", []float64{1, 2, 3}, []float64{2, 4, 6}, 1},
+		{"opposite", []float64{1, -2}, []float64{-1, 2}, -1},
+		{"zero", []float64{1, 2}, []float64{0, 0}, 0},
+		{"known", []float64{1, 2, 3}, []float64{4, -5, 6}, 12 / (math.Sqrt(14) * math.Sqrt(77))},
+	}
+	for _, tt := range tests {
+		if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s: cosineSimilarity(%v, %v) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// rank returns the indexes of data, closest to target first under m.
+func rank(m DistanceMetric, target []float64, data [][]float64) []int {
+	order := make([]int, len(data))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return better(m, m.Score(target, data[order[i]]), m.Score(target, data[order[j]]))
+	})
+
+	return order
+}
+
+func TestMetricRanking(t *testing.T) {
+	target := []float64{1, 0}
+	data := [][]float64{
+		{-1, 0},
+		{3, 0.5},
+		{0, 1.2},
+		{1, 0.1},
+	}
+
+	tests := []struct {
+		metric DistanceMetric
+		want   []int
+	}{
+		// Cosine ignores magnitude, so the long vector along the target
+		// ranks behind the short one closer in angle.
+		{CosineSimilarity{}, []int{3, 1, 2, 0}},
+		{CosineDistance{}, []int{3, 1, 2, 0}},
+		{EuclideanDistance{}, []int{3, 2, 0, 1}},
+		{SquaredEuclideanDistance{}, []int{3, 2, 0, 1}},
+		// The dot product rewards magnitude along the target.
+		{DotProduct{}, []int{1, 3, 2, 0}},
+		{ManhattanDistance{}, []int{3, 0, 2, 1}},
+	}
+	for _, tt := range tests {
+		if got := rank(tt.metric, target, data); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%T ranks %v, want %v", tt.metric, got, tt.want)
+		}
+	}
+}
+
+// unitVectors returns n random vectors of length one with dim components.
+func unitVectors(r *rand.Rand, n, dim int) [][]float64 {
+	vecs := make([][]float64, n)
+	for i := range vecs {
+		v := make([]float64, dim)
+		norm := 0.0
+		for j := range v {
+			v[j] = r.NormFloat64()
+			norm += v[j] * v[j]
+		}
+		for j := range v {
+			v[j] /= math.Sqrt(norm)
+		}
+		vecs[i] = v
+	}
+
+	return vecs
+}
+
+func TestEuclideanMatchesCosineWhenNormalized(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	data := unitVectors(r, 50, 8)
+	target := unitVectors(r, 1, 8)[0]
+
+	cosine := rank(CosineSimilarity{}, target, data)
+	for _, m := range []DistanceMetric{EuclideanDistance{}, SquaredEuclideanDistance{}} {
+		if got := rank(m, target, data); !reflect.DeepEqual(got, cosine) {
+			t.Errorf("%T ranks unit vectors %v, cosine ranks them %v", m, got, cosine)
+		}
+	}
+}
+
+func TestDotProductMatchesCosineWhenNormalized(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	data := unitVectors(r, 50, 8)
+	target := unitVectors(r, 1, 8)[0]
+
+	for i, v := range data {
+		if cos, dot := cosineSimilarity(target, v), dotProduct(target, v); math.Abs(cos-dot) > 1e-12 {
+			t.Errorf("vector %d: cosine %v, dot product %v", i, cos, dot)
+		}
+	}
+}
+
+func TestDotScalar(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	vecs := unitVectors(r, 20, 387)
+	for i := 1; i < len(vecs); i++ {
+		a, b := vecs[i-1], vecs[i]
+		if got, want := dot(a, b), dotScalar(a, b); math.Abs(got-want) > 1e-12 {
+			t.Errorf("dot = %v, dotScalar = %v", got, want)
+		}
+	}
+}
+
+// benchmarkMetric scores a query against 10k normalized 384 dimension
+// vectors, the size of the default model's embeddings.
+func benchmarkMetric(b *testing.B, m DistanceMetric) {
+	r := rand.New(rand.NewSource(1))
+	data := unitVectors(r, 10000, 384)
+	target := unitVectors(r, 1, 384)[0]
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, v := range data {
+			m.Score(target, v)
+		}
+	}
+}
+
+func BenchmarkCosineSimilarity(b *testing.B) { benchmarkMetric(b, CosineSimilarity{}) }
+func BenchmarkDotProduct(b *testing.B)       { benchmarkMetric(b, DotProduct{}) }
+
+// benchmarkDot takes the dot product of a query with 50k 384 dimension
+// vectors.
+func benchmarkDot(b *testing.B, dot func(a, b []float64) float64) {
+	r := rand.New(rand.NewSource(1))
+	data := unitVectors(r, 50000, 384)
+	target := unitVectors(r, 1, 384)[0]
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, v := range data {
+			dot(target, v)
+		}
+	}
+}
+
+func BenchmarkDot(b *testing.B)       { benchmarkDot(b, dot) }
+func BenchmarkDotScalar(b *testing.B) { benchmarkDot(b, dotScalar) }
+
+func TestNormalize(t *testing.T) {
+	v := []float64{3, 4}
+	if !normalize(v) || math.Abs(v[0]-0.6) > 1e-12 || math.Abs(v[1]-0.8) > 1e-12 {
+		t.Errorf("normalize(3, 4) = %v", v)
+	}
+
+	zero := []float64{0, 0}
+	if normalize(zero) || zero[0] != 0 || zero[1] != 0 {
+		t.Errorf("normalize changed a zero vector to %v", zero)
+	}
+}
+
+func TestScoreToDistance(t *testing.T) {
+	for _, m := range []DistanceMetric{CosineSimilarity{}, EuclideanDistance{}} {
+		for _, score := range []float64{-1, 0, 0.5, 2} {
+			if got := scoreToDistance(m, scoreToDistance(m, score)); got != score {
+				t.Errorf("scoreToDistance isn't its own inverse for %T: %v -> %v", m, score, got)
+			}
+		}
+	}
+
+	if !better(CosineSimilarity{}, 0.9, 0.1) || better(EuclideanDistance{}, 0.9, 0.1) {
+		t.Error("better ranks scores the wrong way round")
+	}
+}
+
+func TestRelevance(t *testing.T) {
+	for _, m := range []DistanceMetric{
+		CosineSimilarity{},
+		CosineDistance{},
+		EuclideanDistance{},
+		SquaredEuclideanDistance{},
+		DotProduct{},
+		ManhattanDistance{},
+	} {
+		checkRelevance(t, m, m.HigherIsBetter(), func(score float64) float64 { return relevance(m, score) })
+	}
+	checkRelevance(t, "fused", true, fusedRelevance)
+}
+
+// checkRelevance checks that rel maps scores from -3 to 3 onto [0, 1] and
+// never ranks a worse score above a better one.
+func checkRelevance(t *testing.T, name any, higherIsBetter bool, rel func(float64) float64) {
+	t.Helper()
+	prev := math.NaN()
+	for score := -3.0; score <= 3; score += 0.125 {
+		r := rel(score)
+		if r < 0 || r > 1 {
+			t.Errorf("%v: relevance(%v) = %v, outside [0, 1]", name, score, r)
+		}
+		if !math.IsNaN(prev) && (higherIsBetter && r < prev || !higherIsBetter && r > prev) {
+			t.Errorf("%v: relevance(%v) = %v after %v for a worse score", name, score, r, prev)
+		}
+		prev = r
+	}
+}
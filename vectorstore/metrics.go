@@ -0,0 +1,25 @@
+package vectorstore
+
+import "time"
+
+// Metrics receives measurements of a VectorStore's work. Options.Metrics
+// discards them by default; package metrics exports them to Prometheus.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Inserted is called with the number of documents a write stored.
+	Inserted(n int)
+
+	// Encoded is called with the time the embedder took for one text.
+	// Cache hits aren't reported.
+	Encoded(d time.Duration)
+
+	// Searched is called with how long a search took, including encoding
+	// the query.
+	Searched(d time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Inserted(int)           {}
+func (nopMetrics) Encoded(time.Duration)  {}
+func (nopMetrics) Searched(time.Duration) {}
@@ -0,0 +1,50 @@
+package vectorstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics counts the measurements it receives.
+type recordingMetrics struct {
+	mu                          sync.Mutex
+	inserted, encoded, searched int
+}
+
+func (m *recordingMetrics) Inserted(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inserted += n
+}
+
+func (m *recordingMetrics) Encoded(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.encoded++
+}
+
+func (m *recordingMetrics) Searched(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.searched++
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := &recordingMetrics{}
+	s := openStore(t, memOptions().WithMetrics(m).WithCacheSize(8))
+
+	insertAll(t, s, "red apples", "blue sky", "red apples")
+	if _, err := s.InsertBatch(ctx, []string{"green grass", "yellow sun"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Nearest(ctx, "red", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.inserted != 5 || m.encoded != 5 || m.searched != 1 {
+		t.Errorf("metrics counted %d inserted, %d encoded and %d searched, want 5, 5 and 1", m.inserted, m.encoded, m.searched)
+	}
+}
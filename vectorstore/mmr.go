@@ -0,0 +1,77 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// MMRReranker reorders results by maximal marginal relevance, trading
+// relevance to the query against similarity to the results already chosen,
+// so that near duplicates don't crowd out the rest. Results are chosen one
+// at a time, each maximising
+//
+//	Lambda*relevance - (1-Lambda)*max similarity to those chosen before
+//
+// where relevance is the result's Score and similarity is the cosine
+// similarity of embeddings. Relevance should therefore be a metric where
+// higher is better, such as CosineSimilarity. Use it with a RerankDepth
+// above K, so there are candidates to promote. The scores returned are the
+// marginal relevance each result had when it was chosen.
+type MMRReranker struct {
+	// Lambda is how much relevance counts against diversity, from 0 for
+	// diversity alone to 1 for relevance alone.
+	Lambda float64
+}
+
+// Rerank implements Reranker.
+func (r MMRReranker) Rerank(ctx context.Context, query string, results []Result) ([]float64, error) {
+	if r.Lambda < 0 || r.Lambda > 1 {
+		return nil, fmt.Errorf("MMR lambda must be between 0 and 1, got %v", r.Lambda)
+	}
+
+	scores := make([]float64, len(results))
+	chosen := make([]bool, len(results))
+
+	// redundancy[i] is the highest similarity of result i to a chosen one.
+	redundancy := make([]float64, len(results))
+
+	last := -1
+	for n := 0; n < len(results); n++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		best, bestScore := -1, math.Inf(-1)
+		for i, res := range results {
+			if chosen[i] {
+				continue
+			}
+
+			if last >= 0 {
+				redundancy[i] = max(redundancy[i], embeddingSimilarity(res.Embedding, results[last].Embedding))
+			}
+
+			score := r.Lambda*res.Score - (1-r.Lambda)*redundancy[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		chosen[best] = true
+		scores[best] = bestScore
+		last = best
+	}
+
+	return scores, nil
+}
+
+// embeddingSimilarity is the cosine similarity of a and b, or 0 if either
+// is missing.
+func embeddingSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	return cosineSimilarity(a, b)
+}
@@ -0,0 +1,63 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestMMRReranker(t *testing.T) {
+	ctx := context.Background()
+	results := []vectorstore.Result{
+		{ID: 1, Score: 0.9, Embedding: []float64{1, 0}},
+		{ID: 2, Score: 0.89, Embedding: []float64{1, 0}},
+		{ID: 3, Score: 0.5, Embedding: []float64{0, 1}},
+	}
+
+	scores, err := vectorstore.MMRReranker{Lambda: 0.5}.Rerank(ctx, "", results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(scores[0] > scores[2] && scores[2] > scores[1]) {
+		t.Errorf("scores = %v, want the near duplicate 2 demoted below 3", scores)
+	}
+
+	scores, err = vectorstore.MMRReranker{Lambda: 1}.Rerank(ctx, "", results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if scores[i] != r.Score {
+			t.Errorf("with Lambda 1, scores = %v, want the relevance alone", scores)
+			break
+		}
+	}
+
+	if _, err := (vectorstore.MMRReranker{Lambda: 1.5}).Rerank(ctx, "", results); err == nil {
+		t.Error("Rerank with Lambda above 1 succeeded")
+	}
+}
+
+func TestMMRSearch(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	ids := insertAll(t, s, "red apples", "red apples", "red apples today", "red sky")
+	distinct := ids[3]
+
+	results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].ID == distinct {
+		t.Fatalf("plain search = %v, want two near duplicates", resultIDs(results))
+	}
+
+	results, err = s.Search(ctx, "red apples", vectorstore.SearchParams{K: 2, Reranker: vectorstore.MMRReranker{Lambda: 0.3}, RerankDepth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].ID != distinct {
+		t.Errorf("MMR search = %v, want the distinct %d promoted to second", resultIDs(results), distinct)
+	}
+}
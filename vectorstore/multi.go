@@ -0,0 +1,97 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// SearchBatch returns the k stored texts closest to each of queries, best
+// first, in the order of queries. All the queries are embedded first and
+// then scored together in a single scan of the stored documents, which
+// decodes each one once rather than once per query. The scan is exact
+// whatever the configured index.
+func (s *VectorStore) SearchBatch(ctx context.Context, queries []string, k int) ([][]Result, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+	defer s.observeSearch(time.Now())
+
+	qs := make([]*query, len(queries))
+	for i, text := range queries {
+		q, err := s.newSearch(ctx, text, SearchParams{K: k})
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		qs[i] = q
+	}
+
+	results := make([][]Result, len(qs))
+	if err := s.db.View(func(txn *badger.Txn) error {
+		ranked, err := s.scanFlatBatch(ctx, txn, qs)
+		if err != nil {
+			return err
+		}
+
+		for i := range ranked {
+			if results[i], err = s.fetchResults(txn, qs[i], ranked[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// scanFlatBatch is scanFlat for several queries at once.
+func (s *VectorStore) scanFlatBatch(ctx context.Context, txn *badger.Txn, qs []*query) ([][]candidate, error) {
+	best := make([]*topK, len(qs))
+	for i, q := range qs {
+		best[i] = newTopK(q.metric, q.k)
+	}
+
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: s.keys.docPrefix()})
+	defer it.Close()
+
+	n := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		if n++; n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		item := it.Item()
+
+		var vec storedVector
+		if err := item.Value(func(val []byte) (err error) {
+			vec, err = s.format().decodeVector(val)
+			return
+		}); err != nil {
+			return nil, err
+		}
+
+		id := s.keys.docID(item.Key())
+		for i, q := range qs {
+			if vec.dim() != len(q.target) {
+				return nil, fmt.Errorf("%w: stored embedding has dimension %d, query has %d", ErrDimensionMismatch, vec.dim(), len(q.target))
+			}
+
+			best[i].push(candidate{id: id, score: q.score(vec), vec: vec})
+		}
+	}
+
+	ranked := make([][]candidate, len(best))
+	for i, b := range best {
+		ranked[i] = b.sorted()
+	}
+
+	return ranked, nil
+}
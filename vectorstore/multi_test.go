@@ -0,0 +1,70 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchBatch(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(40, 4)...)
+
+	queries := []string{"topic0 things", "document 17", "about topic3"}
+	batch, err := s.SearchBatch(ctx, queries, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != len(queries) {
+		t.Fatalf("SearchBatch returned %d result lists, want %d", len(batch), len(queries))
+	}
+
+	for i, query := range queries {
+		want, err := s.Nearest(ctx, query, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := resultIDs(batch[i])
+		for j, id := range resultIDs(want) {
+			if got[j] != id {
+				t.Errorf("SearchBatch for %q = %v, Nearest = %v", query, got, resultIDs(want))
+				break
+			}
+		}
+	}
+
+	if _, err := s.SearchBatch(ctx, []string{"fine", ""}, 5); err == nil {
+		t.Error("SearchBatch with an empty query succeeded")
+	}
+}
+
+// BenchmarkSearchBatch compares one SearchBatch of 50 queries with 50
+// calls to Nearest.
+func BenchmarkSearchBatch(b *testing.B) {
+	ctx := context.Background()
+	queries := benchTexts(50)
+
+	b.Run("batch", func(b *testing.B) {
+		s := openBench(b, memOptions(), 5000)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := s.SearchBatch(ctx, queries, 10); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("loop", func(b *testing.B) {
+		s := openBench(b, memOptions(), 5000)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, q := range queries {
+				if _, err := s.Nearest(ctx, q, 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// MultiVector is how a search combines the scores of a document's named
+// vectors.
+type MultiVector uint8
+
+const (
+	// MultiVectorNone scores documents by their embedding, or by the named
+	// vector SearchParams.Vector.
+	MultiVectorNone MultiVector = iota
+	// MultiVectorMax scores documents by their best scoring named vector,
+	// the highest for similarities and the lowest for distances.
+	MultiVectorMax
+	// MultiVectorMean scores documents by the mean score of their named
+	// vectors.
+	MultiVectorMean
+)
+
+// InsertWithVectors is InsertWithMetadata with named vectors, such as a
+// title and a summary, stored alongside the document's own embedding in
+// the same transaction. Each of vectors maps a name to the text embedded
+// for it. Searches reach them through SearchParams.Vector and
+// SearchParams.MultiVector. With deduplication on, a text that duplicates
+// an existing document gets that document's ID and its vectors aren't
+// stored.
+func (s *VectorStore) InsertWithVectors(ctx context.Context, text string, metadata map[string]string, vectors map[string]string) (uint64, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	embedding, err := s.embedForStorage(ctx, text)
+	if err != nil {
+		return 0, err
+	}
+
+	rec := record{Text: text, Metadata: metadata, Embedding: embedding, Vectors: make(map[string]record, len(vectors))}
+	for name, vtext := range vectors {
+		if name == "" {
+			return 0, errors.New("vector name must not be empty")
+		}
+
+		embedding, err := s.embedForStorage(ctx, vtext)
+		if err != nil {
+			return 0, fmt.Errorf("vector %q: %w", name, err)
+		}
+		rec.Vectors[name] = record{Text: vtext, Embedding: embedding}
+	}
+
+	ids, err := s.writeRecords(ctx, []record{rec})
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
+
+// SetVector embeds text and stores it as the vector called name of the
+// document id, replacing any it had. It returns ErrNotFound if there is no
+// such document.
+func (s *VectorStore) SetVector(ctx context.Context, id uint64, name, text string) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("vector name must not be empty")
+	}
+
+	embedding, err := s.embedForStorage(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, func(txn *badger.Txn) error {
+		if _, err := txn.Get(s.keys.doc(id)); err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		if err := s.markNormalized(txn); err != nil {
+			return err
+		}
+		if err := s.checkDim(txn, len(embedding)); err != nil {
+			return err
+		}
+
+		return s.vectorsAdd(txn, id, map[string]record{name: {Text: text, Embedding: embedding}})
+	})
+}
+
+// DeleteVector removes the vector called name from the document id. It
+// returns ErrNotFound if the document has no such vector.
+func (s *VectorStore) DeleteVector(ctx context.Context, id uint64, name string) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	return s.update(ctx, func(txn *badger.Txn) error {
+		key := s.keys.docVector(id, name)
+		if _, err := txn.Get(key); err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		return txn.Delete(key)
+	})
+}
+
+// vectorsAdd writes the named vectors of the document id.
+func (s *VectorStore) vectorsAdd(txn *badger.Txn, id uint64, vectors map[string]record) error {
+	for name, rec := range vectors {
+		if err := s.setRecord(txn, s.keys.docVector(id, name), rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// docVectors reads the named vectors of the document id.
+func (s *VectorStore) docVectors(txn *badger.Txn, id uint64) (map[string]record, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = s.keys.docVectorPrefix(id)
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var vectors map[string]record
+	for it.Rewind(); it.Valid(); it.Next() {
+		_, name := s.keys.docVectorKey(it.Item().Key())
+
+		var rec record
+		if err := it.Item().Value(func(val []byte) (err error) {
+			rec, err = s.format().decodeRecord(val)
+			return
+		}); err != nil {
+			return nil, err
+		}
+
+		if vectors == nil {
+			vectors = make(map[string]record)
+		}
+		vectors[name] = rec
+	}
+
+	return vectors, nil
+}
+
+// vectorsRemove deletes the named vectors of the document id.
+func (s *VectorStore) vectorsRemove(txn *badger.Txn, id uint64) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = s.keys.docVectorPrefix(id)
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var keys [][]byte
+	for it.Rewind(); it.Valid(); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+
+	for _, key := range keys {
+		if err := txn.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// vectorGroup accumulates the scores of one document's named vectors.
+type vectorGroup struct {
+	id    uint64
+	n     int
+	score float64
+	vec   storedVector
+}
+
+// scanVectors scores the named vectors of every document against the
+// query, as SearchParams.Vector and SearchParams.MultiVector say, and
+// returns the best k documents. Documents without a vector to score are
+// left out.
+func (s *VectorStore) scanVectors(ctx context.Context, txn *badger.Txn, q *query) ([]candidate, error) {
+	best := newTopK(q.metric, q.k)
+
+	push := func(g vectorGroup) {
+		if g.n == 0 {
+			return
+		}
+
+		c := candidate{id: g.id, score: g.score, vec: g.vec}
+		if q.multi == MultiVectorMean {
+			// The mean matches no single vector, so the result carries the
+			// document's embedding instead.
+			c.score, c.vec = g.score/float64(g.n), storedVector{}
+		}
+		if q.after != nil && !ranksAhead(q.metric, *q.after, c) {
+			return
+		}
+
+		best.push(c)
+	}
+
+	it := txn.NewIterator(q.iteratorOptions(s.keys.vectorPrefix()))
+	defer it.Close()
+
+	var g vectorGroup
+	skip := false
+	n := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		if n++; n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		item := it.Item()
+		id, name := s.keys.docVectorKey(item.Key())
+		if id != g.id || n == 1 {
+			push(g)
+			g = vectorGroup{id: id}
+
+			var err error
+			if skip, err = s.skipVectors(txn, q, id); err != nil {
+				return nil, err
+			}
+		}
+		if skip || q.vector != "" && name != q.vector {
+			continue
+		}
+
+		var vec storedVector
+		if err := item.Value(func(val []byte) (err error) {
+			vec, err = s.format().decodeVector(val)
+			return
+		}); err != nil {
+			return nil, err
+		}
+
+		if vec.dim() != len(q.target) {
+			return nil, fmt.Errorf("%w: stored vector has dimension %d, query has %d", ErrDimensionMismatch, vec.dim(), len(q.target))
+		}
+
+		score := q.score(vec)
+		switch {
+		case q.multi == MultiVectorMean:
+			g.score += score
+		case g.n == 0 || better(q.metric, score, g.score):
+			g.score, g.vec = score, vec
+		}
+		g.n++
+	}
+	push(g)
+
+	return best.sorted(), nil
+}
+
+// skipVectors reports whether a scan should pass over the named vectors of
+// the document id, because it is outside the sample or doesn't pass the
+// filter.
+func (s *VectorStore) skipVectors(txn *badger.Txn, q *query, id uint64) (bool, error) {
+	if q.skip(id) {
+		return true, nil
+	}
+	if q.filter == nil {
+		return false, nil
+	}
+
+	item, err := txn.Get(s.keys.doc(id))
+	if err == badger.ErrKeyNotFound {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var ok bool
+	err = item.Value(func(val []byte) (err error) {
+		_, ok, err = q.decode(val)
+		return
+	})
+
+	return !ok, err
+}
@@ -0,0 +1,97 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestNamedVectors(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	a, err := s.InsertWithVectors(ctx, "body of the first", nil, map[string]string{"title": "red apples"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.InsertWithVectors(ctx, "body of the second", nil, map[string]string{"title": "blue sky", "summary": "red apples mostly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, "red apples without vectors")
+
+	results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 3, Vector: "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(results); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("search by title = %v, want [%d %d]", got, a, b)
+	}
+
+	// The document's own embedding is searched apart from its title's.
+	results, err = s.Search(ctx, "blue sky", vectorstore.SearchParams{K: 1, Vector: "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(results); len(got) != 1 || got[0] != b {
+		t.Errorf("search by title for the second title = %v, want [%d]", got, b)
+	}
+	results, err = s.Search(ctx, "body of the first", vectorstore.SearchParams{K: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(results); len(got) != 1 || got[0] != a {
+		t.Errorf("search by body for the first body = %v, want [%d]", got, a)
+	}
+
+	best, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 3, MultiVector: vectorstore.MultiVectorMax})
+	if err != nil {
+		t.Fatal(err)
+	}
+	avg, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 3, MultiVector: vectorstore.MultiVectorMean})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(best) != 2 || len(avg) != 2 || best[1].ID != b || avg[1].ID != b || avg[1].Score >= best[1].Score {
+		t.Errorf("for the second document's title and summary, max = %+v and mean = %+v, want the mean below the max", best, avg)
+	}
+
+	results, err = s.Search(ctx, "red apples", vectorstore.SearchParams{K: 3, MultiVector: vectorstore.MultiVectorMax})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("multi-vector search returned %v, want the 2 documents with vectors", resultIDs(results))
+	}
+
+	if err := s.SetVector(ctx, b, "title", "red apples"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetVector(ctx, 99, "title", "x"); !errors.Is(err, vectorstore.ErrNotFound) {
+		t.Errorf("SetVector of a missing document returned %v, want ErrNotFound", err)
+	}
+
+	if err := s.DeleteVector(ctx, a, "title"); err != nil {
+		t.Fatal(err)
+	}
+	results, err = s.Search(ctx, "red apples", vectorstore.SearchParams{K: 3, Vector: "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(results); len(got) != 1 || got[0] != b {
+		t.Errorf("after DeleteVector, search by title = %v, want [%d]", got, b)
+	}
+	if err := s.DeleteVector(ctx, a, "title"); !errors.Is(err, vectorstore.ErrNotFound) {
+		t.Errorf("second DeleteVector returned %v, want ErrNotFound", err)
+	}
+
+	if err := s.Delete(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	results, err = s.Search(ctx, "red apples", vectorstore.SearchParams{K: 3, Vector: "summary"})
+	if err != nil || len(results) != 0 {
+		t.Errorf("after deleting the document, its vectors are found: %v, %v", resultIDs(results), err)
+	}
+}
@@ -0,0 +1,43 @@
+package vectorstore
+
+import "math"
+
+// storedNorm is the L2 norm of v once stored as dtype, so that it matches
+// the norm of the embedding as decoded.
+func storedNorm(v []float64, dtype DType) float64 {
+	stored := v
+	switch dtype {
+	case DTypeFloat32:
+		stored = make([]float64, len(v))
+		for i, x := range v {
+			stored[i] = float64(float32(x))
+		}
+	case DTypeFloat16:
+		stored = make([]float64, len(v))
+		for i, x := range v {
+			stored[i] = float64(float16frombits(float16bits(float32(x))))
+		}
+	}
+
+	return math.Sqrt(dot(stored, stored))
+}
+
+// scoreNormed scores a dense stored vector whose norm was stored against a
+// query whose dot product with itself is querySq, computing only their dot
+// product. Like scoreQuantized, it reports false for metrics that don't
+// need the norms.
+func scoreNormed(metric DistanceMetric, query []float64, querySq float64, v storedVector) (float64, bool) {
+	switch metric.(type) {
+	case CosineSimilarity:
+		if querySq == 0 {
+			return 0, true
+		}
+
+		return dot(query, v.dense) / (math.Sqrt(querySq) * v.norm), true
+	case CosineDistance:
+		score, ok := scoreNormed(CosineSimilarity{}, query, querySq, v)
+		return 1 - score, ok
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,304 @@
+package vectorstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// npyRow is one line of the sidecar written by ExportNPY.
+type npyRow struct {
+	Row  int    `json:"row"`
+	ID   uint64 `json:"id"`
+	Text string `json:"text"`
+}
+
+// ExportNPY writes every embedding to w as a little endian float32 array
+// of shape (documents, dimension) in numpy's .npy format, readable with
+// numpy.load. Row i of the array is described by line i of sidecar, a JSON
+// object holding the row, id and text of its document.
+func (s *VectorStore) ExportNPY(ctx context.Context, w, sidecar io.Writer) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(sidecar)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		rows, dim, err := s.exportShape(txn)
+		if err != nil {
+			return err
+		}
+
+		if _, err := bw.Write(npyHeader(rows, dim)); err != nil {
+			return err
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.keys.docPrefix()
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		row := 0
+		buf := make([]byte, 4*dim)
+		for it.Rewind(); it.Valid() && row < rows; it.Next() {
+			if row%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			var rec record
+			if err := it.Item().Value(func(val []byte) (err error) {
+				rec, err = s.format().decodeRecord(val)
+				return
+			}); err != nil {
+				return err
+			}
+
+			if len(rec.Embedding) != dim {
+				return fmt.Errorf("%w: document %d has %d dimensions, expected %d", ErrDimensionMismatch, s.keys.docID(it.Item().Key()), len(rec.Embedding), dim)
+			}
+
+			for i, x := range rec.Embedding {
+				binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(x)))
+			}
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+
+			if err := enc.Encode(npyRow{Row: row, ID: s.keys.docID(it.Item().Key()), Text: rec.Text}); err != nil {
+				return err
+			}
+			row++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// exportShape counts the documents and finds their dimension.
+func (s *VectorStore) exportShape(txn *badger.Txn) (rows, dim int, err error) {
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: s.keys.docPrefix()})
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		if rows == 0 {
+			var vec storedVector
+			if err := it.Item().Value(func(val []byte) (err error) {
+				vec, err = s.format().decodeVector(val)
+				return
+			}); err != nil {
+				return 0, 0, err
+			}
+			dim = vec.dim()
+		}
+		rows++
+	}
+
+	return rows, dim, nil
+}
+
+// npyHeader returns a version 1.0 .npy header for a C ordered little endian
+// float32 array of the given shape. The header is padded with spaces so
+// the data starts on a 64 byte boundary.
+func npyHeader(rows, cols int) []byte {
+	dict := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", rows, cols)
+
+	// magic (6) + version (2) + header length (2) + dict + newline
+	pad := 63 - (10+len(dict))%64
+	dict += strings.Repeat(" ", pad) + "\n"
+
+	buf := append([]byte("\x93NUMPY"), 1, 0)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(dict)))
+	return append(buf, dict...)
+}
+
+// npyDescr, npyFortranOrder and npyShape pick the fields ImportNPY needs
+// out of a .npy header's dict.
+var (
+	npyDescr        = regexp.MustCompile(`'descr':\s*'([<>|=])f([48])'`)
+	npyFortranOrder = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape        = regexp.MustCompile(`'shape':\s*\(\s*(\d+)\s*,\s*(\d+)\s*,?\s*\)`)
+)
+
+// npyArray is the layout of a .npy array as its header declares it.
+type npyArray struct {
+	order      binary.ByteOrder
+	width      int
+	rows, cols int
+}
+
+// readNPYHeader reads a version 1, 2 or 3 .npy header. Only C ordered two
+// dimensional float32 and float64 arrays are accepted, in either byte
+// order, which must be declared: '=' means the writer's native order, which
+// can't be known from the file.
+func readNPYHeader(r io.Reader) (npyArray, error) {
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return npyArray{}, fmt.Errorf("reading .npy header: %w", err)
+	}
+	if string(magic[:6]) != "\x93NUMPY" {
+		return npyArray{}, errors.New("not a .npy file")
+	}
+
+	var size int
+	switch magic[6] {
+	case 1:
+		buf := make([]byte, 2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return npyArray{}, fmt.Errorf("reading .npy header: %w", err)
+		}
+		size = int(binary.LittleEndian.Uint16(buf))
+	case 2, 3:
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return npyArray{}, fmt.Errorf("reading .npy header: %w", err)
+		}
+		size = int(binary.LittleEndian.Uint32(buf))
+	default:
+		return npyArray{}, fmt.Errorf("unsupported .npy version %d.%d", magic[6], magic[7])
+	}
+
+	dict := make([]byte, size)
+	if _, err := io.ReadFull(r, dict); err != nil {
+		return npyArray{}, fmt.Errorf("reading .npy header: %w", err)
+	}
+
+	descr := npyDescr.FindSubmatch(dict)
+	if descr == nil {
+		return npyArray{}, fmt.Errorf("unsupported .npy array %s, expected '<f4', '>f4', '<f8' or '>f8'", strings.TrimSpace(string(dict)))
+	}
+
+	var a npyArray
+	switch descr[1][0] {
+	case '<':
+		a.order = binary.LittleEndian
+	case '>':
+		a.order = binary.BigEndian
+	default:
+		return npyArray{}, fmt.Errorf("unsupported .npy byte order %q, expected '<' or '>'", descr[1])
+	}
+	a.width = int(descr[2][0] - '0')
+
+	if fo := npyFortranOrder.FindSubmatch(dict); fo == nil || string(fo[1]) != "False" {
+		return npyArray{}, errors.New("unsupported .npy array, expected C order")
+	}
+
+	shape := npyShape.FindSubmatch(dict)
+	if shape == nil {
+		return npyArray{}, errors.New("unsupported .npy array, expected two dimensions")
+	}
+	var err error
+	if a.rows, err = strconv.Atoi(string(shape[1])); err != nil {
+		return npyArray{}, fmt.Errorf("invalid .npy shape: %w", err)
+	}
+	if a.cols, err = strconv.Atoi(string(shape[2])); err != nil {
+		return npyArray{}, fmt.Errorf("invalid .npy shape: %w", err)
+	}
+
+	return a, nil
+}
+
+// row decodes one row of the array from buf, in the array's byte order.
+func (a npyArray) row(buf []byte) []float64 {
+	vec := make([]float64, a.cols)
+	for i := range vec {
+		if a.width == 4 {
+			vec[i] = float64(math.Float32frombits(a.order.Uint32(buf[i*4:])))
+		} else {
+			vec[i] = math.Float64frombits(a.order.Uint64(buf[i*8:]))
+		}
+	}
+
+	return vec
+}
+
+// ImportNPY stores the embeddings of a .npy array read from r without
+// calling the embedder, the reverse of ExportNPY. Line i of sidecar gives
+// the ID and text of row i, as ExportNPY writes it. The array may be
+// float32 or float64 in either byte order, so arrays written on big endian
+// machines or with an explicit '>' dtype are converted; arrays whose byte
+// order isn't declared are rejected. Existing documents with the same IDs
+// are replaced. It returns the number of documents imported.
+func (s *VectorStore) ImportNPY(ctx context.Context, r, sidecar io.Reader) (int, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	br := bufio.NewReader(r)
+	a, err := readNPYHeader(br)
+	if err != nil {
+		return 0, err
+	}
+
+	if dim := s.e.Dim(); dim != 0 && a.cols != dim {
+		return 0, fmt.Errorf("%w: expected %d dimensions, array has %d", ErrDimensionMismatch, dim, a.cols)
+	}
+
+	dec := json.NewDecoder(sidecar)
+	buf := make([]byte, a.width*a.cols)
+	n := 0
+	var ids []uint64
+	var recs []record
+	for row := 0; row < a.rows; row++ {
+		if row%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return n, err
+			}
+		}
+
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return n, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		var line npyRow
+		if err := dec.Decode(&line); err != nil {
+			return n, fmt.Errorf("sidecar line %d: %w", row+1, err)
+		}
+		if line.Row != row || line.ID == 0 {
+			return n, fmt.Errorf("sidecar line %d: expected row %d with an id, got row %d id %d", row+1, row, line.Row, line.ID)
+		}
+
+		rec := record{Text: line.Text, Embedding: a.row(buf)}
+		if s.opts.Normalize {
+			normalize(rec.Embedding)
+		}
+
+		ids = append(ids, line.ID)
+		recs = append(recs, rec)
+
+		if len(ids) >= max(s.opts.BatchSize, 1) || row == a.rows-1 {
+			written, err := s.putRecords(ctx, ids, recs)
+			n += written
+			if err != nil {
+				return n, err
+			}
+			ids, recs = ids[:0], recs[:0]
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,156 @@
+package vectorstore_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExportNPY(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	ids := insertAll(t, s, "red apples", "blue sky", "green grass")
+
+	var arr, sidecar bytes.Buffer
+	if err := s.ExportNPY(ctx, &arr, &sidecar); err != nil {
+		t.Fatal(err)
+	}
+
+	data := arr.Bytes()
+	if !bytes.HasPrefix(data, []byte("\x93NUMPY\x01\x00")) {
+		t.Fatalf("export doesn't start with the .npy 1.0 magic: %q", data[:8])
+	}
+	n := int(binary.LittleEndian.Uint16(data[8:]))
+	header, body := string(data[10:10+n]), data[10+n:]
+	if (10+n)%64 != 0 || !strings.HasSuffix(header, "\n") {
+		t.Errorf("the data starts at %d, not on a 64 byte boundary after a newline", 10+n)
+	}
+	if !strings.Contains(header, "'descr': '<f4'") || !strings.Contains(header, "'shape': (3, 16)") {
+		t.Errorf("header = %q, want little endian float32 of shape (3, 16)", header)
+	}
+	if len(body) != 3*testDim*4 {
+		t.Fatalf("the array holds %d bytes, want %d", len(body), 3*testDim*4)
+	}
+
+	sc := bufio.NewScanner(&sidecar)
+	for row := 0; sc.Scan(); row++ {
+		var line struct {
+			Row  int    `json:"row"`
+			ID   uint64 `json:"id"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		if line.Row != row || line.ID != ids[row] {
+			t.Errorf("sidecar line %d = %+v, want id %d", row, line, ids[row])
+		}
+
+		doc, err := s.Get(ctx, line.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, x := range doc.Embedding {
+			got := math.Float32frombits(binary.LittleEndian.Uint32(body[(row*testDim+i)*4:]))
+			if got != float32(x) {
+				t.Errorf("row %d, column %d = %v, want %v", row, i, got, float32(x))
+				break
+			}
+		}
+	}
+}
+
+func TestNPYRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openStore(t, memOptions())
+	ids := insertAll(t, src, "red apples", "blue sky", "green grass")
+
+	var arr, sidecar bytes.Buffer
+	if err := src.ExportNPY(ctx, &arr, &sidecar); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(arr.Bytes(), []byte("\x93NUMPY")) {
+		t.Fatalf("export doesn't start with the .npy magic: %q", arr.Bytes()[:8])
+	}
+
+	dst := openStore(t, memOptions())
+	n, err := dst.ImportNPY(ctx, &arr, &sidecar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("ImportNPY imported %d documents, want 3", n)
+	}
+
+	results, err := dst.Nearest(ctx, "blue sky", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != ids[1] || results[0].Text != "blue sky" {
+		t.Errorf("Nearest on the imported store = %+v, want %d", results, ids[1])
+	}
+}
+
+// npyFile returns a .npy file of rows with the dtype descr, in order.
+func npyFile(descr string, order binary.AppendByteOrder, rows [][]float64) []byte {
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d), }\n", descr, len(rows), len(rows[0]))
+
+	buf := append([]byte("\x93NUMPY"), 1, 0)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(dict)))
+	buf = append(buf, dict...)
+	for _, row := range rows {
+		for _, x := range row {
+			if strings.HasSuffix(descr, "4") {
+				buf = order.AppendUint32(buf, math.Float32bits(float32(x)))
+			} else {
+				buf = order.AppendUint64(buf, math.Float64bits(x))
+			}
+		}
+	}
+
+	return buf
+}
+
+func TestImportNPYByteOrders(t *testing.T) {
+	ctx := context.Background()
+	rows := [][]float64{{1, 0, 0}, {0, 0.5, 0}}
+	sidecar := `{"row": 0, "id": 3, "text": "three"}` + "\n" + `{"row": 1, "id": 4, "text": "four"}` + "\n"
+
+	tests := []struct {
+		descr string
+		order binary.AppendByteOrder
+		ok    bool
+	}{
+		{"<f4", binary.LittleEndian, true},
+		{">f4", binary.BigEndian, true},
+		{"<f8", binary.LittleEndian, true},
+		{">f8", binary.BigEndian, true},
+		{"=f4", binary.LittleEndian, false},
+		{"<i4", binary.LittleEndian, false},
+	}
+	for _, tt := range tests {
+		s := openStoreWith(t, memOptions().WithNormalize(false), fixedEmbedder{dim: 3})
+		_, err := s.ImportNPY(ctx, bytes.NewReader(npyFile(tt.descr, tt.order, rows)), strings.NewReader(sidecar))
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: ImportNPY returned %v", tt.descr, err)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+
+		doc, err := s.Get(ctx, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Text != "four" || doc.Embedding[1] != 0.5 {
+			t.Errorf("%s: document 4 = %+v", tt.descr, doc)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package vectorstore
+
+import "time"
+
+// Options configures a VectorStore. Start from DefaultOptions and adjust it
+// with the With* methods.
+type Options struct {
+	// Dir is the directory holding the Badger database.
+	Dir string
+
+	// Normalize L2-normalizes embeddings before they are stored, which turns
+	// cosine similarity into a plain dot product at query time. An index
+	// must be built and queried with the same setting.
+	Normalize bool
+
+	// ModelName identifies the embedding model. It is recorded with the
+	// index on first insert, after which Open refuses a different one.
+	// Leave it empty to skip the check.
+	ModelName string
+
+	// Metric ranks stored embeddings against the query.
+	Metric DistanceMetric
+
+	// DType is the precision new embeddings are written with. Float32
+	// halves the index size at a precision cost that is negligible for
+	// retrieval. Existing records keep the type they were written with.
+	DType DType
+
+	// Index selects how queries find their neighbours. Documents are always
+	// stored the same way, the index only adds to them.
+	Index IndexType
+
+	// HNSW tunes the graph when Index is IndexHNSW.
+	HNSW HNSWOptions
+
+	// IVF tunes the clustering when Index is IndexIVF.
+	IVF IVFOptions
+
+	// LSH tunes the hashing when Index is IndexLSH.
+	LSH LSHOptions
+
+	// Keywords maintains an inverted index of the words in each document,
+	// for hybrid searches with SearchParams.KeywordWeight. Switching it on
+	// for a populated store builds the index on Open, and switching it off
+	// drops it.
+	Keywords bool
+
+	// Retry controls how texts that fail to encode are retried. By default
+	// they aren't.
+	Retry RetryOptions
+
+	// Concurrency is the number of goroutines InsertBatch encodes with.
+	// They share one model, so only raise it if the model is safe for
+	// concurrent use, for instance a ModelPool with as many instances.
+	Concurrency int
+
+	// BatchSize is the number of documents InsertBatch writes per
+	// transaction.
+	BatchSize int
+
+	// DedupThreshold, if above zero, makes inserts skip any text whose
+	// embedding has a cosine similarity above it with one already stored,
+	// returning the existing document's ID instead. The check uses the
+	// configured index, so with an approximate one a duplicate may be
+	// missed.
+	DedupThreshold float64
+
+	// CacheSize is the number of recent embeddings kept in memory, keyed
+	// by their text, so that repeated inserts and queries skip the model.
+	// Zero disables the cache.
+	CacheSize int
+
+	// QueryCacheSize is the number of recent search query embeddings kept
+	// in memory, apart from CacheSize's, so that repeated searches skip
+	// the model. Queries differing only in case or whitespace share an
+	// entry. Zero disables the cache.
+	QueryCacheSize int
+
+	// QueryCacheTTL is how long a query embedding is cached for, so that
+	// a change of model is eventually picked up. Zero keeps them until
+	// they are evicted.
+	QueryCacheTTL time.Duration
+
+	// AllowEmptyText lets empty and whitespace-only texts be inserted, for
+	// instance as placeholders. Their embeddings carry no meaning, so
+	// by default such inserts fail with ErrEmptyText.
+	AllowEmptyText bool
+
+	// LongText is what inserts do with text longer than the embedder
+	// takes, for embedders that report a limit such as
+	// CybertronEmbedder. By default they fail with ErrTextTooLong.
+	LongText LongTextPolicy
+
+	// LongTextWeighted weights each part's embedding by its number of
+	// tokens under LongTextAverage, so that a short last part counts for
+	// less. By default the parts count equally.
+	LongTextWeighted bool
+
+	// Preprocessor, if set, rewrites texts before they are embedded, so
+	// that inserts and queries differing only in, say, case or whitespace
+	// embed and cache alike. Stored texts are kept as given. Documents
+	// embedded under a different Preprocessor need Reindex.
+	Preprocessor Preprocessor
+
+	// GCDiscardRatio is the fraction of a value log file that must be stale
+	// before GC rewrites it.
+	GCDiscardRatio float64
+
+	// GCInterval is how often value log GC runs in the background. Zero
+	// disables it, leaving RunGC to the caller.
+	GCInterval time.Duration
+
+	// EncryptionKey, if set, encrypts the database with AES. It must be 16,
+	// 24 or 32 bytes, and every later Open must pass the same key.
+	EncryptionKey []byte
+
+	// IndexCacheSize is the number of bytes of table indexes Badger keeps
+	// in memory. It must be above zero when EncryptionKey is set, as
+	// encrypted indexes would otherwise be decrypted on every read.
+	IndexCacheSize int64
+
+	// SyncWrites makes every commit wait for its writes to be synced to
+	// disk, so that a transaction that returned survives a crash of the
+	// machine, not just of the process. It costs an fsync per commit,
+	// which makes small writes many times slower, so leave it off, as
+	// Badger does by default, for bulk loads or data that can be rebuilt.
+	SyncWrites bool
+
+	// NumGoroutines is how many goroutines Badger's streams and Compact's
+	// merge of the LSM tree use, and NumCompactors how many compaction
+	// workers run in the background. Raising them helps on hosts with
+	// many cores and fast disks: NumGoroutines speeds up Compact, and
+	// NumCompactors keeps compaction up with heavy write loads such as large
+	// imports, which otherwise stall while level 0 is full. They default
+	// to Badger's 8 and 4. NumGoroutines must be at least 1 and
+	// NumCompactors at least 2, as Badger needs one for level 0 and
+	// another for the rest.
+	NumGoroutines int
+	NumCompactors int
+
+	// ReadOnly opens the database for queries only, so that several
+	// processes can share it. Writes fail with ErrReadOnly and value log
+	// GC doesn't run.
+	ReadOnly bool
+
+	// InMemory keeps the whole database in memory instead of in Dir, which
+	// is ignored. Everything is lost on Close. There is no value log, so
+	// GC never runs.
+	InMemory bool
+
+	// VerifyOnOpen makes Open, and Collection for the collection's
+	// documents, run Verify and fail if any document record is damaged.
+	VerifyOnOpen bool
+
+	// Codec, if set, encodes document records in place of the built-in
+	// format. See Codec.
+	Codec Codec
+
+	// StoreNorms stores each float64, float32 or float16 embedding's L2
+	// norm with it, so that cosine scans compute one dot product per
+	// document rather than three, for 8 bytes per document. It matters
+	// only without Normalize, which makes cosine a dot product anyway.
+	// Documents written before it was set are scored without a norm until
+	// they are rewritten.
+	StoreNorms bool
+
+	// KMeansSeed, if not zero, seeds the k-means run by Cluster and by
+	// BuildIndex for IVF, so that the same documents cluster the same way
+	// every time.
+	KMeansSeed int64
+
+	// MaxK is the most results a search may ask for, so that a bad
+	// request can't exhaust memory ranking them. Searches over it, or
+	// with a SearchParams.RerankDepth over it, fail with ErrKTooLarge, and
+	// hybrid searches fetch at most this many candidates from each side.
+	// Zero removes the limit.
+	MaxK int
+
+	// MaxMatrixDocs is the most documents ComputeSimilarityMatrix
+	// compares; the matrix takes 8 bytes per pair.
+	MaxMatrixDocs int
+
+	// Metrics is told about inserts, encodes and searches.
+	Metrics Metrics
+
+	// Logger receives the store's and Badger's log messages.
+	Logger Logger
+}
+
+func DefaultOptions(dir string) Options {
+	return Options{
+		Dir:            dir,
+		Metric:         CosineSimilarity{},
+		DType:          DTypeFloat64,
+		HNSW:           DefaultHNSWOptions(),
+		IVF:            DefaultIVFOptions(),
+		LSH:            DefaultLSHOptions(),
+		Retry:          DefaultRetryOptions(),
+		Concurrency:    1,
+		BatchSize:      512,
+		GCDiscardRatio: 0.7,
+		GCInterval:     5 * time.Minute,
+		QueryCacheTTL:  10 * time.Minute,
+		NumGoroutines:  8,
+		NumCompactors:  4,
+		MaxMatrixDocs:  4096,
+		MaxK:           1000,
+		Metrics:        nopMetrics{},
+		Logger:         nopLogger{},
+	}
+}
+
+func (o Options) WithNormalize(b bool) Options {
+	o.Normalize = b
+	return o
+}
+
+func (o Options) WithModelName(name string) Options {
+	o.ModelName = name
+	return o
+}
+
+func (o Options) WithMetric(m DistanceMetric) Options {
+	o.Metric = m
+	return o
+}
+
+func (o Options) WithDType(d DType) Options {
+	o.DType = d
+	return o
+}
+
+func (o Options) WithIndex(t IndexType) Options {
+	o.Index = t
+	return o
+}
+
+func (o Options) WithHNSW(h HNSWOptions) Options {
+	o.HNSW = h
+	return o
+}
+
+func (o Options) WithIVF(i IVFOptions) Options {
+	o.IVF = i
+	return o
+}
+
+func (o Options) WithLSH(l LSHOptions) Options {
+	o.LSH = l
+	return o
+}
+
+func (o Options) WithKeywords(b bool) Options {
+	o.Keywords = b
+	return o
+}
+
+func (o Options) WithRetry(r RetryOptions) Options {
+	o.Retry = r
+	return o
+}
+
+func (o Options) WithConcurrency(n int) Options {
+	o.Concurrency = n
+	return o
+}
+
+func (o Options) WithBatchSize(n int) Options {
+	o.BatchSize = n
+	return o
+}
+
+func (o Options) WithDedupThreshold(t float64) Options {
+	o.DedupThreshold = t
+	return o
+}
+
+func (o Options) WithCacheSize(n int) Options {
+	o.CacheSize = n
+	return o
+}
+
+func (o Options) WithQueryCacheSize(n int) Options {
+	o.QueryCacheSize = n
+	return o
+}
+
+func (o Options) WithQueryCacheTTL(d time.Duration) Options {
+	o.QueryCacheTTL = d
+	return o
+}
+
+func (o Options) WithAllowEmptyText(b bool) Options {
+	o.AllowEmptyText = b
+	return o
+}
+
+func (o Options) WithLongText(p LongTextPolicy) Options {
+	o.LongText = p
+	return o
+}
+
+func (o Options) WithLongTextWeighted(weighted bool) Options {
+	o.LongTextWeighted = weighted
+	return o
+}
+
+func (o Options) WithPreprocessor(p Preprocessor) Options {
+	o.Preprocessor = p
+	return o
+}
+
+func (o Options) WithGCDiscardRatio(r float64) Options {
+	o.GCDiscardRatio = r
+	return o
+}
+
+func (o Options) WithGCInterval(d time.Duration) Options {
+	o.GCInterval = d
+	return o
+}
+
+func (o Options) WithEncryptionKey(key []byte) Options {
+	o.EncryptionKey = key
+	return o
+}
+
+func (o Options) WithIndexCacheSize(n int64) Options {
+	o.IndexCacheSize = n
+	return o
+}
+
+func (o Options) WithSyncWrites(b bool) Options {
+	o.SyncWrites = b
+	return o
+}
+
+func (o Options) WithNumGoroutines(n int) Options {
+	o.NumGoroutines = n
+	return o
+}
+
+func (o Options) WithNumCompactors(n int) Options {
+	o.NumCompactors = n
+	return o
+}
+
+func (o Options) WithCodec(c Codec) Options {
+	o.Codec = c
+	return o
+}
+
+func (o Options) WithStoreNorms(store bool) Options {
+	o.StoreNorms = store
+	return o
+}
+
+func (o Options) WithKMeansSeed(seed int64) Options {
+	o.KMeansSeed = seed
+	return o
+}
+
+func (o Options) WithMaxMatrixDocs(n int) Options {
+	o.MaxMatrixDocs = n
+	return o
+}
+
+func (o Options) WithMaxK(k int) Options {
+	o.MaxK = k
+	return o
+}
+
+func (o Options) WithReadOnly(b bool) Options {
+	o.ReadOnly = b
+	return o
+}
+
+func (o Options) WithInMemory(b bool) Options {
+	o.InMemory = b
+	return o
+}
+
+func (o Options) WithVerifyOnOpen(b bool) Options {
+	o.VerifyOnOpen = b
+	return o
+}
+
+func (o Options) WithMetrics(m Metrics) Options {
+	o.Metrics = m
+	return o
+}
+
+func (o Options) WithLogger(l Logger) Options {
+	o.Logger = l
+	return o
+}
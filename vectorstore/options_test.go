@@ -0,0 +1,65 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSyncWrites(t *testing.T) {
+	ctx := context.Background()
+	good := encodeRecord(record{Text: "kept", Embedding: []float64{1, 0, 0, 0}}, DTypeFloat64, false)
+
+	for _, sync := range []bool{false, true} {
+		opts := DefaultOptions(t.TempDir()).WithGCInterval(0).WithSyncWrites(sync)
+		s, err := Open(opts, NewCybertronEmbedder(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.db.Opts().SyncWrites; got != sync {
+			t.Errorf("SyncWrites %v opened Badger with SyncWrites %v", sync, got)
+		}
+		writeRaw(t, s, good)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err = Open(opts, NewCybertronEmbedder(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := s.Get(ctx, 1)
+		if err != nil || doc.Text != "kept" {
+			t.Errorf("SyncWrites %v: after reopening, Get = %+v, %v", sync, doc, err)
+		}
+		s.Close()
+	}
+}
+
+func TestBadgerConcurrency(t *testing.T) {
+	ctx := context.Background()
+	opts := DefaultOptions("").WithInMemory(true).WithNumGoroutines(3).WithNumCompactors(2)
+
+	s, err := Open(opts, NewCybertronEmbedder(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.db.Opts(); got.NumGoroutines != 3 || got.NumCompactors != 2 {
+		t.Errorf("Badger was opened with %d goroutines and %d compactors, want 3 and 2", got.NumGoroutines, got.NumCompactors)
+	}
+
+	writeRaw(t, s, encodeRecord(record{Text: "kept", Embedding: []float64{1, 0, 0, 0}}, DTypeFloat64, false))
+	if err := s.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := s.Get(ctx, 1); err != nil || doc.Text != "kept" {
+		t.Errorf("after Compact, Get = %+v, %v", doc, err)
+	}
+
+	for _, bad := range []Options{opts.WithNumGoroutines(0), opts.WithNumCompactors(1)} {
+		if s, err := Open(bad, NewCybertronEmbedder(nil)); err == nil {
+			s.Close()
+			t.Errorf("Open with %d goroutines and %d compactors succeeded", bad.NumGoroutines, bad.NumCompactors)
+		}
+	}
+}
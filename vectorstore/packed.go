@@ -0,0 +1,319 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// The packed store keeps a copy of every embedding in segments of up to
+// packedSegmentRows rows. A segment is a big endian uint32 dimension
+// followed by rows of a big endian uint64 ID and that many little endian
+// float64s, so a scan decodes one value per segment instead of one per
+// document and reuses a single buffer for the vectors.
+//
+// Segments are kept small enough that rewriting one on every insert or
+// delete stays cheap.
+const packedSegmentRows = 256
+
+func packedRowWidth(dim int) int {
+	return 8 + 8*dim
+}
+
+// packedSegment is a decoded segment value.
+type packedSegment struct {
+	dim  int
+	rows []byte
+}
+
+func decodePackedSegment(val []byte) (packedSegment, error) {
+	if len(val) < 4 {
+		return packedSegment{}, ErrCorruptRecord
+	}
+
+	seg := packedSegment{dim: int(binary.BigEndian.Uint32(val)), rows: val[4:]}
+	if len(seg.rows)%packedRowWidth(seg.dim) != 0 {
+		return packedSegment{}, ErrCorruptRecord
+	}
+
+	return seg, nil
+}
+
+func (p packedSegment) len() int {
+	return len(p.rows) / packedRowWidth(p.dim)
+}
+
+func (p packedSegment) id(row int) uint64 {
+	return binary.BigEndian.Uint64(p.rows[row*packedRowWidth(p.dim):])
+}
+
+// vec decodes the vector of row into dst, which must have length p.dim.
+func (p packedSegment) vec(row int, dst []float64) {
+	off := row*packedRowWidth(p.dim) + 8
+	for i := range dst {
+		dst[i] = math.Float64frombits(binary.LittleEndian.Uint64(p.rows[off+8*i:]))
+	}
+}
+
+func (p packedSegment) encode() []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(p.dim)), p.rows...)
+}
+
+func appendPackedRow(rows []byte, id uint64, vec []float64) []byte {
+	rows = binary.BigEndian.AppendUint64(rows, id)
+	for _, x := range vec {
+		rows = binary.LittleEndian.AppendUint64(rows, math.Float64bits(x))
+	}
+
+	return rows
+}
+
+// packedEnabled reports whether RebuildPackedStore has been run, so that
+// writes must keep the packed store up to date.
+func (s *VectorStore) packedEnabled(txn *badger.Txn) (bool, error) {
+	val, err := getMeta(txn, s.keys.meta(metaPacked))
+	return val != nil, err
+}
+
+// packedTail returns the last segment and its index, or an empty segment
+// at index 0 if there are none.
+func packedTail(txn *badger.Txn, keys keyspace) (uint32, []byte, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = keys.packedSegmentPrefix()
+	opts.Reverse = true
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	it.Seek(keys.packedSegment(math.MaxUint32))
+	if !it.Valid() {
+		return 0, nil, nil
+	}
+
+	val, err := it.Item().ValueCopy(nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	key := it.Item().Key()
+	return binary.BigEndian.Uint32(key[len(key)-4:]), val, nil
+}
+
+// packedAdd appends the vectors to the last segment, starting new ones as
+// it fills.
+func packedAdd(txn *badger.Txn, keys keyspace, ids []uint64, vecs [][]float64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	index, val, err := packedTail(txn, keys)
+	if err != nil {
+		return err
+	}
+
+	seg := packedSegment{dim: len(vecs[0])}
+	if val != nil {
+		if seg, err = decodePackedSegment(val); err != nil {
+			return err
+		}
+	}
+
+	for i, id := range ids {
+		if len(vecs[i]) != seg.dim {
+			return fmt.Errorf("%w: packed store has %d dimensions, got %d", ErrDimensionMismatch, seg.dim, len(vecs[i]))
+		}
+
+		if seg.len() == packedSegmentRows {
+			if err := txn.Set(keys.packedSegment(index), seg.encode()); err != nil {
+				return err
+			}
+			index++
+			seg.rows = nil
+		}
+
+		seg.rows = appendPackedRow(seg.rows, id, vecs[i])
+		if err := txn.Set(keys.packedAssign(id), binary.BigEndian.AppendUint32(nil, index)); err != nil {
+			return err
+		}
+	}
+
+	return txn.Set(keys.packedSegment(index), seg.encode())
+}
+
+// packedRemove drops id's row, moving the segment's last row into its
+// place.
+func packedRemove(txn *badger.Txn, keys keyspace, id uint64) error {
+	val, err := getMeta(txn, keys.packedAssign(id))
+	if err != nil || val == nil {
+		return err
+	}
+	if len(val) != 4 {
+		return ErrCorruptRecord
+	}
+	index := binary.BigEndian.Uint32(val)
+
+	item, err := txn.Get(keys.packedSegment(index))
+	if err != nil {
+		return err
+	}
+
+	val, err = item.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+
+	seg, err := decodePackedSegment(val)
+	if err != nil {
+		return err
+	}
+
+	width := packedRowWidth(seg.dim)
+	last := seg.len() - 1
+	for row := 0; row <= last; row++ {
+		if seg.id(row) != id {
+			continue
+		}
+
+		copy(seg.rows[row*width:], seg.rows[last*width:])
+		seg.rows = seg.rows[:last*width]
+		break
+	}
+
+	if err := txn.Delete(keys.packedAssign(id)); err != nil {
+		return err
+	}
+
+	if len(seg.rows) == 0 {
+		return txn.Delete(keys.packedSegment(index))
+	}
+
+	return txn.Set(keys.packedSegment(index), seg.encode())
+}
+
+// scanPacked is scanFlat over the packed store.
+func (s *VectorStore) scanPacked(ctx context.Context, txn *badger.Txn, q *query) ([]candidate, error) {
+	best := newTopK(q.metric, q.k)
+
+	// Segments are decoded in place, so copying them ahead of use would
+	// only add to the garbage.
+	opts := badger.IteratorOptions{Prefix: s.keys.packedSegmentPrefix()}
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	vec := make([]float64, len(q.target))
+	n := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		if err := it.Item().Value(func(val []byte) error {
+			seg, err := decodePackedSegment(val)
+			if err != nil {
+				return err
+			}
+
+			if seg.dim != len(q.target) {
+				return fmt.Errorf("%w: stored embedding has dimension %d, query has %d", ErrDimensionMismatch, seg.dim, len(q.target))
+			}
+
+			for row := 0; row < seg.len(); row++ {
+				if n++; n%ctxCheckInterval == 0 {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+				}
+
+				id := seg.id(row)
+				if q.skip(id) {
+					continue
+				}
+
+				seg.vec(row, vec)
+				score := q.metric.Score(q.target, vec)
+				if best.accepts(score) {
+					best.push(candidate{id: id, score: score, vec: storedVector{dense: append([]float64(nil), vec...)}})
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return best.sorted(), nil
+}
+
+// RebuildPackedStore copies every stored embedding into the packed store,
+// which flat searches without a filter then scan instead of decoding each
+// document. Once built, inserts, updates and deletes keep it current.
+// Writes made while it runs may be missed.
+func (s *VectorStore) RebuildPackedStore(ctx context.Context) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		return txn.Delete(s.keys.meta(metaPacked))
+	}); err != nil {
+		return err
+	}
+
+	if err := s.dropPrefix(s.keys.packedPrefix()); err != nil {
+		return err
+	}
+
+	var after uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var ids []uint64
+		var vecs [][]float64
+
+		if err := s.db.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.Prefix = s.keys.docPrefix()
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			for it.Seek(s.keys.doc(after + 1)); it.Valid() && len(ids) < packedSegmentRows; it.Next() {
+				var vec storedVector
+				if err := it.Item().Value(func(val []byte) (err error) {
+					vec, err = s.format().decodeVector(val)
+					return
+				}); err != nil {
+					return err
+				}
+
+				ids = append(ids, s.keys.docID(it.Item().Key()))
+				vecs = append(vecs, vec.float64s())
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		if err := s.update(ctx, func(txn *badger.Txn) error {
+			return packedAdd(txn, s.keys, ids, vecs)
+		}); err != nil {
+			return err
+		}
+
+		after = ids[len(ids)-1]
+	}
+
+	return s.update(ctx, func(txn *badger.Txn) error {
+		return txn.Set(s.keys.meta(metaPacked), []byte{1})
+	})
+}
@@ -0,0 +1,83 @@
+package vectorstore_test
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestPackedStore(t *testing.T) {
+	ctx := context.Background()
+	texts := numbered(60, 6)
+
+	flat := openStore(t, memOptions())
+	insertAll(t, flat, texts...)
+
+	s := openStore(t, memOptions())
+	ids := insertAll(t, s, texts[:30]...)
+	if err := s.RebuildPackedStore(ctx); err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, texts[30:]...)
+
+	// Deletes and updates keep the packed store current.
+	if err := s.Delete(ctx, ids[3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := flat.Delete(ctx, ids[3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, ids[4], "entirely new words"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flat.Update(ctx, ids[4], "entirely new words"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"topic3 things", "entirely new words", "document 3"} {
+		want, err := flat.Nearest(ctx, query, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Nearest(ctx, query, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Documents with the same words tie, so compare scores, not IDs.
+		if len(got) != len(want) {
+			t.Fatalf("packed search for %q returned %d results, want %d", query, len(got), len(want))
+		}
+		for i := range want {
+			if math.Abs(got[i].Score-want[i].Score) > 1e-9 || got[i].ID == ids[3] {
+				t.Errorf("packed results for %q = %v, want %v", query, resultIDs(got), resultIDs(want))
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkPackedStore(b *testing.B) {
+	ctx := context.Background()
+	for _, packed := range []bool{false, true} {
+		name := "per-key"
+		if packed {
+			name = "packed"
+		}
+		b.Run(name, func(b *testing.B) {
+			s := openBench(b, memOptions(), 5000)
+			if packed {
+				if err := s.RebuildPackedStore(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := s.Nearest(ctx, "w1 w2 w3", 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// Cursor marks where a page of search results ended. It is opaque, safe to
+// put in a URL, and empty once there are no more results.
+type Cursor string
+
+// Page is a page of search results and the cursor for the next one.
+type Page struct {
+	Results []Result
+	Next    Cursor
+}
+
+// pageCursor is the decoded form of a Cursor: the query and the last
+// result returned.
+type pageCursor struct {
+	text  string
+	score float64
+	id    uint64
+}
+
+func (c pageCursor) encode() Cursor {
+	buf := appendString(nil, c.text)
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(c.score))
+	buf = binary.BigEndian.AppendUint64(buf, c.id)
+
+	return Cursor(base64.RawURLEncoding.EncodeToString(buf))
+}
+
+func decodeCursor(cur Cursor) (pageCursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(string(cur))
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	text, rest, ok := readString(buf)
+	if !ok || len(rest) != 16 {
+		return pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	return pageCursor{
+		text:  string(text),
+		score: math.Float64frombits(binary.BigEndian.Uint64(rest)),
+		id:    binary.BigEndian.Uint64(rest[8:]),
+	}, nil
+}
+
+// SearchPage returns the first k stored texts closest to text, best first,
+// and a cursor for SearchAfter to continue from. Paged searches always
+// scan every document, ranking ties by ID, so pages neither overlap nor
+// leave gaps while the documents are unchanged.
+func (s *VectorStore) SearchPage(ctx context.Context, text string, k int) (Page, error) {
+	return s.searchPage(ctx, pageCursor{text: text}, false, k)
+}
+
+// SearchAfter returns the next k results of the search cur came from.
+func (s *VectorStore) SearchAfter(ctx context.Context, cur Cursor, k int) (Page, error) {
+	if cur == "" {
+		return Page{}, nil
+	}
+
+	c, err := decodeCursor(cur)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return s.searchPage(ctx, c, true, k)
+}
+
+func (s *VectorStore) searchPage(ctx context.Context, c pageCursor, after bool, k int) (Page, error) {
+	if err := s.begin(); err != nil {
+		return Page{}, err
+	}
+	defer s.end()
+
+	defer s.observeSearch(time.Now())
+
+	p := SearchParams{K: k}
+	q, err := s.newSearch(ctx, c.text, p)
+	if err != nil {
+		return Page{}, err
+	}
+	if after {
+		q.after = &candidate{id: c.id, score: c.score}
+	}
+
+	var page Page
+	if err := s.db.View(func(txn *badger.Txn) error {
+		ranked, err := s.scanFlat(ctx, txn, q)
+		if err != nil {
+			return err
+		}
+
+		page.Results, err = s.fetchResults(txn, q, ranked)
+		return err
+	}); err != nil {
+		return Page{}, err
+	}
+	for i := range page.Results {
+		p.Fields.project(&page.Results[i])
+	}
+
+	if n := len(page.Results); n == k {
+		last := page.Results[n-1]
+		page.Next = pageCursor{text: c.text, score: last.Score, id: last.ID}.encode()
+	}
+
+	return page, nil
+}
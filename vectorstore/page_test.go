@@ -0,0 +1,57 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestSearchPages(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(23, 3)...)
+
+	all, err := s.Nearest(ctx, "topic1 things", 23)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Page through in threes, which splits runs of tied scores.
+	var paged []vectorstore.Result
+	seen := make(map[uint64]bool)
+	page, err := s.SearchPage(ctx, "topic1 things", 3)
+	for {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range page.Results {
+			if seen[r.ID] {
+				t.Errorf("document %d was returned on two pages", r.ID)
+			}
+			seen[r.ID] = true
+		}
+		paged = append(paged, page.Results...)
+		if page.Next == "" {
+			break
+		}
+		page, err = s.SearchAfter(ctx, page.Next, 3)
+	}
+
+	if len(paged) != len(all) {
+		t.Fatalf("pages returned %d results, want %d", len(paged), len(all))
+	}
+	for i := range all {
+		if paged[i].ID != all[i].ID {
+			t.Errorf("paged result %d is %d, want %d", i, paged[i].ID, all[i].ID)
+		}
+		// Pages return the fields a search does by default.
+		if paged[i].Text != all[i].Text || paged[i].Embedding != nil {
+			t.Errorf("paged result %d = %+v, want its text alone", i, paged[i])
+		}
+	}
+
+	if _, err := s.SearchAfter(ctx, "not a cursor!", 3); err == nil {
+		t.Error("SearchAfter with an invalid cursor succeeded")
+	}
+}
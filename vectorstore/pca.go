@@ -0,0 +1,282 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// pcaProjection maps embeddings onto their principal components:
+// components * (x - mean).
+type pcaProjection struct {
+	mean       []float64
+	components [][]float64
+}
+
+func (p *pcaProjection) in() int  { return len(p.mean) }
+func (p *pcaProjection) out() int { return len(p.components) }
+
+func (p *pcaProjection) project(x []float64) ([]float64, error) {
+	if len(x) != p.in() {
+		return nil, fmt.Errorf("%w: PCA projects %d dimensions, got %d", ErrDimensionMismatch, p.in(), len(x))
+	}
+
+	centred := make([]float64, len(x))
+	for i := range x {
+		centred[i] = x[i] - p.mean[i]
+	}
+
+	y := make([]float64, p.out())
+	for i, c := range p.components {
+		y[i] = dot(c, centred)
+	}
+
+	return y, nil
+}
+
+// encode lays the projection out as the uvarint input and output
+// dimensions followed by the mean and each component as little endian
+// float64s.
+func (p *pcaProjection) encode() []byte {
+	buf := binary.AppendUvarint(nil, uint64(p.in()))
+	buf = binary.AppendUvarint(buf, uint64(p.out()))
+	buf = append(buf, encodeFloat64s(p.mean)...)
+	for _, c := range p.components {
+		buf = append(buf, encodeFloat64s(c)...)
+	}
+
+	return buf
+}
+
+func decodePCAProjection(val []byte) (*pcaProjection, error) {
+	in, l := binary.Uvarint(val)
+	if l <= 0 {
+		return nil, ErrCorruptRecord
+	}
+	val = val[l:]
+
+	out, l := binary.Uvarint(val)
+	if l <= 0 {
+		return nil, ErrCorruptRecord
+	}
+	val = val[l:]
+
+	if uint64(len(val)) != 8*in*(out+1) {
+		return nil, ErrCorruptRecord
+	}
+
+	floats, err := decodeFloat64s(val)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pcaProjection{mean: floats[:in]}
+	for i := uint64(1); i <= out; i++ {
+		p.components = append(p.components, floats[i*in:(i+1)*in])
+	}
+
+	return p, nil
+}
+
+// fitPCA returns the projection of vecs onto their top n principal
+// components.
+func fitPCA(vecs [][]float64, n int) (*pcaProjection, error) {
+	dim := len(vecs[0])
+	x := mat.NewDense(len(vecs), dim, nil)
+	for i, v := range vecs {
+		if len(v) != dim {
+			return nil, fmt.Errorf("%w: stored embeddings have dimensions %d and %d", ErrDimensionMismatch, dim, len(v))
+		}
+		x.SetRow(i, v)
+	}
+
+	mean := make([]float64, dim)
+	for j := range mean {
+		mean[j] = stat.Mean(mat.Col(nil, j, x), nil)
+	}
+
+	var cov mat.SymDense
+	stat.CovarianceMatrix(&cov, x, nil)
+
+	var eig mat.EigenSym
+	if !eig.Factorize(&cov, true) {
+		return nil, errors.New("PCA: eigendecomposition failed")
+	}
+
+	var vectors mat.Dense
+	eig.VectorsTo(&vectors)
+
+	// The eigenvalues are in ascending order, so the principal components
+	// are the last columns.
+	p := &pcaProjection{mean: mean}
+	for j := dim - 1; j >= dim-n; j-- {
+		p.components = append(p.components, mat.Col(nil, j, &vectors))
+	}
+
+	return p, nil
+}
+
+// loadPCA reads the projection fitted by FitPCA, if any.
+func (s *VectorStore) loadPCA() error {
+	return s.db.View(func(txn *badger.Txn) error {
+		val, err := getMeta(txn, s.keys.meta(metaPCA))
+		if err != nil || val == nil {
+			return err
+		}
+
+		p, err := decodePCAProjection(val)
+		if err != nil {
+			return fmt.Errorf("PCA projection: %w", err)
+		}
+
+		s.pca.Store(p)
+		return nil
+	})
+}
+
+// storedEmbeddingDim returns the dimension embeddings from s.e have once
+// stored, or 0 if the embedder doesn't know its dimension.
+func (s *VectorStore) storedEmbeddingDim() (int, error) {
+	dim := s.e.Dim()
+
+	p := s.pca.Load()
+	if p == nil {
+		return dim, nil
+	}
+
+	if dim != 0 && dim != p.in() {
+		return 0, fmt.Errorf("%w: PCA projects %d dimensions, embedder has %d", ErrDimensionMismatch, p.in(), dim)
+	}
+
+	return p.out(), nil
+}
+
+// FitPCA computes the top components principal components of the stored
+// embeddings and projects every stored embedding onto them. From then on,
+// embeddings of inserted texts and queries are projected too, so search
+// is faster and the index smaller, at some cost in accuracy. The index and
+// the packed store are rebuilt at the reduced dimension. Reindex undoes
+// the projection.
+//
+// The projection is saved before the embeddings are rewritten, so if
+// FitPCA is interrupted, calling it again with the same number of
+// components finishes the job; until then searches should wait. Writes
+// made while it runs may be missed.
+func (s *VectorStore) FitPCA(ctx context.Context, components int) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	if components < 1 {
+		return fmt.Errorf("components must be at least 1, got %d", components)
+	}
+
+	p := s.pca.Load()
+	if p != nil && p.out() != components {
+		return fmt.Errorf("embeddings are already reduced to %d components, Reindex to start over", p.out())
+	}
+
+	if p == nil {
+		var vecs [][]float64
+		if err := s.forEachVectorBatch(ctx, func(_ []uint64, batch [][]float64) error {
+			vecs = append(vecs, batch...)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(vecs) < 2 {
+			return fmt.Errorf("PCA needs at least 2 stored embeddings, got %d", len(vecs))
+		}
+		if components >= len(vecs[0]) {
+			return fmt.Errorf("can't reduce %d dimensions to %d components", len(vecs[0]), components)
+		}
+
+		var err error
+		if p, err = fitPCA(vecs, components); err != nil {
+			return err
+		}
+
+		if err := s.update(ctx, func(txn *badger.Txn) error {
+			return txn.Set(s.keys.meta(metaPCA), p.encode())
+		}); err != nil {
+			return err
+		}
+		s.pca.Store(p)
+	}
+
+	var packed bool
+	if err := s.db.View(func(txn *badger.Txn) (err error) {
+		packed, err = s.packedEnabled(txn)
+		return
+	}); err != nil {
+		return err
+	}
+
+	if err := s.forEachVectorBatch(ctx, func(ids []uint64, vecs [][]float64) error {
+		return s.update(ctx, func(txn *badger.Txn) error {
+			for i, id := range ids {
+				// Embeddings already projected by an interrupted FitPCA
+				// are left alone.
+				if len(vecs[i]) != p.in() {
+					continue
+				}
+
+				item, err := txn.Get(s.keys.doc(id))
+				if err == badger.ErrKeyNotFound {
+					continue
+				} else if err != nil {
+					return err
+				}
+
+				var rec record
+				if err := item.Value(func(val []byte) (err error) {
+					rec, err = s.format().decodeRecord(val)
+					return
+				}); err != nil {
+					return err
+				}
+
+				if rec.Embedding, err = p.project(rec.Embedding); err != nil {
+					return err
+				}
+				if s.opts.Normalize {
+					normalize(rec.Embedding)
+				}
+
+				if err := s.setRecord(txn, s.keys.doc(id), rec); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		return txn.Set(s.keys.meta(metaDim), binary.BigEndian.AppendUint32(nil, uint32(components)))
+	}); err != nil {
+		return err
+	}
+
+	if err := s.BuildIndex(ctx); err != nil {
+		return err
+	}
+	if packed {
+		return s.RebuildPackedStore(ctx)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package vectorstore_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFitPCA(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(40, 4)...)
+
+	if err := s.FitPCA(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Dimension != 4 {
+		t.Errorf("after FitPCA, Dimension = %d, want 4", st.Dimension)
+	}
+
+	id := insertAll(t, s, "document 99 about topic2 things")[0]
+	doc, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Embedding) != 4 {
+		t.Errorf("a document inserted after FitPCA has %d dimensions, want 4", len(doc.Embedding))
+	}
+
+	results, err := s.Nearest(ctx, "document 99 about topic2 things", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Score < 0.999 {
+		t.Errorf("Nearest of a stored text after FitPCA = %+v", results)
+	}
+
+	if err := s.FitPCA(ctx, 8); err == nil {
+		t.Error("FitPCA over an existing projection succeeded")
+	}
+}
+
+func TestFitPCATopResult(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	// Four clusters, each dominated by its own colour. The colours hash into
+	// different buckets of the HashEmbedder.
+	colours := []string{"red", "blue", "gold", "cyan"}
+	for i := 0; i < 40; i++ {
+		c := colours[i%len(colours)]
+		insertAll(t, s, fmt.Sprintf("%s %s %s item%d", c, c, c, i))
+	}
+
+	before := make([]string, len(colours))
+	for i, c := range colours {
+		results, err := s.Nearest(ctx, c, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[i] = results[0].Text
+	}
+
+	if err := s.FitPCA(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, c := range colours {
+		results, err := s.Nearest(ctx, c, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || !strings.HasPrefix(results[0].Text, c+" ") {
+			t.Errorf("after FitPCA, the top result for %q is %+v, want one from its cluster like %q", c, results, before[i])
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package vectorstore
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+)
+
+// ModelPool shares encoding across several model instances. Cybertron
+// models are not documented as safe for concurrent use, so each instance
+// only ever runs one Encode at a time and callers wait for a free one.
+type ModelPool struct {
+	models []textencoding.Interface
+	free   chan textencoding.Interface
+}
+
+var _ textencoding.Interface = (*ModelPool)(nil)
+
+// NewModelPool returns a pool over models. Use it with
+// Options.Concurrency set to len(models).
+func NewModelPool(models ...textencoding.Interface) *ModelPool {
+	p := &ModelPool{models: models, free: make(chan textencoding.Interface, len(models))}
+	for _, m := range models {
+		p.free <- m
+	}
+
+	return p
+}
+
+func (p *ModelPool) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	var m textencoding.Interface
+	select {
+	case m = <-p.free:
+	case <-ctx.Done():
+		return textencoding.Response{}, ctx.Err()
+	}
+	defer func() { p.free <- m }()
+
+	return m.Encode(ctx, text, poolingStrategy)
+}
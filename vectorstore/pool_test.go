@@ -0,0 +1,132 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/spago/mat"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// exclusiveModel is a textencoding.Interface over a HashEmbedder that
+// counts its encodings and fails the test if it is used by two goroutines
+// at once.
+type exclusiveModel struct {
+	*vectortest.HashEmbedder
+	t       testing.TB
+	delay   time.Duration
+	busy    atomic.Bool
+	encodes atomic.Int64
+}
+
+func (m *exclusiveModel) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	if !m.busy.CompareAndSwap(false, true) {
+		m.t.Error("a model instance was used concurrently")
+	}
+	defer m.busy.Store(false)
+
+	m.encodes.Add(1)
+	time.Sleep(m.delay)
+
+	vec, err := m.Embed(ctx, text)
+	if err != nil {
+		return textencoding.Response{}, err
+	}
+
+	return textencoding.Response{Vector: mat.NewDense[float64](mat.WithBacking(vec))}, nil
+}
+
+// newPool returns a ModelPool of n exclusiveModels and the models.
+func newPool(t testing.TB, n int, delay time.Duration) (*vectorstore.ModelPool, []*exclusiveModel) {
+	models := make([]*exclusiveModel, n)
+	instances := make([]textencoding.Interface, n)
+	for i := range models {
+		models[i] = &exclusiveModel{HashEmbedder: vectortest.NewHashEmbedder(testDim), t: t, delay: delay}
+		instances[i] = models[i]
+	}
+
+	return vectorstore.NewModelPool(instances...), models
+}
+
+func TestModelPool(t *testing.T) {
+	ctx := context.Background()
+	pool, models := newPool(t, 4, time.Millisecond)
+	s, err := vectorstore.Open(testOptions(t).WithConcurrency(4).WithBatchSize(16), vectorstore.NewCybertronEmbedder(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	texts := numbered(100, 10)
+	if _, err := s.InsertBatch(ctx, texts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var encodes int64
+	for _, m := range models {
+		encodes += m.encodes.Load()
+	}
+	if encodes != int64(len(texts)) {
+		t.Errorf("the pool encoded %d times, want %d", encodes, len(texts))
+	}
+
+	results, err := s.Nearest(ctx, "document", 2*len(texts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := make(map[string]int)
+	for _, r := range results {
+		stored[r.Text]++
+	}
+	for _, text := range texts {
+		if stored[text] != 1 {
+			t.Errorf("%q was stored %d times, want once", text, stored[text])
+		}
+	}
+	if len(results) != len(texts) {
+		t.Errorf("%d documents were stored, want %d", len(results), len(texts))
+	}
+}
+
+func TestModelPoolCancel(t *testing.T) {
+	// Every instance is busy, here because there are none, so Encode waits
+	// until its context is done.
+	pool := vectorstore.NewModelPool()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Encode(ctx, "text", 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Encode with no free instance returned %v, want DeadlineExceeded", err)
+	}
+}
+
+func BenchmarkModelPool(b *testing.B) {
+	ctx := context.Background()
+	texts := benchTexts(256)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			// The delay stands in for a model's encoding time, which
+			// dominates an insert.
+			pool, _ := newPool(b, workers, 100*time.Microsecond)
+			s, err := vectorstore.Open(testOptions(b).WithConcurrency(workers), vectorstore.NewCybertronEmbedder(pool))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer s.Close()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := s.InsertBatch(ctx, texts, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(texts))/b.Elapsed().Seconds(), "docs/s")
+		})
+	}
+}
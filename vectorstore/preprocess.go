@@ -0,0 +1,68 @@
+package vectorstore
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Preprocessor rewrites text before it is embedded, for inserts and queries
+// alike. The text is stored as it was given.
+type Preprocessor func(text string) string
+
+// Lowercase maps text to lower case, for embedders that tell cases apart
+// when the texts shouldn't be.
+func Lowercase(text string) string {
+	return strings.ToLower(text)
+}
+
+// NFC puts text in Unicode normalization form C, so that a composed
+// character and its decomposed sequence embed the same.
+func NFC(text string) string {
+	return norm.NFC.String(text)
+}
+
+// CollapseWhitespace trims text and replaces each run of whitespace in it
+// with a single space.
+func CollapseWhitespace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Preprocessors returns a Preprocessor applying each of ps in turn.
+func Preprocessors(ps ...Preprocessor) Preprocessor {
+	return func(text string) string {
+		for _, p := range ps {
+			text = p(text)
+		}
+
+		return text
+	}
+}
+
+// preprocessorNames are the built-in Preprocessors ParsePreprocessor knows.
+var preprocessorNames = map[string]Preprocessor{
+	"lowercase": Lowercase,
+	"nfc":       NFC,
+	"collapse":  CollapseWhitespace,
+}
+
+// ParsePreprocessor returns the built-in Preprocessors named in the comma
+// separated list s, applied in order: lowercase, nfc and collapse. An empty
+// s returns nil, for no preprocessing.
+func ParsePreprocessor(s string) (Preprocessor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var ps []Preprocessor
+	for _, name := range strings.Split(s, ",") {
+		p, ok := preprocessorNames[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown preprocessor %q", name)
+		}
+		ps = append(ps, p)
+	}
+
+	return Preprocessors(ps...), nil
+}
@@ -0,0 +1,60 @@
+package vectorstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+func TestPreprocessors(t *testing.T) {
+	p, err := vectorstore.ParsePreprocessor("collapse, lowercase,nfc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p("  Café \n AU  LAIT "); got != "café au lait" {
+		t.Errorf("preprocessed text = %q", got)
+	}
+
+	if p, err := vectorstore.ParsePreprocessor(""); p != nil || err != nil {
+		t.Errorf("ParsePreprocessor(\"\") = %v, %v, want nil", p, err)
+	}
+	if _, err := vectorstore.ParsePreprocessor("lowercase,stem"); err == nil {
+		t.Error("ParsePreprocessor of an unknown name succeeded")
+	}
+}
+
+// recordingEmbedder remembers the last text it embedded.
+type recordingEmbedder struct {
+	vectorstore.Embedder
+	last string
+}
+
+func (e *recordingEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	e.last = text
+	return e.Embedder.Embed(ctx, text)
+}
+
+func TestPreprocessorOption(t *testing.T) {
+	ctx := context.Background()
+	e := &recordingEmbedder{Embedder: vectortest.NewHashEmbedder(testDim)}
+	s := openStoreWith(t, memOptions().WithPreprocessor(vectorstore.CollapseWhitespace), e)
+
+	id := insertAll(t, s, "  spaced   out  ")[0]
+	if e.last != "spaced out" {
+		t.Errorf("insert embedded %q, want the preprocessed text", e.last)
+	}
+
+	doc, err := s.Get(ctx, id)
+	if err != nil || doc.Text != "  spaced   out  " {
+		t.Errorf("Get = %q, %v, want the text as given", doc.Text, err)
+	}
+
+	if _, err := s.Nearest(ctx, " query\t text ", 1); err != nil {
+		t.Fatal(err)
+	}
+	if e.last != "query text" {
+		t.Errorf("search embedded %q, want the preprocessed query", e.last)
+	}
+}
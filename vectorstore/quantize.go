@@ -0,0 +1,67 @@
+package vectorstore
+
+import "math"
+
+// Quantize maps v onto int8 symmetrically around zero, returning the
+// quantized values and the scale that recovers them: v[i] ≈ q[i] * scale.
+// The per vector scale keeps the full int8 range in use whatever the
+// embedding's magnitude.
+func Quantize(v []float64) ([]int8, float32) {
+	maxAbs := 0.0
+	for _, x := range v {
+		maxAbs = math.Max(maxAbs, math.Abs(x))
+	}
+
+	q := make([]int8, len(v))
+	if maxAbs == 0 {
+		return q, 0
+	}
+
+	scale := maxAbs / math.MaxInt8
+	for i, x := range v {
+		q[i] = int8(math.Round(x / scale))
+	}
+
+	return q, float32(scale)
+}
+
+// Dequantize reverses Quantize.
+func Dequantize(q []int8, scale float32) []float64 {
+	v := make([]float64, len(q))
+	for i, x := range q {
+		v[i] = float64(x) * float64(scale)
+	}
+
+	return v
+}
+
+func dotInt8(a, b []int8) int64 {
+	var sum int64
+	for i := range a {
+		sum += int64(a[i]) * int64(b[i])
+	}
+
+	return sum
+}
+
+// scoreQuantized scores a quantized stored vector against a quantized query
+// without expanding either to floats. It only knows the inner product
+// metrics and reports false for anything else.
+func scoreQuantized(metric DistanceMetric, query []int8, queryScale float32, v storedVector) (float64, bool) {
+	switch metric.(type) {
+	case DotProduct:
+		return float64(dotInt8(query, v.q)) * float64(queryScale) * float64(v.scale), true
+	case CosineSimilarity:
+		qq, vv := dotInt8(query, query), dotInt8(v.q, v.q)
+		if qq == 0 || vv == 0 {
+			return 0, true
+		}
+
+		return float64(dotInt8(query, v.q)) / (math.Sqrt(float64(qq)) * math.Sqrt(float64(vv))), true
+	case CosineDistance:
+		score, ok := scoreQuantized(CosineSimilarity{}, query, queryScale, v)
+		return 1 - score, ok
+	default:
+		return 0, false
+	}
+}
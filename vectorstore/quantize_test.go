@@ -0,0 +1,33 @@
+package vectorstore
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuantize(t *testing.T) {
+	v := []float64{0.5, -1, 0.25, 0}
+	q, scale := Quantize(v)
+	if q[1] != -math.MaxInt8 {
+		t.Errorf("the largest magnitude quantizes to %d, want %d", q[1], -math.MaxInt8)
+	}
+
+	got := Dequantize(q, scale)
+	for i := range v {
+		if math.Abs(got[i]-v[i]) > float64(scale)/2+1e-9 {
+			t.Errorf("Dequantize(Quantize(%v)) = %v", v, got)
+			break
+		}
+	}
+
+	zero, scale := Quantize(make([]float64, 3))
+	if scale != 0 || zero[0] != 0 {
+		t.Errorf("Quantize of zeros = %v, %v", zero, scale)
+	}
+}
+
+func TestDotInt8(t *testing.T) {
+	if got := dotInt8([]int8{1, -2, 3}, []int8{4, 5, -6}); got != 4-10-18 {
+		t.Errorf("dotInt8 = %d", got)
+	}
+}
@@ -0,0 +1,57 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RecencyReranker blends each result's similarity with how recently it was
+// inserted, to surface fresher documents:
+//
+//	score = similarity*(1-Weight) + recency*Weight
+//
+// where recency halves every HalfLife of age, from 1 for a document inserted
+// now. Documents without a creation time count as infinitely old. The
+// similarity is the result's Score, so it suits metrics where higher is
+// better, such as CosineSimilarity.
+type RecencyReranker struct {
+	// Weight is how much recency counts, from 0 for not at all to 1 for
+	// ranking by age alone.
+	Weight float64
+
+	// HalfLife is the age at which a document's recency score is halved.
+	HalfLife time.Duration
+
+	// Now returns the time ages are measured from. Nil uses time.Now.
+	Now func() time.Time
+}
+
+// Rerank implements Reranker.
+func (r RecencyReranker) Rerank(ctx context.Context, query string, results []Result) ([]float64, error) {
+	if r.Weight < 0 || r.Weight > 1 {
+		return nil, fmt.Errorf("recency weight must be between 0 and 1, got %v", r.Weight)
+	}
+	if r.HalfLife <= 0 {
+		return nil, fmt.Errorf("recency half-life must be positive, got %v", r.HalfLife)
+	}
+
+	now := time.Now()
+	if r.Now != nil {
+		now = r.Now()
+	}
+
+	scores := make([]float64, len(results))
+	for i, res := range results {
+		var recency float64
+		if res.CreatedAt != 0 {
+			age := max(now.Sub(time.Unix(0, res.CreatedAt)), 0)
+			recency = math.Exp2(-float64(age) / float64(r.HalfLife))
+		}
+
+		scores[i] = res.Score*(1-r.Weight) + recency*r.Weight
+	}
+
+	return scores, nil
+}
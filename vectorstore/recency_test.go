@@ -0,0 +1,63 @@
+package vectorstore_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestRecencyReranker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000000, 0)
+	r := vectorstore.RecencyReranker{Weight: 0.5, HalfLife: time.Hour, Now: func() time.Time { return now }}
+
+	results := []vectorstore.Result{
+		{ID: 1, Score: 0.8, CreatedAt: now.UnixNano()},
+		{ID: 2, Score: 0.8, CreatedAt: now.Add(-time.Hour).UnixNano()},
+		{ID: 3, Score: 0.8},
+	}
+	scores, err := r.Rerank(ctx, "", results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float64{0.4 + 0.5, 0.4 + 0.25, 0.4}
+	for i := range want {
+		if math.Abs(scores[i]-want[i]) > 1e-9 {
+			t.Errorf("scores = %v, want %v", scores, want)
+			break
+		}
+	}
+
+	for _, bad := range []vectorstore.RecencyReranker{{Weight: -0.1, HalfLife: time.Hour}, {Weight: 0.5}} {
+		if _, err := bad.Rerank(ctx, "", results); err == nil {
+			t.Errorf("Rerank with %+v succeeded", bad)
+		}
+	}
+}
+
+func TestSearchRecency(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	// Two documents that embed the same, inserted 2ms apart.
+	older := insertAll(t, s, "red apples")[0]
+	time.Sleep(2 * time.Millisecond)
+	newer := insertAll(t, s, "Red apples!")[0]
+	doc, err := s.Get(ctx, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := vectorstore.RecencyReranker{Weight: 0.1, HalfLife: time.Millisecond, Now: func() time.Time { return time.Unix(0, doc.CreatedAt) }}
+	results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 2, Reranker: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != newer || results[1].ID != older {
+		t.Errorf("recency ranked %v, want the newer %d first", resultIDs(results), newer)
+	}
+}
@@ -0,0 +1,463 @@
+package vectorstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const recordVersion = 6
+
+// DType is the element type embeddings are stored as.
+type DType uint8
+
+const (
+	DTypeFloat64 DType = iota + 1
+	DTypeFloat32
+	// DTypeInt8 scalar quantizes each embedding to int8 with a per vector
+	// scale factor. See Quantize.
+	DTypeInt8
+	// DTypeSparse stores only the non-zero elements of each embedding, as
+	// float32, for embeddings that are mostly zeros. See SparseVector.
+	DTypeSparse
+	// DTypeFloat16 stores each element as an IEEE 754 half precision
+	// float, a quarter the size of float64 and accurate to about three
+	// significant digits, which keeps rankings close to float32's.
+	DTypeFloat16
+)
+
+// dtypeNorm is set in a record's dtype byte when the embedding's L2 norm is
+// stored with it.
+const dtypeNorm DType = 0x80
+
+func (d DType) String() string {
+	switch d {
+	case DTypeFloat64:
+		return "float64"
+	case DTypeFloat32:
+		return "float32"
+	case DTypeFloat16:
+		return "float16"
+	case DTypeInt8:
+		return "int8"
+	case DTypeSparse:
+		return "sparse"
+	default:
+		return fmt.Sprintf("DType(%d)", uint8(d))
+	}
+}
+
+// ParseDType returns the DType named by s, as printed by DType.String.
+func ParseDType(s string) (DType, error) {
+	for _, d := range []DType{DTypeFloat64, DTypeFloat32, DTypeFloat16, DTypeInt8, DTypeSparse} {
+		if d.String() == s {
+			return d, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown dtype %q", s)
+}
+
+// dense reports whether d stores every element as a float.
+func (d DType) dense() bool {
+	return d == DTypeFloat64 || d == DTypeFloat32 || d == DTypeFloat16
+}
+
+func (d DType) width() int {
+	switch d {
+	case DTypeFloat64:
+		return 8
+	case DTypeFloat32, DTypeSparse:
+		return 4
+	case DTypeFloat16:
+		return 2
+	case DTypeInt8:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// record is the value stored under a document key:
+//
+//	version byte
+//	uvarint text length, text
+//	uvarint metadata count, then per entry (since version 3, sorted by key):
+//	    uvarint key length, key, uvarint value length, value
+//	uvarint parent document ID, 0 for none (since version 4)
+//	varint creation time in Unix nanoseconds, 0 if unknown (since version 5)
+//	dtype byte (since version 2, version 1 is always float64), with
+//	    dtypeNorm set if the norm follows the dimension (since version 6)
+//	uvarint dimension
+//	float64 L2 norm of the elements as stored (if dtypeNorm is set)
+//	float32 scale (int8 only)
+//	dimension little endian elements of dtype, or for sparse, a uvarint
+//	    count of non-zero elements, then per element the uvarint gap from
+//	    the previous index and a little endian float32
+//
+// The byte order is fixed rather than the host's, so stores and backups can
+// be copied between machines of either endianness.
+type record struct {
+	Text      string
+	Metadata  map[string]string
+	Parent    uint64
+	CreatedAt int64
+	Embedding []float64
+
+	// Sparse writes Embedding as DTypeSparse whatever dtype it is encoded
+	// with.
+	Sparse bool
+
+	// Vectors are the document's named vectors, written under their own
+	// keys with it rather than as part of its encoding.
+	Vectors map[string]record
+}
+
+// encodeRecord lays r out with its embedding as dtype. If norm is set, the
+// L2 norm of a float64, float32 or float16 embedding is stored with it.
+func encodeRecord(r record, dtype DType, norm bool) []byte {
+	if r.Sparse {
+		dtype = DTypeSparse
+	}
+	norm = norm && dtype.dense()
+
+	buf := make([]byte, 0, 14+2*binary.MaxVarintLen64+len(r.Text)+dtype.width()*len(r.Embedding))
+	buf = append(buf, recordVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(r.Text)))
+	buf = append(buf, r.Text...)
+
+	keys := make([]string, 0, len(r.Metadata))
+	for k := range r.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf = binary.AppendUvarint(buf, uint64(len(keys)))
+	for _, k := range keys {
+		buf = appendString(buf, k)
+		buf = appendString(buf, r.Metadata[k])
+	}
+
+	buf = binary.AppendUvarint(buf, r.Parent)
+	buf = binary.AppendVarint(buf, r.CreatedAt)
+
+	if norm {
+		buf = append(buf, byte(dtype|dtypeNorm))
+	} else {
+		buf = append(buf, byte(dtype))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(r.Embedding)))
+	if norm {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(storedNorm(r.Embedding, dtype)))
+	}
+
+	switch dtype {
+	case DTypeFloat32:
+		for _, x := range r.Embedding {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(x)))
+		}
+	case DTypeFloat16:
+		for _, x := range r.Embedding {
+			buf = binary.LittleEndian.AppendUint16(buf, float16bits(float32(x)))
+		}
+	case DTypeInt8:
+		q, scale := Quantize(r.Embedding)
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(scale))
+		for _, x := range q {
+			buf = append(buf, byte(x))
+		}
+	case DTypeSparse:
+		sv := SparseFromDense(r.Embedding)
+		buf = binary.AppendUvarint(buf, uint64(len(sv.Indices)))
+		prev := uint32(0)
+		for i, idx := range sv.Indices {
+			buf = binary.AppendUvarint(buf, uint64(idx-prev))
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(sv.Values[i]))
+			prev = idx
+		}
+	default:
+		for _, x := range r.Embedding {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(x))
+		}
+	}
+
+	return buf
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func decodeRecord(val []byte) (r record, err error) {
+	v, err := decodeDocument(val, &r)
+	r.Embedding = v.float64s()
+
+	return r, err
+}
+
+// decodeDocument reads the text, metadata, parent and creation time of a
+// record into r and returns its embedding without expanding it.
+func decodeDocument(val []byte, r *record) (storedVector, error) {
+	text, rest, err := decodeText(val)
+	if err != nil {
+		return storedVector{}, err
+	}
+
+	r.Text = string(text)
+
+	if rest, err = decodeFields(val[0], rest, r); err != nil {
+		return storedVector{}, err
+	}
+
+	return decodeEmbedding(val[0], rest)
+}
+
+// decodeVector reads only the embedding from a record, skipping the text.
+func decodeVector(val []byte) (storedVector, error) {
+	_, rest, err := decodeText(val)
+	if err != nil {
+		return storedVector{}, err
+	}
+
+	if rest, err = decodeFields(val[0], rest, nil); err != nil {
+		return storedVector{}, err
+	}
+
+	return decodeEmbedding(val[0], rest)
+}
+
+// decodeFields reads the metadata, parent and creation time that follow the
+// text of a record into r, leaving r.Metadata nil if there is none. If r is
+// nil they are skipped.
+func decodeFields(version byte, val []byte, r *record) ([]byte, error) {
+	if version < 3 {
+		return val, nil
+	}
+
+	n, l := binary.Uvarint(val)
+	if l <= 0 || n > uint64(len(val)) {
+		return nil, ErrCorruptRecord
+	}
+	val = val[l:]
+
+	if n > 0 && r != nil {
+		r.Metadata = make(map[string]string, n)
+	}
+
+	for i := uint64(0); i < n; i++ {
+		var k, v []byte
+		var ok bool
+		if k, val, ok = readString(val); !ok {
+			return nil, ErrCorruptRecord
+		}
+		if v, val, ok = readString(val); !ok {
+			return nil, ErrCorruptRecord
+		}
+		if r != nil {
+			r.Metadata[string(k)] = string(v)
+		}
+	}
+
+	if version < 4 {
+		return val, nil
+	}
+
+	parent, l := binary.Uvarint(val)
+	if l <= 0 {
+		return nil, ErrCorruptRecord
+	}
+	if r != nil {
+		r.Parent = parent
+	}
+	val = val[l:]
+
+	if version < 5 {
+		return val, nil
+	}
+
+	createdAt, l := binary.Varint(val)
+	if l <= 0 {
+		return nil, ErrCorruptRecord
+	}
+	if r != nil {
+		r.CreatedAt = createdAt
+	}
+
+	return val[l:], nil
+}
+
+func readString(val []byte) (s, rest []byte, ok bool) {
+	n, l := binary.Uvarint(val)
+	if l <= 0 || uint64(len(val)-l) < n {
+		return nil, nil, false
+	}
+
+	return val[l : l+int(n)], val[l+int(n):], true
+}
+
+func decodeText(val []byte) (text, rest []byte, err error) {
+	if len(val) == 0 {
+		return nil, nil, ErrCorruptRecord
+	}
+	if val[0] < 1 || val[0] > recordVersion {
+		return nil, nil, fmt.Errorf("unsupported record version %d", val[0])
+	}
+
+	n, l := binary.Uvarint(val[1:])
+	if l <= 0 || uint64(len(val)-1-l) < n {
+		return nil, nil, ErrCorruptRecord
+	}
+	start := 1 + l
+
+	return val[start : start+int(n)], val[start+int(n):], nil
+}
+
+// storedVector is an embedding as decoded from a record. Quantized
+// embeddings are kept as int8 and sparse ones as their non-zero elements, so
+// they can be scored without expanding them.
+type storedVector struct {
+	dense  []float64
+	q      []int8
+	scale  float32
+	sparse *SparseVector
+
+	// norm is the L2 norm of dense if it was stored, or else 0.
+	norm float64
+}
+
+func (v storedVector) dim() int {
+	if v.q != nil {
+		return len(v.q)
+	}
+	if v.sparse != nil {
+		return v.sparse.Dim
+	}
+
+	return len(v.dense)
+}
+
+func (v storedVector) float64s() []float64 {
+	if v.q != nil {
+		return Dequantize(v.q, v.scale)
+	}
+	if v.sparse != nil {
+		return v.sparse.Dense()
+	}
+
+	return v.dense
+}
+
+func decodeEmbedding(version byte, val []byte) (v storedVector, err error) {
+	dtype := DTypeFloat64
+	hasNorm := false
+	if version >= 2 {
+		if len(val) == 0 {
+			return v, ErrCorruptRecord
+		}
+		dtype, val = DType(val[0]), val[1:]
+	}
+	if version >= 6 && dtype&dtypeNorm != 0 {
+		dtype, hasNorm = dtype&^dtypeNorm, true
+	}
+
+	width := dtype.width()
+	if width == 0 {
+		return v, fmt.Errorf("unsupported dtype %v", dtype)
+	}
+
+	dim, l := binary.Uvarint(val)
+	if l <= 0 {
+		return v, ErrCorruptRecord
+	}
+	val = val[l:]
+
+	if hasNorm {
+		if len(val) < 8 {
+			return v, ErrCorruptRecord
+		}
+		v.norm, val = math.Float64frombits(binary.LittleEndian.Uint64(val)), val[8:]
+	}
+
+	if dtype == DTypeSparse {
+		return decodeSparse(dim, val)
+	}
+
+	if dtype == DTypeInt8 {
+		if len(val) < 4 {
+			return v, ErrCorruptRecord
+		}
+		v.scale, val = math.Float32frombits(binary.LittleEndian.Uint32(val)), val[4:]
+	}
+
+	if dim > uint64(len(val))/uint64(width) || dim*uint64(width) != uint64(len(val)) {
+		return v, ErrCorruptRecord
+	}
+
+	switch dtype {
+	case DTypeFloat32:
+		v.dense = make([]float64, dim)
+		for i := range v.dense {
+			v.dense[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(val[i*4:])))
+		}
+	case DTypeFloat16:
+		v.dense = make([]float64, dim)
+		for i := range v.dense {
+			v.dense[i] = float64(float16frombits(binary.LittleEndian.Uint16(val[i*2:])))
+		}
+	case DTypeInt8:
+		v.q = make([]int8, dim)
+		for i := range v.q {
+			v.q[i] = int8(val[i])
+		}
+	default:
+		v.dense = make([]float64, dim)
+		for i := range v.dense {
+			v.dense[i] = math.Float64frombits(binary.LittleEndian.Uint64(val[i*8:]))
+		}
+	}
+
+	return v, nil
+}
+
+// decodeSparse reads the elements of a DTypeSparse embedding of dimension
+// dim.
+func decodeSparse(dim uint64, val []byte) (v storedVector, err error) {
+	// The store's dimension is kept as a uint32, and each element takes at
+	// least 5 bytes.
+	if dim > math.MaxUint32 {
+		return v, ErrCorruptRecord
+	}
+	n, l := binary.Uvarint(val)
+	if l <= 0 || n > uint64(len(val)-l)/5 {
+		return v, ErrCorruptRecord
+	}
+	val = val[l:]
+
+	sv := &SparseVector{Dim: int(dim), Indices: make([]uint32, n), Values: make([]float32, n)}
+	idx := uint64(0)
+	for i := range sv.Indices {
+		gap, l := binary.Uvarint(val)
+		if l <= 0 || len(val)-l < 4 {
+			return v, ErrCorruptRecord
+		}
+		// Comparing the gap rather than the sum keeps a huge gap from
+		// wrapping the index back into range.
+		if gap >= dim-idx || (i > 0 && gap == 0) {
+			return v, ErrCorruptRecord
+		}
+		idx += gap
+
+		sv.Indices[i] = uint32(idx)
+		sv.Values[i] = math.Float32frombits(binary.LittleEndian.Uint32(val[l:]))
+		val = val[l+4:]
+	}
+
+	if len(val) != 0 {
+		return v, ErrCorruptRecord
+	}
+	v.sparse = sv
+
+	return v, nil
+}
@@ -0,0 +1,262 @@
+package vectorstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func testRecord() record {
+	return record{
+		Text:      "hello world",
+		Metadata:  map[string]string{"b": "2", "a": "1"},
+		CreatedAt: 1700000000000000000,
+		Embedding: []float64{0.5, -0.25, 0, 1},
+	}
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	for _, dtype := range []DType{DTypeFloat64, DTypeFloat32, DTypeFloat16, DTypeInt8, DTypeSparse} {
+		want := testRecord()
+		got, err := decodeRecord(encodeRecord(want, dtype, false))
+		if err != nil {
+			t.Fatalf("%v: %v", dtype, err)
+		}
+
+		if got.Text != want.Text || got.CreatedAt != want.CreatedAt {
+			t.Errorf("%v: decoded %+v", dtype, got)
+		}
+		if !reflect.DeepEqual(got.Metadata, want.Metadata) {
+			t.Errorf("%v: metadata = %v", dtype, got.Metadata)
+		}
+		checkEmbedding(t, dtype, got.Embedding, want.Embedding, 0.01)
+
+		vec, err := decodeVector(encodeRecord(want, dtype, false))
+		if err != nil {
+			t.Fatalf("%v: decodeVector: %v", dtype, err)
+		}
+		checkEmbedding(t, dtype, vec.float64s(), want.Embedding, 0.01)
+
+		// The norm, where it is stored, is that of the decoded embedding.
+		normed, err := decodeVector(encodeRecord(want, dtype, true))
+		if err != nil {
+			t.Fatalf("%v: decodeVector with a norm: %v", dtype, err)
+		}
+		checkEmbedding(t, dtype, normed.float64s(), want.Embedding, 0.01)
+		if dtype.dense() {
+			decoded := normed.float64s()
+			if want := math.Sqrt(dot(decoded, decoded)); math.Abs(normed.norm-want) > 1e-12 {
+				t.Errorf("%v: stored norm = %v, want %v", dtype, normed.norm, want)
+			}
+		} else if normed.norm != 0 {
+			t.Errorf("%v: a norm of %v was stored", dtype, normed.norm)
+		}
+	}
+}
+
+// checkEmbedding checks that got matches want to within tolerance.
+func checkEmbedding(t *testing.T, dtype DType, got, want []float64, tolerance float64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%v: embedding = %v, want %v", dtype, got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%v: embedding = %v, want %v", dtype, got, want)
+			break
+		}
+	}
+}
+
+func TestFloat32RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	want := make([]float64, 384)
+	for i := range want {
+		want[i] = r.NormFloat64()
+	}
+
+	got, err := decodeVector(encodeRecord(record{Embedding: want}, DTypeFloat32, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// float32 keeps 24 bits of mantissa, a relative error of at most 2^-24.
+	for i, x := range got.float64s() {
+		if math.Abs(x-want[i]) > math.Abs(want[i])*0x1p-24 {
+			t.Fatalf("component %d is %v after the round trip, want %v", i, x, want[i])
+		}
+	}
+}
+
+func TestDecodeVersion1(t *testing.T) {
+	// Version 1 records have no dtype byte and always hold float64s.
+	val := []byte{1, 2, 'h', 'i', 1}
+	val = binary.LittleEndian.AppendUint64(val, math.Float64bits(0.5))
+
+	got, err := decodeRecord(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "hi" || len(got.Embedding) != 1 || got.Embedding[0] != 0.5 {
+		t.Errorf("decoded %+v", got)
+	}
+}
+
+func TestDecodeVersion2(t *testing.T) {
+	// Version 2 records have a dtype byte but no metadata.
+	val := []byte{2, 2, 'h', 'i', byte(DTypeFloat64), 1}
+	val = binary.LittleEndian.AppendUint64(val, math.Float64bits(0.5))
+
+	got, err := decodeRecord(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "hi" || got.Metadata != nil || len(got.Embedding) != 1 || got.Embedding[0] != 0.5 {
+		t.Errorf("decoded %+v", got)
+	}
+}
+
+func TestDecodeVersion4(t *testing.T) {
+	// Version 4 records have a parent but no creation time.
+	val := []byte{4, 2, 'h', 'i', 0, 7, byte(DTypeFloat64), 1}
+	val = binary.LittleEndian.AppendUint64(val, math.Float64bits(0.5))
+
+	got, err := decodeRecord(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "hi" || got.Parent != 7 || got.CreatedAt != 0 || len(got.Embedding) != 1 || got.Embedding[0] != 0.5 {
+		t.Errorf("decoded %+v", got)
+	}
+}
+
+func TestDecodeUnsupportedDType(t *testing.T) {
+	if _, err := decodeEmbedding(recordVersion, []byte{0x7f, 0}); err == nil {
+		t.Error("decoding an unknown dtype succeeded")
+	}
+	if _, err := decodeEmbedding(recordVersion, nil); !errors.Is(err, ErrCorruptRecord) {
+		t.Errorf("decoding an empty embedding returned %v, want ErrCorruptRecord", err)
+	}
+}
+
+func TestDecodeOverflowingDim(t *testing.T) {
+	// A dimension of 2^62 float32s is 0 bytes once multiplied out in 64
+	// bits, which matches the empty remainder.
+	val := binary.AppendUvarint([]byte{byte(DTypeFloat32)}, 1<<62)
+	if _, err := decodeEmbedding(recordVersion, val); !errors.Is(err, ErrCorruptRecord) {
+		t.Errorf("decoding an overflowing dimension returned %v, want ErrCorruptRecord", err)
+	}
+}
+
+func TestDecodeSparseOutOfRange(t *testing.T) {
+	tests := []struct {
+		name string
+		dim  uint64
+		gaps []uint64
+	}{
+		{"dimension beyond a uint32", 1 << 40, []uint64{0}},
+		{"index at the dimension", 4, []uint64{4}},
+		{"gap wrapping the index", 8, []uint64{3, math.MaxUint64 - 1}},
+	}
+	for _, tt := range tests {
+		val := binary.AppendUvarint([]byte{byte(DTypeSparse)}, tt.dim)
+		val = binary.AppendUvarint(val, uint64(len(tt.gaps)))
+		for _, gap := range tt.gaps {
+			val = binary.LittleEndian.AppendUint32(binary.AppendUvarint(val, gap), math.Float32bits(1))
+		}
+
+		if _, err := decodeEmbedding(recordVersion, val); !errors.Is(err, ErrCorruptRecord) {
+			t.Errorf("%s: decoding returned %v, want ErrCorruptRecord", tt.name, err)
+		}
+	}
+}
+
+func TestParseDType(t *testing.T) {
+	for _, d := range []DType{DTypeFloat64, DTypeFloat32, DTypeFloat16, DTypeInt8, DTypeSparse} {
+		got, err := ParseDType(d.String())
+		if err != nil || got != d {
+			t.Errorf("ParseDType(%q) = %v, %v", d.String(), got, err)
+		}
+	}
+	if _, err := ParseDType("float128"); err == nil {
+		t.Error("ParseDType of an unknown name succeeded")
+	}
+}
+
+func TestBinaryCodec(t *testing.T) {
+	doc := documentFromRecord(testRecord())
+	c := BinaryCodec{DType: DTypeFloat32, StoreNorms: true}
+
+	val, err := c.Encode(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Decode(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, doc) {
+		t.Errorf("Decode(Encode(doc)) = %+v, want %+v", got, doc)
+	}
+
+	builtin, err := recordFormat{}.decodeRecord(val)
+	if err != nil || builtin.Text != doc.Text {
+		t.Errorf("the built-in format doesn't read BinaryCodec's records: %+v, %v", builtin, err)
+	}
+}
+
+func TestRecordFormatCodec(t *testing.T) {
+	f := recordFormat{codec: BinaryCodec{}}
+	val, err := f.encode(testRecord())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val[0] != codecVersion {
+		t.Fatalf("a Codec's record starts with %#x, want %#x", val[0], codecVersion)
+	}
+
+	got, err := f.decodeRecord(val)
+	if err != nil || got.Text != "hello world" {
+		t.Errorf("decodeRecord = %+v, %v", got, err)
+	}
+	if _, err := (recordFormat{}).decodeRecord(val); !errors.Is(err, ErrNoCodec) {
+		t.Errorf("decoding a Codec's record without it returned %v, want ErrNoCodec", err)
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	for _, norm := range []bool{false, true} {
+		val := encodeRecord(testRecord(), DTypeFloat32, norm)
+		for n := 0; n < len(val); n++ {
+			if _, err := decodeRecord(val[:n]); !errors.Is(err, ErrCorruptRecord) {
+				t.Errorf("decoding the first %d of %d bytes (norm %v) returned %v, want ErrCorruptRecord", n, len(val), norm, err)
+			}
+		}
+	}
+}
+
+// BenchmarkDecodeVector reports the size of a record holding a 384
+// dimension embedding in each dtype and times reading the embedding back.
+func BenchmarkDecodeVector(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	rec := record{Text: "a short document", Embedding: make([]float64, 384)}
+	for i := range rec.Embedding {
+		rec.Embedding[i] = r.NormFloat64()
+	}
+
+	for _, dtype := range []DType{DTypeFloat64, DTypeFloat32, DTypeFloat16, DTypeInt8, DTypeSparse} {
+		b.Run(dtype.String(), func(b *testing.B) {
+			val := encodeRecord(rec, dtype, false)
+			b.ReportMetric(float64(len(val)), "B/record")
+
+			for i := 0; i < b.N; i++ {
+				if _, err := decodeVector(val); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// reindexState is the progress of a Reindex, kept under metaReindex until
+// it finishes so that an interrupted one can carry on where it stopped.
+type reindexState struct {
+	// model is the name recorded once the reindex finishes.
+	model string
+
+	// dim is the dimension of the new embeddings, or 0 before the first.
+	dim int
+
+	// after is the ID of the last document reindexed.
+	after uint64
+
+	// packed is whether the packed store was enabled when the reindex
+	// started, and so must be rebuilt at the end.
+	packed bool
+}
+
+func (r reindexState) encode() []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(r.model)))
+	buf = append(buf, r.model...)
+	buf = binary.AppendUvarint(buf, uint64(r.dim))
+	buf = binary.BigEndian.AppendUint64(buf, r.after)
+	if r.packed {
+		return append(buf, 1)
+	}
+
+	return append(buf, 0)
+}
+
+func decodeReindexState(val []byte) (reindexState, error) {
+	var r reindexState
+
+	model, rest, ok := readString(val)
+	if !ok {
+		return r, ErrCorruptRecord
+	}
+	r.model = string(model)
+
+	dim, n := binary.Uvarint(rest)
+	if n <= 0 || len(rest) != n+9 {
+		return r, ErrCorruptRecord
+	}
+	r.dim = int(dim)
+	r.after = binary.BigEndian.Uint64(rest[n:])
+	r.packed = rest[n+8] == 1
+
+	return r, nil
+}
+
+// pendingReindex returns the state of an unfinished Reindex, or nil.
+func pendingReindex(txn *badger.Txn, keys keyspace) (*reindexState, error) {
+	val, err := getMeta(txn, keys.meta(metaReindex))
+	if err != nil || val == nil {
+		return nil, err
+	}
+
+	r, err := decodeReindexState(val)
+	return &r, err
+}
+
+const reindexBatchSize = 256
+
+// Reindex re-embeds the text of every document with e and records model
+// as the index's model, for instance after upgrading the embedding model.
+// IDs, text, metadata and parents are kept, and any projection fitted by
+// FitPCA is dropped. Texts are embedded the way inserts embed them, after
+// Options.Preprocessor and with its retries and long text policy.
+//
+// Each document is rewritten in its own transaction along with the
+// progress, so if Reindex is interrupted, calling it again with the same
+// model resumes after the last document it finished. Until then Open
+// accepts either the old embedder or the new one, but searches and writes
+// should wait, since the stored embeddings are a mix of both. The index and
+// the packed store are rebuilt once every document has been reindexed.
+//
+// Afterwards, reopen the store with e and model. The embeddings s cached
+// and the dimension it detected are dropped, as they were the old
+// embedder's.
+func (s *VectorStore) Reindex(ctx context.Context, e Embedder, model string, onProgress func(done, total int)) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	var state reindexState
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		pending, err := pendingReindex(txn, s.keys)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case pending != nil && pending.model == model:
+			state = *pending
+		case pending != nil:
+			state = reindexState{model: model, packed: pending.packed}
+		default:
+			packed, err := s.packedEnabled(txn)
+			if err != nil {
+				return err
+			}
+			state = reindexState{model: model, packed: packed}
+		}
+
+		if err := txn.Delete(s.keys.meta(metaPacked)); err != nil {
+			return err
+		}
+
+		if err := txn.Delete(s.keys.meta(metaPCA)); err != nil {
+			return err
+		}
+
+		return txn.Set(s.keys.meta(metaReindex), state.encode())
+	}); err != nil {
+		return err
+	}
+	s.pca.Store(nil)
+
+	// The indexes can't hold embeddings of two dimensions, so they are
+	// dropped until every document has been reindexed.
+	if err := s.dropPrefix(s.keys.hnswPrefix(), s.keys.ivfPrefix(), s.keys.lshPrefix(), s.keys.packedPrefix()); err != nil {
+		return err
+	}
+
+	total, err := s.Count(ctx)
+	if err != nil {
+		return err
+	}
+	done, err := s.countThrough(ctx, state.after)
+	if err != nil {
+		return err
+	}
+
+	r := s.reindexing(e)
+	for {
+		ids, texts, err := s.textsAfter(state.after, reindexBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		for i, id := range ids {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := s.reindexDocument(ctx, r, &state, id, texts[i]); err != nil {
+				return fmt.Errorf("reindexing document %d: %w", id, err)
+			}
+
+			done++
+			if onProgress != nil {
+				onProgress(done, max(total, done))
+			}
+		}
+	}
+
+	if err := s.BuildIndex(ctx); err != nil {
+		return err
+	}
+	if state.packed {
+		if err := s.RebuildPackedStore(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		if state.dim > 0 {
+			if err := txn.Set(s.keys.meta(metaDim), binary.BigEndian.AppendUint32(nil, uint32(state.dim))); err != nil {
+				return err
+			}
+		}
+
+		if model != "" {
+			if err := txn.Set(s.keys.meta(metaModel), []byte(model)); err != nil {
+				return err
+			}
+		}
+
+		if p, ok := e.(pooler); ok {
+			if err := txn.Set(s.keys.meta(metaPooling), []byte{byte(p.Pooling())}); err != nil {
+				return err
+			}
+		}
+
+		return txn.Delete(s.keys.meta(metaReindex))
+	}); err != nil {
+		return err
+	}
+
+	s.cache.clear()
+	s.queryCache.clear()
+	s.dim.Store(0)
+
+	return nil
+}
+
+// reindexing returns a handle on s that embeds with e, for Reindex. Texts
+// take the same path as they do for inserts, through Options.Preprocessor,
+// the retries and the long text policy, but with a cache and dimension of
+// e's own and no PCA.
+func (s *VectorStore) reindexing(e Embedder) *VectorStore {
+	r := &VectorStore{
+		db:     s.db,
+		e:      e,
+		dim:    new(atomic.Int64),
+		cache:  newEmbeddingCache(s.opts.CacheSize, 0),
+		opts:   s.opts,
+		log:    s.log,
+		keys:   s.keys,
+		root:   s.root,
+		closed: s.closed,
+		gcDone: s.gcDone,
+		life:   s.life,
+	}
+	r.dim.Store(int64(e.Dim()))
+
+	return r
+}
+
+// reindexDocument re-embeds one document, and its named vectors, through r
+// and records it as done.
+func (s *VectorStore) reindexDocument(ctx context.Context, r *VectorStore, state *reindexState, id uint64, text string) error {
+	embedding, err := r.embedForStorage(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	if state.dim != 0 && len(embedding) != state.dim {
+		return fmt.Errorf("%w: reindexed embeddings have %d dimensions, got %d", ErrDimensionMismatch, state.dim, len(embedding))
+	}
+
+	var vectors map[string]record
+	if err := s.db.View(func(txn *badger.Txn) (err error) {
+		vectors, err = s.docVectors(txn, id)
+		return
+	}); err != nil {
+		return err
+	}
+
+	for name, v := range vectors {
+		if v.Embedding, err = r.embedForStorage(ctx, v.Text); err != nil {
+			return fmt.Errorf("vector %q: %w", name, err)
+		}
+		if len(v.Embedding) != len(embedding) {
+			return fmt.Errorf("%w: vector %q has %d dimensions, the document %d", ErrDimensionMismatch, name, len(v.Embedding), len(embedding))
+		}
+		vectors[name] = v
+	}
+
+	next := *state
+	next.dim, next.after = len(embedding), id
+
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		item, err := txn.Get(s.keys.doc(id))
+		if err == badger.ErrKeyNotFound {
+			return txn.Set(s.keys.meta(metaReindex), next.encode())
+		} else if err != nil {
+			return err
+		}
+
+		var rec record
+		if err := item.Value(func(val []byte) error {
+			_, err := s.format().decodeDocument(val, &rec)
+			return err
+		}); err != nil {
+			return err
+		}
+		rec.Embedding = embedding
+
+		if err := s.setRecord(txn, s.keys.doc(id), rec); err != nil {
+			return err
+		}
+		if err := s.vectorsAdd(txn, id, vectors); err != nil {
+			return err
+		}
+
+		return txn.Set(s.keys.meta(metaReindex), next.encode())
+	}); err != nil {
+		return err
+	}
+
+	*state = next
+	return nil
+}
+
+// textsAfter returns up to n documents with IDs above after, in ID order.
+func (s *VectorStore) textsAfter(after uint64, n int) ([]uint64, []string, error) {
+	var ids []uint64
+	var texts []string
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.keys.docPrefix()
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(s.keys.doc(after + 1)); it.Valid() && len(ids) < n; it.Next() {
+			var text string
+			if err := it.Item().Value(func(val []byte) error {
+				var err error
+				text, err = s.format().decodeText(val)
+				return err
+			}); err != nil {
+				return err
+			}
+
+			ids = append(ids, s.keys.docID(it.Item().Key()))
+			texts = append(texts, text)
+		}
+
+		return nil
+	})
+
+	return ids, texts, err
+}
+
+// countThrough counts the documents with IDs up to and including id.
+func (s *VectorStore) countThrough(ctx context.Context, id uint64) (int, error) {
+	n := 0
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: s.keys.docPrefix()})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid() && s.keys.docID(it.Item().Key()) <= id; it.Next() {
+			if n++; n%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return n, err
+}
@@ -0,0 +1,183 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+func TestReindex(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions(t).WithGCInterval(0).WithModelName("old")
+
+	s, err := vectorstore.Open(opts.WithIndex(vectorstore.IndexHNSW), vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := numbered(20, 4)
+	ids := insertAll(t, s, texts...)
+	if _, err := s.InsertWithMetadata(ctx, "with metadata", map[string]string{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var last int
+	if err := s.Reindex(ctx, vectortest.NewHashEmbedder(8), "new", func(done, total int) {
+		last = done
+		if total != len(texts)+1 {
+			t.Errorf("progress total = %d, want %d", total, len(texts)+1)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if last != len(texts)+1 {
+		t.Errorf("last progress was %d done", last)
+	}
+	s.Close()
+
+	if _, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim)); !errors.Is(err, vectorstore.ErrModelMismatch) {
+		t.Errorf("Open with the old model returned %v, want ErrModelMismatch", err)
+	}
+
+	s = openStoreWith(t, opts.WithModelName("new").WithIndex(vectorstore.IndexHNSW), vectortest.NewHashEmbedder(8))
+	doc, err := s.Get(ctx, ids[5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != texts[5] || len(doc.Embedding) != 8 {
+		t.Errorf("reindexed document = %q with %d dimensions", doc.Text, len(doc.Embedding))
+	}
+	meta, err := s.Get(ctx, ids[len(ids)-1]+1)
+	if err != nil || meta.Metadata["k"] != "v" {
+		t.Errorf("reindexing lost the metadata: %+v, %v", meta, err)
+	}
+
+	results, err := s.Nearest(ctx, texts[5], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Score < 0.999 {
+		t.Errorf("Nearest of a reindexed text = %+v", results)
+	}
+}
+
+func TestReindexResume(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(20, 4)...)
+
+	// Interrupt the first Reindex after 5 documents.
+	interrupted, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := s.Reindex(interrupted, vectortest.NewHashEmbedder(8), "new", func(done, total int) {
+		if done == 5 {
+			cancel()
+		}
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted Reindex returned %v, want context.Canceled", err)
+	}
+
+	e := &countingEmbedder{HashEmbedder: vectortest.NewHashEmbedder(8)}
+	first := 0
+	if err := s.Reindex(ctx, e, "new", func(done, total int) {
+		if first == 0 {
+			first = done
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if first != 6 || e.calls.Load() != 15 {
+		t.Errorf("resumed Reindex started at %d and embedded %d texts, want 6 and 15", first, e.calls.Load())
+	}
+}
+
+// reindexEmbedder is a limitedEmbedder that counts the texts it is given
+// and fails the first failures of them.
+type reindexEmbedder struct {
+	limitedEmbedder
+	texts    map[string]int
+	failures int
+}
+
+func (e *reindexEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	e.texts[text]++
+	if e.failures > 0 {
+		e.failures--
+		return nil, errFlaky
+	}
+
+	return e.limitedEmbedder.Embed(ctx, text)
+}
+
+func TestReindexEmbedsLikeInserts(t *testing.T) {
+	ctx := context.Background()
+	opts := memOptions().
+		WithPreprocessor(vectorstore.CollapseWhitespace).
+		WithRetry(vectorstore.RetryOptions{Attempts: 3, Backoff: time.Millisecond}).
+		WithLongText(vectorstore.LongTextAverage)
+
+	s := openStore(t, opts)
+	ids := insertAll(t, s, "  a   spaced  text ", longText)
+
+	e := &reindexEmbedder{
+		limitedEmbedder: limitedEmbedder{vectortest.NewHashEmbedder(8), 5},
+		texts:           make(map[string]int),
+		failures:        2,
+	}
+	if err := s.Reindex(ctx, e, "new", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.texts["a spaced text"] != 3 || e.texts["  a   spaced  text "] != 0 {
+		t.Errorf("Reindex embedded %v, want the preprocessed text, retried twice", e.texts)
+	}
+	if e.texts[longText] != 0 {
+		t.Errorf("Reindex embedded the long text whole, want it averaged in parts: %v", e.texts)
+	}
+
+	for _, id := range ids {
+		doc, err := s.Get(ctx, id)
+		if err != nil || len(doc.Embedding) != 8 {
+			t.Errorf("after Reindex, Get(%d) = %d dimensions, %v", id, len(doc.Embedding), err)
+		}
+	}
+}
+
+// swappableEmbedder embeds with whichever embedder it was last given, like
+// a model upgraded in place.
+type swappableEmbedder struct {
+	vectorstore.Embedder
+}
+
+func TestReindexResetsCaches(t *testing.T) {
+	ctx := context.Background()
+	e := &swappableEmbedder{vectortest.NewHashEmbedder(testDim)}
+	s := openStoreWith(t, memOptions().WithCacheSize(8).WithQueryCacheSize(8), e)
+
+	insertAll(t, s, "red apples", "blue sky")
+	if _, err := s.Nearest(ctx, "red apples", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Embedder = vectortest.NewHashEmbedder(8)
+	if err := s.Reindex(ctx, e, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Nearest(ctx, "red apples", 1)
+	if err != nil {
+		t.Fatalf("Nearest after Reindex: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != "red apples" {
+		t.Errorf("Nearest after Reindex = %+v", results)
+	}
+
+	id := insertAll(t, s, "red apples")[0]
+	doc, err := s.Get(ctx, id)
+	if err != nil || len(doc.Embedding) != 8 {
+		t.Errorf("a text cached before Reindex was stored with %d dimensions, %v", len(doc.Embedding), err)
+	}
+}
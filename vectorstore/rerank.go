@@ -0,0 +1,43 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Reranker rescores the candidates of a search with something more
+// expensive than the index metric, such as a cross-encoder.
+type Reranker interface {
+	// Rerank returns a score for each result, higher is better.
+	Rerank(ctx context.Context, query string, results []Result) ([]float64, error)
+}
+
+// rerank orders results by r's scores, which replace their Score, then by
+// ID, and keeps the best k.
+func rerank(ctx context.Context, r Reranker, query string, results []Result, k int) ([]Result, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	scores, err := r.Rerank(ctx, query, results)
+	if err != nil {
+		return nil, fmt.Errorf("reranking: %w", err)
+	}
+	if len(scores) != len(results) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d results", len(scores), len(results))
+	}
+
+	for i := range results {
+		results[i].Score, results[i].Relevance = scores[i], logistic(scores[i])
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].ID < results[j].ID
+	})
+
+	return results[:min(k, len(results))], nil
+}
@@ -0,0 +1,63 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryOptions controls how a text that fails to encode is retried, so
+// that a transient failure of the model doesn't abort a large insert.
+type RetryOptions struct {
+	// Attempts is the most times a text is encoded before the last error
+	// is returned. Values below 2 disable retries.
+	Attempts int
+
+	// Backoff is the wait before the first retry. It doubles before each
+	// one after, up to MaxBackoff if that is above zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		Attempts:   1,
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: 5 * time.Second,
+	}
+}
+
+// embedWithRetry calls the embedder until it succeeds, Options.Retry.Attempts
+// is used up or ctx is done. The last error is wrapped in ErrEncodeFailed.
+func (s *VectorStore) embedWithRetry(ctx context.Context, text string) ([]float64, error) {
+	attempts := max(s.opts.Retry.Attempts, 1)
+	wait := s.opts.Retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		vec, err := s.e.Embed(ctx, text)
+		if err == nil {
+			return vec, nil
+		}
+
+		if attempt == attempts || ctx.Err() != nil || err == errNoModel {
+			if attempt > 1 {
+				return nil, fmt.Errorf("%w after %d attempts: %w", ErrEncodeFailed, attempt, err)
+			}
+
+			return nil, fmt.Errorf("%w: %w", ErrEncodeFailed, err)
+		}
+
+		s.log.Warn("Encoding failed, retrying", "attempt", attempt, "wait", wait, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrEncodeFailed, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		wait *= 2
+		if s.opts.Retry.MaxBackoff > 0 {
+			wait = min(wait, s.opts.Retry.MaxBackoff)
+		}
+	}
+}
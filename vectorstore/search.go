@@ -0,0 +1,673 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// SearchParams controls a Search.
+type SearchParams struct {
+	// K is the number of results to return.
+	K int
+
+	// Metric ranks the results. Nil uses the store's configured metric.
+	// It applies to this search alone, so searches with different metrics
+	// may run at once. An HNSW, IVF or LSH index is still walked as built,
+	// under the store's metric, with the candidates it finds ranked by
+	// Metric.
+	Metric DistanceMetric
+
+	// Vector, if set, scores each document by its vector of that name,
+	// stored by InsertWithVectors or SetVector, in place of its embedding.
+	// Documents without one are left out.
+	Vector string
+
+	// MultiVector, if set, scores each document by combining the scores
+	// of all of its named vectors, late interaction style. Documents
+	// without named vectors are left out. It can't be combined with
+	// Vector.
+	//
+	// Named vectors aren't indexed, so searches of them scan every one.
+	MultiVector MultiVector
+
+	// Filter, if set, is called with the metadata of each candidate and
+	// only documents it returns true for are scored. With an HNSW index a
+	// filtered search scans every document instead of walking the graph, so
+	// that k matches are found however selective the filter is.
+	Filter func(metadata map[string]string) bool
+
+	// MinScore, if set, drops results scoring worse than it, so that up to
+	// K results at least that close are returned. For metrics where lower
+	// is better, such as EuclideanDistance, it is a maximum distance. With
+	// a Reranker it applies to the Reranker's scores.
+	MinScore *float64
+
+	// Reranker, if set, rescores the best RerankDepth candidates and the
+	// K it scores highest are returned, best first, with its scores.
+	Reranker Reranker
+
+	// RerankDepth is how many candidates are fetched for the Reranker. It
+	// is raised to K if lower, and like K may not exceed Options.MaxK.
+	RerankDepth int
+
+	// Exactness trades speed for recall under whichever index is in use,
+	// from the fastest just above 0 to exact at 1. Zero leaves the index's
+	// own tunables in charge. It sets:
+	//
+	//   - flat: the fraction of documents scored, sampled by ID
+	//   - IVF: the fraction of clusters probed, ceil(Exactness * NList)
+	//   - HNSW: the candidate list size, K + Exactness * 8 * EfSearch, with
+	//     1 scanning every document instead
+	//   - LSH: nothing below 1, which scans every document instead
+	Exactness float64
+
+	// SampleFraction, if set, makes scans score only about that fraction
+	// of the documents, for fast approximate results over a large index.
+	// Which documents are skipped is decided by hashing their IDs with
+	// SampleSeed, so the same fraction and seed always score the same
+	// documents. It overrides Exactness under a flat index and also
+	// applies to the clusters an IVF search probes.
+	SampleFraction float64
+	SampleSeed     uint64
+
+	// KeywordWeight, if above 0, makes the search hybrid: the vector
+	// ranking is fused with a BM25 ranking of the documents sharing words
+	// with the query, by reciprocal rank fusion, so that exact keyword
+	// matches aren't missed. It is the weight of the keyword ranks, from 0
+	// to 1, with the vector ranks weighted by the rest, and results are
+	// scored by the fused ranks, higher being better. It needs
+	// Options.Keywords.
+	KeywordWeight float64
+
+	// PrefetchValues makes scans that score every document read the
+	// records ahead of scoring them, PrefetchSize at a time, or Badger's
+	// default of 100 if it is 0. That holds more of them in memory but can
+	// speed up scans of large stores, particularly on disk. By default
+	// scans read only the keys ahead and each record as it is scored.
+	PrefetchValues bool
+	PrefetchSize   int
+
+	// Explain sets each result's Explanation.
+	Explain bool
+
+	// Fields selects which of a Result's text, metadata and embedding are
+	// returned. Zero returns the text alone, so that results don't carry
+	// embeddings that callers don't need. The Reranker sees every field
+	// whatever it is.
+	Fields ResultFields
+}
+
+// ResultFields is a set of the optional fields of a Result. ID, Score and
+// the rest are always set.
+type ResultFields uint8
+
+const (
+	FieldText ResultFields = 1 << iota
+	FieldMetadata
+	FieldEmbedding
+)
+
+// project clears the fields of r that aren't in f, where zero means
+// FieldText.
+func (f ResultFields) project(r *Result) {
+	if f == 0 {
+		f = FieldText
+	}
+
+	if f&FieldText == 0 {
+		r.Text = ""
+	}
+	if f&FieldMetadata == 0 {
+		r.Metadata = nil
+	}
+	if f&FieldEmbedding == 0 {
+		r.Embedding = nil
+	}
+}
+
+// hnswExactnessScale is how many times HNSWOptions.EfSearch the candidate
+// list grows to as Exactness approaches 1.
+const hnswExactnessScale = 8
+
+// query is a search in progress against the index.
+type query struct {
+	metric DistanceMetric
+	target []float64
+	k      int
+	filter func(map[string]string) bool
+
+	// exactness is SearchParams.Exactness and sample the fraction of
+	// documents a scan scores, where 0 means all of them.
+	exactness float64
+	sample    float64
+	seed      uint64
+
+	// approximate is set by the search if it may have missed documents.
+	approximate bool
+
+	// after, if set, is the last result of the previous page, and scans
+	// only keep documents ranking behind it.
+	after *candidate
+
+	quantized  []int8
+	queryScale float32
+
+	// targetSq is the dot product of target with itself, for scoring
+	// sparse records.
+	targetSq float64
+
+	// vector and multi are SearchParams.Vector and
+	// SearchParams.MultiVector.
+	vector string
+	multi  MultiVector
+
+	// prefetch is how many records scans read ahead, or 0 for none.
+	prefetch int
+
+	// format is how the records scanned were written.
+	format recordFormat
+
+	// relevance is the metric the search asked for, which metric may
+	// stand in for, and the one Result.Relevance is computed under.
+	relevance DistanceMetric
+}
+
+func newQuery(metric DistanceMetric, target []float64, k int) *query {
+	q := &query{metric: metric, target: target, k: k}
+	q.quantized, q.queryScale = Quantize(target)
+	q.targetSq = dot(target, target)
+
+	return q
+}
+
+// iteratorOptions returns the options for a scan of the keys under prefix,
+// prefetching records if the search asked for it.
+func (q *query) iteratorOptions(prefix []byte) badger.IteratorOptions {
+	opts := badger.IteratorOptions{Prefix: prefix}
+	if q.prefetch > 0 {
+		opts.PrefetchValues, opts.PrefetchSize = true, q.prefetch
+	}
+
+	return opts
+}
+
+// decode reads the embedding of a stored record. It reports false if the
+// record doesn't pass the filter, without decoding the embedding.
+func (q *query) decode(val []byte) (storedVector, bool, error) {
+	if rec, ok, err := q.format.decodeCodec(val); ok {
+		if err != nil || q.filter != nil && !q.filter(rec.Metadata) {
+			return storedVector{}, false, err
+		}
+
+		return storedVector{dense: rec.Embedding}, true, nil
+	}
+
+	_, rest, err := decodeText(val)
+	if err != nil {
+		return storedVector{}, false, err
+	}
+
+	var rec *record
+	if q.filter != nil {
+		rec = &record{}
+	}
+
+	if rest, err = decodeFields(val[0], rest, rec); err != nil {
+		return storedVector{}, false, err
+	}
+
+	if q.filter != nil && !q.filter(rec.Metadata) {
+		return storedVector{}, false, nil
+	}
+
+	vec, err := decodeEmbedding(val[0], rest)
+	return vec, err == nil, err
+}
+
+// skip reports whether a scan should pass over the document id without
+// scoring it. The choice depends only on the ID and seed, so a given sample
+// fraction always scores the same documents.
+func (q *query) skip(id uint64) bool {
+	if q.sample <= 0 || q.sample >= 1 {
+		return false
+	}
+
+	q.approximate = true
+	return float64(mix64(id^q.seed)) >= q.sample*math.MaxUint64
+}
+
+// mix64 is the splitmix64 finalizer, which spreads sequential IDs evenly
+// over the uint64 range.
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// score scores vec against the target, using the quantized form of the
+// target when vec is stored as int8, only vec's non-zero elements when it
+// is sparse and its stored norm when it has one, if the metric allows it.
+func (q *query) score(vec storedVector) float64 {
+	if vec.sparse != nil {
+		if score, ok := scoreSparse(q.metric, q.target, q.targetSq, vec.sparse); ok {
+			return score
+		}
+
+		return q.metric.Score(q.target, vec.float64s())
+	}
+
+	if vec.q == nil {
+		if vec.norm > 0 {
+			if score, ok := scoreNormed(q.metric, q.target, q.targetSq, vec); ok {
+				return score
+			}
+		}
+
+		return q.metric.Score(q.target, vec.dense)
+	}
+
+	if score, ok := scoreQuantized(q.metric, q.quantized, q.queryScale, vec); ok {
+		return score
+	}
+
+	return q.metric.Score(q.target, vec.float64s())
+}
+
+// Nearest returns the k stored texts closest to query, best first. If the
+// index holds fewer than k entries, all of them are returned. Results carry
+// no metadata or embedding; use Search with SearchParams.Fields for them.
+func (s *VectorStore) Nearest(ctx context.Context, query string, k int) ([]Result, error) {
+	return s.Search(ctx, query, SearchParams{K: k})
+}
+
+// NearestWithMetric is Nearest ranked by metric instead of the store's
+// configured one.
+func (s *VectorStore) NearestWithMetric(ctx context.Context, query string, k int, metric DistanceMetric) ([]Result, error) {
+	return s.Search(ctx, query, SearchParams{K: k, Metric: metric})
+}
+
+// Search returns the p.K stored texts closest to text, best first.
+func (s *VectorStore) Search(ctx context.Context, text string, p SearchParams) ([]Result, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	defer s.observeSearch(time.Now())
+
+	q, err := s.newSearch(ctx, text, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.search(ctx, text, q, p)
+}
+
+// NearestByVector returns the k stored texts closest to vec, best first,
+// without calling the embedder. vec must have the dimension of the stored
+// embeddings.
+func (s *VectorStore) NearestByVector(ctx context.Context, vec []float64, k int) ([]Result, error) {
+	return s.SearchByVector(ctx, vec, SearchParams{K: k})
+}
+
+// SearchByVector is Search with an embedding in place of the query text,
+// for instance one computed by another system. After FitPCA, vec may have
+// either the embedder's dimension, and is then projected, or the reduced
+// one. A Reranker is passed an empty query.
+func (s *VectorStore) SearchByVector(ctx context.Context, vec []float64, p SearchParams) ([]Result, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	defer s.observeSearch(time.Now())
+
+	if err := s.checkSearchParams(p); err != nil {
+		return nil, err
+	}
+	if len(vec) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	if pca := s.pca.Load(); pca != nil && len(vec) == pca.in() {
+		var err error
+		if vec, err = pca.project(vec); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.View(func(txn *badger.Txn) error {
+		dim, err := storedDim(txn, s.keys)
+		if err != nil {
+			return err
+		}
+		if dim != 0 && dim != len(vec) {
+			return fmt.Errorf("%w: stored embeddings have dimension %d, query has %d", ErrDimensionMismatch, dim, len(vec))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.search(ctx, "", s.newVectorSearch(append([]float64(nil), vec...), p), p)
+}
+
+// NearestByID returns the k stored texts closest to the document id, best
+// first, leaving out the document itself. Its stored embedding is the
+// query, so nothing is embedded.
+func (s *VectorStore) NearestByID(ctx context.Context, id uint64, k int) ([]Result, error) {
+	return s.SearchByID(ctx, id, SearchParams{K: k})
+}
+
+// SearchByID is Search with the stored embedding of the document id, or its
+// named vector p.Vector, in place of the query text. The document itself is
+// left out of the results. It returns ErrNotFound if the document, or the
+// named vector, doesn't exist. A Reranker is passed an empty query.
+func (s *VectorStore) SearchByID(ctx context.Context, id uint64, p SearchParams) ([]Result, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	defer s.observeSearch(time.Now())
+
+	if err := s.checkSearchParams(p); err != nil {
+		return nil, err
+	}
+
+	key := s.keys.doc(id)
+	if p.Vector != "" {
+		key = s.keys.docVector(id, p.Vector)
+	}
+
+	var vec storedVector
+	if err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) (err error) {
+			vec, err = s.format().decodeVector(val)
+			return
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	// One more than asked for, in case the document itself is among them.
+	wide := p
+	wide.K++
+	results, err := s.search(ctx, "", s.newVectorSearch(vec.float64s(), wide), wide)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := results[:0]
+	for _, r := range results {
+		if r.ID != id {
+			kept = append(kept, r)
+		}
+	}
+
+	return kept[:min(p.K, len(kept))], nil
+}
+
+// search ranks the stored documents against q, which was built from p.
+// text is the query passed to the Reranker.
+func (s *VectorStore) search(ctx context.Context, text string, q *query, p SearchParams) ([]Result, error) {
+	if p.KeywordWeight > 0 && !s.opts.Keywords {
+		return nil, errors.New("hybrid search needs Options.Keywords")
+	}
+
+	if p.Reranker != nil {
+		q.k = max(p.RerankDepth, p.K)
+	}
+	k := q.k
+	if p.KeywordWeight > 0 {
+		q.k *= hybridDepth
+		if s.opts.MaxK > 0 {
+			q.k = min(q.k, max(s.opts.MaxK, k))
+		}
+	}
+
+	var results []Result
+	if err := s.db.View(func(txn *badger.Txn) error {
+		ranked, err := s.indexSearch(ctx, txn, q)
+		if err != nil {
+			return err
+		}
+
+		if p.KeywordWeight > 0 {
+			matched, err := s.keywordSearch(ctx, txn, text, q.filter, q.k)
+			if err != nil {
+				return err
+			}
+
+			ranked = fuseRankings(ranked, matched, p.KeywordWeight, k)
+		}
+
+		results, err = s.fetchResults(txn, q, ranked)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Approximate = q.approximate
+		if p.KeywordWeight > 0 {
+			results[i].Relevance = fusedRelevance(results[i].Score)
+		}
+	}
+	if p.Explain {
+		q.explain(results)
+	}
+
+	higherIsBetter := q.metric.HigherIsBetter() || p.KeywordWeight > 0
+	if p.Reranker != nil {
+		var err error
+		if results, err = rerank(ctx, p.Reranker, text, results, p.K); err != nil {
+			return nil, err
+		}
+		higherIsBetter = true
+	}
+
+	if p.MinScore != nil {
+		results = aboveThreshold(results, *p.MinScore, higherIsBetter)
+	}
+
+	for i := range results {
+		p.Fields.project(&results[i])
+	}
+
+	return results, nil
+}
+
+// aboveThreshold truncates results, best first, before the first one
+// scoring worse than threshold.
+func aboveThreshold(results []Result, threshold float64, higherIsBetter bool) []Result {
+	for i, r := range results {
+		if higherIsBetter && r.Score < threshold || !higherIsBetter && r.Score > threshold {
+			return results[:i]
+		}
+	}
+
+	return results
+}
+
+// fetchResults reads the records of the ranked candidates. It runs in the
+// same transaction as the ranking, so none of the winners can have been
+// deleted in between.
+func (s *VectorStore) fetchResults(txn *badger.Txn, q *query, ranked []candidate) ([]Result, error) {
+	results := make([]Result, len(ranked))
+	for i, c := range ranked {
+		item, err := txn.Get(s.keys.doc(c.id))
+		if err != nil {
+			return nil, err
+		}
+
+		var rec record
+		var vec storedVector
+		if err := item.Value(func(val []byte) (err error) {
+			vec, err = s.format().decodeDocument(val, &rec)
+			return
+		}); err != nil {
+			return nil, err
+		}
+
+		// Candidates found only by keyword carry no vector.
+		if c.vec.dim() != 0 {
+			vec = c.vec
+		}
+
+		results[i] = Result{ID: c.id, Text: rec.Text, Metadata: rec.Metadata, Score: c.score, Relevance: relevance(q.relevance, c.score), Embedding: vec.float64s(), Parent: rec.Parent, CreatedAt: rec.CreatedAt}
+	}
+
+	return results, nil
+}
+
+func (s *VectorStore) observeSearch(start time.Time) {
+	s.opts.Metrics.Searched(time.Since(start))
+}
+
+// newSearch validates p and embeds text. See newVectorSearch.
+func (s *VectorStore) newSearch(ctx context.Context, text string, p SearchParams) (*query, error) {
+	if err := s.checkSearchParams(p); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	target, err := s.embedQuery(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newVectorSearch(target, p), nil
+}
+
+func (s *VectorStore) checkSearchParams(p SearchParams) error {
+	if p.K < 1 {
+		return fmt.Errorf("%w: k must be at least 1, got %d", ErrInvalidParams, p.K)
+	}
+	if s.opts.MaxK > 0 && p.K > s.opts.MaxK {
+		return fmt.Errorf("%w: %w: k is %d, the limit is %d", ErrInvalidParams, ErrKTooLarge, p.K, s.opts.MaxK)
+	}
+	if s.opts.MaxK > 0 && p.Reranker != nil && p.RerankDepth > s.opts.MaxK {
+		return fmt.Errorf("%w: %w: rerank depth is %d, the limit is %d", ErrInvalidParams, ErrKTooLarge, p.RerankDepth, s.opts.MaxK)
+	}
+	if p.Exactness < 0 || p.Exactness > 1 {
+		return fmt.Errorf("%w: exactness must be between 0 and 1, got %v", ErrInvalidParams, p.Exactness)
+	}
+	if p.SampleFraction < 0 || p.SampleFraction > 1 {
+		return fmt.Errorf("%w: sample fraction must be between 0 and 1, got %v", ErrInvalidParams, p.SampleFraction)
+	}
+	if p.KeywordWeight < 0 || p.KeywordWeight > 1 {
+		return fmt.Errorf("%w: keyword weight must be between 0 and 1, got %v", ErrInvalidParams, p.KeywordWeight)
+	}
+	if p.PrefetchSize < 0 {
+		return fmt.Errorf("%w: prefetch size must not be negative, got %d", ErrInvalidParams, p.PrefetchSize)
+	}
+	if p.Vector != "" && p.MultiVector != MultiVectorNone {
+		return fmt.Errorf("%w: a search can't set both Vector and MultiVector", ErrInvalidParams)
+	}
+
+	return nil
+}
+
+// newVectorSearch builds the query for target under the validated p,
+// ranking by a metric adjusted for how the stored vectors were written. It
+// may normalize target in place.
+func (s *VectorStore) newVectorSearch(target []float64, p SearchParams) *query {
+	metric := p.Metric
+	if metric == nil {
+		metric = s.opts.Metric
+	}
+
+	if _, ok := metric.(CosineSimilarity); ok && s.opts.Normalize {
+		normalize(target)
+	}
+
+	q := newQuery(s.effectiveMetric(metric), target, p.K)
+	q.relevance = metric
+	q.format = s.format()
+	q.filter = p.Filter
+	q.exactness = p.Exactness
+	q.seed = p.SampleSeed
+	q.vector, q.multi = p.Vector, p.MultiVector
+	if p.PrefetchValues {
+		q.prefetch = p.PrefetchSize
+		if q.prefetch == 0 {
+			q.prefetch = badger.DefaultIteratorOptions.PrefetchSize
+		}
+	}
+	switch {
+	case p.SampleFraction > 0:
+		q.sample = p.SampleFraction
+	case s.opts.Index == IndexFlat:
+		q.sample = p.Exactness
+	}
+
+	return q
+}
+
+// scanFlat scores every stored document against the query and returns the
+// best k.
+func (s *VectorStore) scanFlat(ctx context.Context, txn *badger.Txn, q *query) ([]candidate, error) {
+	best := newTopK(q.metric, q.k)
+
+	it := txn.NewIterator(q.iteratorOptions(s.keys.docPrefix()))
+	defer it.Close()
+
+	n := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		if n++; n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		item := it.Item()
+		id := s.keys.docID(item.Key())
+		if q.skip(id) {
+			continue
+		}
+
+		var vec storedVector
+		var ok bool
+		if err := item.Value(func(val []byte) (err error) {
+			vec, ok, err = q.decode(val)
+			return
+		}); err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		if vec.dim() != len(q.target) {
+			return nil, fmt.Errorf("%w: stored embedding has dimension %d, query has %d", ErrDimensionMismatch, vec.dim(), len(q.target))
+		}
+
+		c := candidate{id: id, score: q.score(vec), vec: vec}
+		if q.after != nil && !ranksAhead(q.metric, *q.after, c) {
+			continue
+		}
+
+		best.push(c)
+	}
+
+	return best.sorted(), nil
+}
@@ -0,0 +1,697 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+// overlap returns the fraction of want's IDs that are among got's.
+func overlap(want, got []vectorstore.Result) float64 {
+	if len(want) == 0 {
+		return 1
+	}
+
+	found := make(map[uint64]bool, len(got))
+	for _, r := range got {
+		found[r.ID] = true
+	}
+
+	hits := 0
+	for _, r := range want {
+		if found[r.ID] {
+			hits++
+		}
+	}
+
+	return float64(hits) / float64(len(want))
+}
+
+// TestInt8Recall compares the top 10 of an int8 store with a float64 one
+// over the same documents.
+func TestInt8Recall(t *testing.T) {
+	ctx := context.Background()
+	texts := benchTexts(300)
+
+	full := openStore(t, memOptions())
+	quantized := openStore(t, memOptions().WithDType(vectorstore.DTypeInt8))
+	for _, s := range []*vectorstore.VectorStore{full, quantized} {
+		if _, err := s.InsertBatch(ctx, texts, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	total := 0.0
+	queries := texts[:20]
+	for _, q := range queries {
+		want, err := full.Nearest(ctx, q, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := quantized.Nearest(ctx, q, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The query's own document is an exact match either way.
+		if got[0].Text != q {
+			t.Errorf("the int8 store ranks %q first for its own text", got[0].Text)
+		}
+		total += overlap(want, got)
+	}
+
+	// Quantizing to 1/127th of the largest component reorders documents
+	// whose scores are within rounding of each other, which costs about 5%
+	// of the top 10, all at its border. More than 10% lost would mean the
+	// scores themselves are off.
+	if r := total / float64(len(queries)); r < 0.9 {
+		t.Errorf("int8 recall of the float64 top 10 is %v, want at least 0.9", r)
+	}
+}
+
+func TestSearchFilter(t *testing.T) {
+	ctx := context.Background()
+	ivf := vectorstore.IVFOptions{NList: 8, NProbe: 2, Iterations: 5}
+	for _, index := range []vectorstore.IndexType{vectorstore.IndexFlat, vectorstore.IndexHNSW, vectorstore.IndexIVF} {
+		t.Run(index.String(), func(t *testing.T) {
+			s := openStore(t, memOptions().WithIndex(index).WithIVF(ivf))
+
+			// The filter keeps 4 of 200 documents, fewer than K.
+			for i, text := range numbered(200, 10) {
+				rare := "no"
+				if i%50 == 0 {
+					rare = "yes"
+				}
+				if _, err := s.InsertWithMetadata(ctx, text, map[string]string{"rare": rare}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if index == vectorstore.IndexIVF {
+				if err := s.BuildIndex(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			results, err := s.Search(ctx, "topic3 things", vectorstore.SearchParams{
+				K:      10,
+				Filter: func(md map[string]string) bool { return md["rare"] == "yes" },
+				Fields: vectorstore.FieldMetadata,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// IVF only scans the lists it probes, so it may find fewer.
+			if index != vectorstore.IndexIVF && len(results) != 4 {
+				t.Errorf("filtered search returned %d results, want the 4 that match", len(results))
+			}
+			for _, r := range results {
+				if r.Metadata["rare"] != "yes" {
+					t.Errorf("result %+v doesn't match the filter", r)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchMinScore(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, "red apples", "red apples pears", "red sky blue", "green grass")
+
+	for _, m := range []vectorstore.DistanceMetric{vectorstore.CosineSimilarity{}, vectorstore.EuclideanDistance{}} {
+		all, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 4, Metric: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if all[1].Score == all[2].Score {
+			t.Fatalf("%T: the second and third results tie at %v", m, all[1].Score)
+		}
+
+		// A threshold between the second and third scores keeps the top two.
+		min := (all[1].Score + all[2].Score) / 2
+		results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 4, Metric: m, MinScore: &min})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resultIDs(results), resultIDs(all[:2]); !reflect.DeepEqual(got, want) {
+			t.Errorf("%T: MinScore %v kept %v, want the top two %v", m, min, got, want)
+		}
+	}
+}
+
+func TestSearchByVector(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithNormalize(false))
+	ids := insertAll(t, s, "red apples", "blue sky")
+
+	doc, err := s.Get(ctx, ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.NearestByVector(ctx, doc.Embedding, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != ids[1] {
+		t.Errorf("NearestByVector = %v, want %d", resultIDs(results), ids[1])
+	}
+
+	if _, err := s.NearestByVector(ctx, make([]float64, testDim+1), 1); !errors.Is(err, vectorstore.ErrDimensionMismatch) {
+		t.Errorf("query of the wrong dimension returned %v, want ErrDimensionMismatch", err)
+	}
+	if _, err := s.NearestByVector(ctx, nil, 1); !errors.Is(err, vectorstore.ErrEmptyQuery) {
+		t.Errorf("empty query vector returned %v, want ErrEmptyQuery", err)
+	}
+}
+
+func TestSearchByID(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	ids := insertAll(t, s, "red apples", "blue sky", "red apples and pears", "red apples with pears and plums")
+
+	results, err := s.NearestByID(ctx, ids[0], 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resultIDs(results), []uint64{ids[2], ids[3], ids[1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NearestByID = %v, want %v", got, want)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("NearestByID scores %v aren't best first", results)
+			break
+		}
+	}
+
+	// Asking for every other document doesn't bring the source back.
+	results, err = s.NearestByID(ctx, ids[0], 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Errorf("NearestByID with k 10 returned %v, want the other 3", resultIDs(results))
+	}
+
+	if _, err := s.NearestByID(ctx, 99, 2); !errors.Is(err, vectorstore.ErrNotFound) {
+		t.Errorf("NearestByID of a missing document returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestSearchMaxK(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithMaxK(5))
+	insertAll(t, s, "red apples")
+
+	if _, err := s.Nearest(ctx, "red", 6); !errors.Is(err, vectorstore.ErrKTooLarge) {
+		t.Errorf("k above MaxK returned %v, want ErrKTooLarge", err)
+	}
+	if _, err := s.Nearest(ctx, "red", 5); err != nil {
+		t.Errorf("k at MaxK: %v", err)
+	}
+	if _, err := openStore(t, memOptions().WithMaxK(0)).Nearest(ctx, "red", 100000); err != nil {
+		t.Errorf("k with no MaxK: %v", err)
+	}
+
+	p := vectorstore.SearchParams{K: 2, Reranker: vectorstore.MMRReranker{Lambda: 0.5}, RerankDepth: 6}
+	if _, err := s.Search(ctx, "red", p); !errors.Is(err, vectorstore.ErrKTooLarge) {
+		t.Errorf("rerank depth above MaxK returned %v, want ErrKTooLarge", err)
+	}
+	p.RerankDepth = 5
+	if _, err := s.Search(ctx, "red", p); err != nil {
+		t.Errorf("rerank depth at MaxK: %v", err)
+	}
+
+	hybrid := openStore(t, memOptions().WithMaxK(5).WithKeywords(true))
+	insertAll(t, hybrid, "red apples", "red cherries", "blue sky")
+	if results, err := hybrid.Search(ctx, "red", vectorstore.SearchParams{K: 5, KeywordWeight: 0.5}); err != nil || len(results) != 3 {
+		t.Errorf("hybrid search at MaxK = %+v, %v", results, err)
+	}
+}
+
+func TestSearchInvalidParams(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithMaxK(5))
+	insertAll(t, s, "red apples")
+
+	for _, p := range []vectorstore.SearchParams{
+		{K: 0},
+		{K: 6},
+		{K: 1, Exactness: 1.5},
+		{K: 1, SampleFraction: -1},
+		{K: 1, KeywordWeight: 2},
+		{K: 1, PrefetchSize: -1},
+		{K: 1, Vector: "title", MultiVector: vectorstore.MultiVectorMax},
+	} {
+		if _, err := s.Search(ctx, "red", p); !errors.Is(err, vectorstore.ErrInvalidParams) {
+			t.Errorf("Search with %+v returned %v, want ErrInvalidParams", p, err)
+		}
+	}
+}
+
+func TestSearchDuringDeletes(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	texts := numbered(200, 5)
+	ids, err := s.InsertBatch(ctx, texts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	textOf := make(map[uint64]string, len(ids))
+	for i, id := range ids {
+		textOf[id] = texts[i]
+	}
+
+	// Ranking and fetching share a transaction, so deleting documents
+	// meanwhile never loses a winner between the two.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, id := range ids[:150] {
+			if err := s.Delete(ctx, id); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		results, err := s.Nearest(ctx, "topic1 things", 10)
+		if err != nil {
+			t.Fatalf("Nearest during deletes: %v", err)
+		}
+		if len(results) != 10 {
+			t.Fatalf("Nearest during deletes returned %d results, want 10", len(results))
+		}
+		for _, r := range results {
+			if r.Text != textOf[r.ID] {
+				t.Fatalf("result %d has text %q, want %q", r.ID, r.Text, textOf[r.ID])
+			}
+		}
+	}
+}
+
+func TestSearchSample(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(200, 4)...)
+
+	p := vectorstore.SearchParams{K: 200, SampleFraction: 0.25, SampleSeed: 1}
+	a, err := s.Search(ctx, "topic1 things", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) == 0 || len(a) > 100 {
+		t.Errorf("a quarter sample returned %d of 200 documents", len(a))
+	}
+	for _, r := range a {
+		if !r.Approximate {
+			t.Errorf("sampled result %d isn't marked approximate", r.ID)
+			break
+		}
+	}
+
+	b, err := s.Search(ctx, "topic1 things", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resultIDs(a), resultIDs(b)) {
+		t.Error("the same sample seed scored different documents")
+	}
+
+	// Sampling everything is the exact scan.
+	exact, err := s.Nearest(ctx, "topic1 things", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := s.Search(ctx, "topic1 things", vectorstore.SearchParams{K: 10, SampleFraction: 1, SampleSeed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resultIDs(all), resultIDs(exact)) {
+		t.Errorf("a sample fraction of 1 returned %v, want the exact %v", resultIDs(all), resultIDs(exact))
+	}
+	for _, r := range all {
+		if r.Approximate {
+			t.Errorf("result %d of a full sample is marked approximate", r.ID)
+			break
+		}
+	}
+}
+
+// constReranker scores results by their position from the end.
+type constReranker struct{}
+
+func (constReranker) Rerank(ctx context.Context, query string, results []vectorstore.Result) ([]float64, error) {
+	scores := make([]float64, len(results))
+	for i := range scores {
+		scores[i] = float64(i)
+	}
+
+	return scores, nil
+}
+
+func TestSearchReranker(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, "red apples", "red apples and pears", "red sky")
+
+	plain, err := s.Nearest(ctx, "red apples", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reranked, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 2, Reranker: constReranker{}, RerankDepth: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(reranked); !reflect.DeepEqual(got, []uint64{plain[2].ID, plain[1].ID}) {
+		t.Errorf("reranked = %v, want the vector ranking reversed, %v", got, resultIDs(plain))
+	}
+	if reranked[0].Score != 2 {
+		t.Errorf("reranked score = %v, want the reranker's 2", reranked[0].Score)
+	}
+}
+
+func TestSearchDTypes(t *testing.T) {
+	ctx := context.Background()
+	texts := numbered(30, 5)
+
+	var want []vectorstore.Result
+	for _, dtype := range []vectorstore.DType{vectorstore.DTypeFloat64, vectorstore.DTypeFloat32, vectorstore.DTypeFloat16, vectorstore.DTypeInt8, vectorstore.DTypeSparse} {
+		s := openStore(t, memOptions().WithDType(dtype))
+		insertAll(t, s, texts...)
+
+		results, err := s.Nearest(ctx, "document 7 about topic2 things", 5)
+		if err != nil {
+			t.Fatalf("%v: %v", dtype, err)
+		}
+		if want == nil {
+			want = results
+			continue
+		}
+
+		// Ties may be broken differently, so compare the top K by score.
+		if len(results) != len(want) || results[0].ID != want[0].ID {
+			t.Errorf("%v: results are %v, float64's are %v", dtype, resultIDs(results), resultIDs(want))
+			continue
+		}
+		for i := range want {
+			if math.Abs(results[i].Score-want[i].Score) > 0.01 {
+				t.Errorf("%v: score %d is %v, float64's is %v", dtype, i, results[i].Score, want[i].Score)
+			}
+		}
+	}
+}
+
+// sameReranker scores every result the same, so it leaves only ties.
+type sameReranker struct{}
+
+func (sameReranker) Rerank(ctx context.Context, query string, results []vectorstore.Result) ([]float64, error) {
+	return make([]float64, len(results)), nil
+}
+
+func TestSearchTies(t *testing.T) {
+	ctx := context.Background()
+	texts := []string{"blue sky", "red apples", "green grass", "red apples", "gold coins", "red apples"}
+
+	for _, index := range []vectorstore.IndexType{vectorstore.IndexFlat, vectorstore.IndexHNSW} {
+		s := openStore(t, memOptions().WithIndex(index))
+		ids := insertAll(t, s, texts...)
+		dups := []uint64{ids[1], ids[3], ids[5]}
+
+		for _, p := range []vectorstore.SearchParams{{K: 3}, {K: 3, Reranker: sameReranker{}, RerankDepth: 6}} {
+			var first []uint64
+			for run := 0; run < 10; run++ {
+				results, err := s.Search(ctx, "red apples", p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := resultIDs(results)
+				if first == nil {
+					first = got
+				} else if !reflect.DeepEqual(got, first) {
+					t.Fatalf("%v: run %d returned %v, the first returned %v", index, run, got, first)
+				}
+			}
+			if p.Reranker == nil && !reflect.DeepEqual(first, dups) {
+				t.Errorf("%v: tied results = %v, want them by ID %v", index, first, dups)
+			}
+			if p.Reranker != nil && !reflect.DeepEqual(first, ids[:3]) {
+				t.Errorf("%v: results reranked to ties = %v, want them by ID %v", index, first, ids[:3])
+			}
+		}
+	}
+}
+
+func TestSearchExplain(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, "red apples", "red apples pears", "blue sky", "green grass")
+
+	for _, m := range []vectorstore.DistanceMetric{vectorstore.CosineSimilarity{}, vectorstore.DotProduct{}} {
+		results, err := s.Search(ctx, "red apples pears", vectorstore.SearchParams{K: 4, Metric: m, Explain: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range results {
+			e := r.Explanation
+			if e == nil {
+				t.Fatalf("%T: result %d has no explanation", m, r.ID)
+			}
+			want := e.Dot
+			if _, ok := m.(vectorstore.CosineSimilarity); ok {
+				want /= e.QueryNorm * e.DocNorm
+			}
+			if math.Abs(want-r.Score) > 1e-9 || math.Abs(e.VectorScore-r.Score) > 1e-9 {
+				t.Errorf("%T: explanation %+v doesn't reconstruct the score %v", m, e, r.Score)
+			}
+		}
+	}
+
+	results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Explanation != nil {
+		t.Errorf("a search without Explain explained its result: %+v", results[0].Explanation)
+	}
+}
+
+func TestSearchCosineDistance(t *testing.T) {
+	ctx := context.Background()
+	texts := []string{"red apples", "red apples pears", "red sky blue", "green grass"}
+
+	// Quantized and sparse embeddings are scored on paths of their own.
+	for _, dtype := range []vectorstore.DType{vectorstore.DTypeFloat64, vectorstore.DTypeInt8, vectorstore.DTypeSparse} {
+		s := openStore(t, memOptions().WithDType(dtype))
+		insertAll(t, s, texts...)
+
+		similar, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		distant, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 4, Metric: vectorstore.CosineDistance{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		similarity := make(map[uint64]float64)
+		for _, r := range similar {
+			similarity[r.ID] = r.Score
+		}
+		for i, r := range distant {
+			if want := 1 - similarity[r.ID]; math.Abs(r.Score-want) > 1e-9 {
+				t.Errorf("%v: distance of %d = %v, want 1 - similarity = %v", dtype, r.ID, r.Score, want)
+			}
+			if i > 0 && r.Score < distant[i-1].Score {
+				t.Errorf("%v: distances aren't ascending: %v then %v", dtype, distant[i-1].Score, r.Score)
+			}
+		}
+	}
+}
+
+func TestSearchFields(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	if _, err := s.InsertWithMetadata(ctx, "red apples", map[string]string{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 1, Fields: vectorstore.FieldText | vectorstore.FieldMetadata | vectorstore.FieldEmbedding})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Text != "red apples" || r.Metadata["k"] != "v" || len(r.Embedding) != testDim {
+		t.Errorf("result with every field = %+v", r)
+	}
+
+	results, err = s.Search(ctx, "red apples", vectorstore.SearchParams{K: 1, Fields: vectorstore.FieldEmbedding})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Text != "" || r.Metadata != nil || len(r.Embedding) != testDim {
+		t.Errorf("result with the embedding alone = %+v", r)
+	}
+
+	results, err = s.Nearest(ctx, "red apples", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Text != "red apples" || r.Metadata != nil || r.Embedding != nil || r.Score < 0.999 {
+		t.Errorf("result with the default fields = %+v", r)
+	}
+}
+
+func TestSearchMetricConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	// The dot product rewards the longer vector, cosine the closer angle.
+	ids := insertAll(t, s, "red apples", "red red red red sky", "blue grass")
+	byCosine := []uint64{ids[0], ids[1]}
+	byDot := []uint64{ids[1], ids[0]}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		m, want := vectorstore.DistanceMetric(vectorstore.CosineSimilarity{}), byCosine
+		if i%2 == 1 {
+			m, want = vectorstore.DotProduct{}, byDot
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 2, Metric: m})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got := resultIDs(results); !reflect.DeepEqual(got, want) {
+					t.Errorf("%T: results = %v, want %v", m, got, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	results, err := s.Nearest(ctx, "red apples", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(results); !reflect.DeepEqual(got, byCosine) {
+		t.Errorf("after searches with other metrics, the default ranks %v, want %v", got, byCosine)
+	}
+}
+
+func TestSearchPrefetch(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(50, 5)...)
+
+	want, err := s.Nearest(ctx, "topic2 things", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 7, 100} {
+		got, err := s.Search(ctx, "topic2 things", vectorstore.SearchParams{K: 10, PrefetchValues: true, PrefetchSize: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resultIDs(got), resultIDs(want)) {
+			t.Errorf("prefetching %d: results = %v, want %v", size, resultIDs(got), resultIDs(want))
+		}
+	}
+
+	if _, err := s.Search(ctx, "topic2 things", vectorstore.SearchParams{K: 10, PrefetchValues: true, PrefetchSize: -1}); err == nil {
+		t.Error("a negative PrefetchSize was accepted")
+	}
+}
+
+func TestSearchStoreNorms(t *testing.T) {
+	ctx := context.Background()
+	texts := numbered(40, 4)
+
+	for _, dtype := range []vectorstore.DType{vectorstore.DTypeFloat64, vectorstore.DTypeFloat32, vectorstore.DTypeFloat16} {
+		full := openStore(t, memOptions().WithDType(dtype))
+		ids := insertAll(t, full, texts...)
+		normed := openStore(t, memOptions().WithDType(dtype).WithStoreNorms(true))
+		insertAll(t, normed, texts...)
+
+		// Updates store the norm of the new embedding.
+		for _, s := range []*vectorstore.VectorStore{full, normed} {
+			if err := s.Update(ctx, ids[3], "topic2 topic2 topic2 things"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, m := range []vectorstore.DistanceMetric{vectorstore.CosineSimilarity{}, vectorstore.CosineDistance{}} {
+			want, err := full.Search(ctx, "topic2 things", vectorstore.SearchParams{K: 10, Metric: m})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := normed.Search(ctx, "topic2 things", vectorstore.SearchParams{K: 10, Metric: m})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resultIDs(got), resultIDs(want)) || !slices.Contains(resultIDs(got), ids[3]) {
+				t.Fatalf("%v %T: with norms %v, without %v", dtype, m, resultIDs(got), resultIDs(want))
+			}
+			for i := range want {
+				if math.Abs(got[i].Score-want[i].Score) > 1e-12 {
+					t.Errorf("%v %T: score with the norm %v, computed in full %v", dtype, m, got[i].Score, want[i].Score)
+				}
+			}
+		}
+	}
+}
+
+func TestSearchRelevance(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, "red apples", "red apples and pears", "blue sky", "green grass", "!!!")
+
+	for _, m := range []vectorstore.DistanceMetric{
+		vectorstore.CosineSimilarity{},
+		vectorstore.CosineDistance{},
+		vectorstore.EuclideanDistance{},
+		vectorstore.SquaredEuclideanDistance{},
+		vectorstore.DotProduct{},
+		vectorstore.ManhattanDistance{},
+	} {
+		results, err := s.NearestWithMetric(ctx, "red apples red apples", 5, m)
+		if err != nil {
+			t.Fatalf("%T: %v", m, err)
+		}
+		if len(results) != 5 {
+			t.Fatalf("%T: %d results, want 5", m, len(results))
+		}
+		checkRelevance(t, m, results)
+	}
+}
+
+// checkRelevance checks that results' relevances are within [0, 1] and fall
+// as their scores get worse.
+func checkRelevance(t *testing.T, name any, results []vectorstore.Result) {
+	t.Helper()
+	for i, r := range results {
+		if r.Relevance < 0 || r.Relevance > 1 {
+			t.Errorf("%v: relevance %v is outside [0, 1]", name, r.Relevance)
+		}
+		if i > 0 && r.Relevance > results[i-1].Relevance {
+			t.Errorf("%v: relevance rises from %v to %v down the results", name, results[i-1].Relevance, r.Relevance)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// ComputeSimilarityMatrix returns the cosine similarity of every pair of
+// stored documents, for clustering and analysis, along with their IDs in
+// the order of the matrix's rows and columns. The diagonal is 1, or 0 for
+// a zero embedding. As the matrix grows with the square of the documents,
+// it fails rather than reading more than Options.MaxMatrixDocs of them.
+func (s *VectorStore) ComputeSimilarityMatrix(ctx context.Context) ([][]float64, []uint64, error) {
+	if err := s.begin(); err != nil {
+		return nil, nil, err
+	}
+	defer s.end()
+
+	var ids []uint64
+	var vecs [][]float64
+	if err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.keys.docPrefix()
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if len(ids) == s.opts.MaxMatrixDocs {
+				return fmt.Errorf("similarity matrix is limited to %d documents", s.opts.MaxMatrixDocs)
+			}
+			if len(ids)%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			var vec storedVector
+			if err := it.Item().Value(func(val []byte) (err error) {
+				vec, err = s.format().decodeVector(val)
+				return
+			}); err != nil {
+				return err
+			}
+
+			ids = append(ids, s.keys.docID(it.Item().Key()))
+			vecs = append(vecs, vec.float64s())
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	n := len(vecs)
+	cells := make([]float64, n*n)
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = cells[i*n : (i+1)*n]
+	}
+
+	for i := range vecs {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		if dot(vecs[i], vecs[i]) != 0 {
+			matrix[i][i] = 1
+		}
+		for j := i + 1; j < n; j++ {
+			sim := cosineSimilarity(vecs[i], vecs[j])
+			matrix[i][j], matrix[j][i] = sim, sim
+		}
+	}
+
+	return matrix, ids, nil
+}
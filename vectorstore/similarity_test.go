@@ -0,0 +1,40 @@
+package vectorstore_test
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSimilarityMatrix(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	ids := insertAll(t, s, "red apples", "red apples", "blue sky")
+
+	m, got, err := s.ComputeSimilarityMatrix(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != ids[0] || len(m) != 3 {
+		t.Fatalf("matrix of %d rows for IDs %v", len(m), got)
+	}
+	for i := range m {
+		if math.Abs(m[i][i]-1) > 1e-9 {
+			t.Errorf("m[%d][%d] = %v, want 1", i, i, m[i][i])
+		}
+		for j := range m {
+			if m[i][j] != m[j][i] {
+				t.Errorf("m[%d][%d] = %v but m[%d][%d] = %v", i, j, m[i][j], j, i, m[j][i])
+			}
+		}
+	}
+	if math.Abs(m[0][1]-1) > 1e-9 || m[0][2] >= m[0][1] {
+		t.Errorf("row 0 = %v, want the identical text at 1 and the other below it", m[0])
+	}
+
+	limited := openStore(t, memOptions().WithMaxMatrixDocs(2))
+	insertAll(t, limited, "one", "two", "three")
+	if _, _, err := limited.ComputeSimilarityMatrix(ctx); err == nil {
+		t.Error("a matrix over MaxMatrixDocs succeeded")
+	}
+}
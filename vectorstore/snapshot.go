@@ -0,0 +1,247 @@
+package vectorstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// hnswSnapshotMagic starts an HNSW snapshot, followed by a version byte.
+// The snapshot is then:
+//
+//	uvarint documents in the store when it was taken
+//	byte 1 and the entry point, big endian uint64 ID and uvarint level,
+//	  or byte 0 for an empty graph
+//	for each node: uvarint length, big endian uint64 ID, node as stored
+//	uvarint 0
+//	uvarint node count
+const hnswSnapshotMagic = "HNSWSNAP"
+
+const hnswSnapshotVersion = 1
+
+// snapshotBatchSize is how many nodes LoadIndex writes per transaction.
+const snapshotBatchSize = 1024
+
+// SaveIndex writes the HNSW graph, its nodes' neighbour lists and its entry
+// point, to w, without the vectors, which stay with the documents. The
+// snapshot is consistent even while writes go on.
+func (s *VectorStore) SaveIndex(ctx context.Context, w io.Writer) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	bw := bufio.NewWriter(w)
+
+	if err := s.db.View(func(txn *badger.Txn) error {
+		docs, err := s.countDocs(ctx, txn)
+		if err != nil {
+			return err
+		}
+
+		header := append([]byte(hnswSnapshotMagic), hnswSnapshotVersion)
+		header = binary.AppendUvarint(header, uint64(docs))
+
+		id, level, ok, err := newHNSWGraph(txn, s.keys, s.format(), s.opts.HNSW, s.opts.Metric).entry()
+		if err != nil {
+			return err
+		}
+		if ok {
+			header = append(header, 1)
+			header = binary.BigEndian.AppendUint64(header, id)
+			header = binary.AppendUvarint(header, uint64(level))
+		} else {
+			header = append(header, 0)
+		}
+
+		if _, err := bw.Write(header); err != nil {
+			return err
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.keys.hnswNodePrefix()
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		nodes := 0
+		var buf []byte
+		for it.Rewind(); it.Valid(); it.Next() {
+			if nodes++; nodes%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			val, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			buf = binary.AppendUvarint(buf[:0], uint64(8+len(val)))
+			buf = binary.BigEndian.AppendUint64(buf, s.keys.docID(it.Item().Key()))
+			if _, err := bw.Write(append(buf, val...)); err != nil {
+				return err
+			}
+		}
+
+		_, err = bw.Write(binary.AppendUvarint(binary.AppendUvarint(nil, 0), uint64(nodes)))
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// LoadIndex replaces the HNSW graph with a snapshot from SaveIndex, taken
+// of the same documents, so that a restored store needn't rebuild it. It
+// fails with ErrSnapshotMismatch if the store holds a different number of
+// documents or a node has no document. The graph is usable whatever
+// Options.Index is, so a store can be populated under IndexFlat, have its
+// graph loaded, then be opened with IndexHNSW. If loading fails part way,
+// the graph is left empty for BuildIndex to rebuild.
+func (s *VectorStore) LoadIndex(ctx context.Context, r io.Reader) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(hnswSnapshotMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("reading snapshot header: %w", err)
+	}
+	if string(header[:len(hnswSnapshotMagic)]) != hnswSnapshotMagic {
+		return errors.New("not an HNSW index snapshot")
+	}
+	if v := header[len(hnswSnapshotMagic)]; v != hnswSnapshotVersion {
+		return fmt.Errorf("unsupported HNSW snapshot version %d", v)
+	}
+
+	docs, err := binary.ReadUvarint(br)
+	if err != nil {
+		return fmt.Errorf("reading snapshot header: %w", err)
+	}
+
+	var entry []byte
+	if hasEntry, err := br.ReadByte(); err != nil {
+		return fmt.Errorf("reading snapshot header: %w", err)
+	} else if hasEntry == 1 {
+		entry = make([]byte, 8)
+		if _, err := io.ReadFull(br, entry); err != nil {
+			return fmt.Errorf("reading snapshot header: %w", err)
+		}
+		level, err := binary.ReadUvarint(br)
+		if err != nil {
+			return fmt.Errorf("reading snapshot header: %w", err)
+		}
+		entry = binary.BigEndian.AppendUint64(entry, level)
+	}
+
+	count, err := s.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if uint64(count) != docs {
+		return fmt.Errorf("%w: snapshot was taken of %d documents, the store has %d", ErrSnapshotMismatch, docs, count)
+	}
+
+	if err := s.dropPrefix(s.keys.hnswPrefix()); err != nil {
+		return err
+	}
+
+	if err := s.loadNodes(ctx, br); err != nil {
+		if dropErr := s.dropPrefix(s.keys.hnswPrefix()); dropErr != nil {
+			return errors.Join(err, dropErr)
+		}
+		return err
+	}
+
+	if entry == nil {
+		return nil
+	}
+
+	return s.update(ctx, func(txn *badger.Txn) error {
+		return txn.Set(s.keys.hnswEntry(), entry)
+	})
+}
+
+// loadNodes writes the nodes of a snapshot read from br, checking each has
+// a document.
+func (s *VectorStore) loadNodes(ctx context.Context, br *bufio.Reader) error {
+	type node struct {
+		id  uint64
+		val []byte
+	}
+
+	nodes := 0
+	for done := false; !done; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var batch []node
+		for len(batch) < snapshotBatchSize {
+			size, err := binary.ReadUvarint(br)
+			if err != nil {
+				return fmt.Errorf("reading snapshot node: %w", err)
+			}
+			if size == 0 {
+				done = true
+				break
+			}
+			if size < 9 {
+				return fmt.Errorf("reading snapshot node: %w", ErrCorruptRecord)
+			}
+
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(br, buf); err != nil {
+				return fmt.Errorf("reading snapshot node: %w", err)
+			}
+			if _, err := decodeHNSWNode(buf[8:]); err != nil {
+				return fmt.Errorf("reading snapshot node: %w", err)
+			}
+
+			batch = append(batch, node{id: binary.BigEndian.Uint64(buf), val: buf[8:]})
+		}
+
+		if err := s.update(ctx, func(txn *badger.Txn) error {
+			for _, n := range batch {
+				if _, err := txn.Get(s.keys.doc(n.id)); err == badger.ErrKeyNotFound {
+					return fmt.Errorf("%w: node %d has no document", ErrSnapshotMismatch, n.id)
+				} else if err != nil {
+					return err
+				}
+
+				if err := txn.Set(s.keys.hnswNode(n.id), n.val); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+		nodes += len(batch)
+	}
+
+	want, err := binary.ReadUvarint(br)
+	if err != nil {
+		return fmt.Errorf("reading snapshot trailer: %w", err)
+	}
+	if want != uint64(nodes) {
+		return fmt.Errorf("snapshot is truncated: it has %d nodes, expected %d", nodes, want)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// SparseVector is an embedding that is mostly zeros, held as its non-zero
+// elements: Values[i] is at Indices[i], with Indices ascending and below
+// Dim.
+type SparseVector struct {
+	Dim     int
+	Indices []uint32
+	Values  []float32
+}
+
+// SparseFromDense returns the non-zero elements of v.
+func SparseFromDense(v []float64) SparseVector {
+	sv := SparseVector{Dim: len(v)}
+	for i, x := range v {
+		if x != 0 {
+			sv.Indices = append(sv.Indices, uint32(i))
+			sv.Values = append(sv.Values, float32(x))
+		}
+	}
+
+	return sv
+}
+
+// Dense expands v to all Dim elements.
+func (v SparseVector) Dense() []float64 {
+	dense := make([]float64, v.Dim)
+	for i, idx := range v.Indices {
+		dense[idx] = float64(v.Values[i])
+	}
+
+	return dense
+}
+
+// Dot is the dot product of v and o, which only visits the indices they
+// both have.
+func (v SparseVector) Dot(o SparseVector) float64 {
+	sum := 0.0
+	for i, j := 0, 0; i < len(v.Indices) && j < len(o.Indices); {
+		switch {
+		case v.Indices[i] < o.Indices[j]:
+			i++
+		case v.Indices[i] > o.Indices[j]:
+			j++
+		default:
+			sum += float64(v.Values[i]) * float64(o.Values[j])
+			i++
+			j++
+		}
+	}
+
+	return sum
+}
+
+func (v SparseVector) validate() error {
+	if len(v.Indices) != len(v.Values) {
+		return fmt.Errorf("sparse vector has %d indices but %d values", len(v.Indices), len(v.Values))
+	}
+
+	for i, idx := range v.Indices {
+		if int64(idx) >= int64(v.Dim) {
+			return fmt.Errorf("sparse vector index %d is out of range for dimension %d", idx, v.Dim)
+		}
+		if i > 0 && idx <= v.Indices[i-1] {
+			return fmt.Errorf("sparse vector indices must be ascending, got %d after %d", idx, v.Indices[i-1])
+		}
+	}
+
+	return nil
+}
+
+// dotDense is the dot product of v and a dense vector of its dimension.
+func (v SparseVector) dotDense(dense []float64) float64 {
+	sum := 0.0
+	for i, idx := range v.Indices {
+		sum += float64(v.Values[i]) * dense[idx]
+	}
+
+	return sum
+}
+
+// scoreSparse scores a sparse stored vector against a dense query, whose
+// dot product with itself is querySq, visiting only the stored vector's
+// non-zero elements. Like scoreQuantized, it reports false for metrics it
+// doesn't know.
+func scoreSparse(metric DistanceMetric, query []float64, querySq float64, v *SparseVector) (float64, bool) {
+	if v.Dim != len(query) {
+		return 0, false
+	}
+
+	switch metric.(type) {
+	case DotProduct:
+		return v.dotDense(query), true
+	case CosineSimilarity:
+		vv := v.Dot(*v)
+		if querySq == 0 || vv == 0 {
+			return 0, true
+		}
+
+		return v.dotDense(query) / (math.Sqrt(querySq) * math.Sqrt(vv)), true
+	case CosineDistance:
+		score, ok := scoreSparse(CosineSimilarity{}, query, querySq, v)
+		return 1 - score, ok
+	case SquaredEuclideanDistance:
+		return max(querySq+v.Dot(*v)-2*v.dotDense(query), 0), true
+	case EuclideanDistance:
+		return math.Sqrt(max(querySq+v.Dot(*v)-2*v.dotDense(query), 0)), true
+	default:
+		return 0, false
+	}
+}
+
+// InsertSparse stores text with vec as its embedding, written as
+// DTypeSparse whatever Options.DType is, without calling the embedder. vec
+// must have the dimension of the stored embeddings. Searches score sparse
+// records by their non-zero elements alone.
+func (s *VectorStore) InsertSparse(ctx context.Context, text string, metadata map[string]string, vec SparseVector) (uint64, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+	if err := s.checkText(text); err != nil {
+		return 0, err
+	}
+	if err := vec.validate(); err != nil {
+		return 0, err
+	}
+
+	embedding := vec.Dense()
+	if s.opts.Normalize && !normalize(embedding) {
+		s.log.Warn("Embedding is a zero vector, storing it unnormalized", "text", text)
+	}
+
+	ids, err := s.writeRecords(ctx, []record{{Text: text, Metadata: metadata, Embedding: embedding, Sparse: true}})
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
@@ -0,0 +1,118 @@
+package vectorstore_test
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+func TestSparseVector(t *testing.T) {
+	dense := []float64{0, 1.5, 0, 0, -2, 0}
+	sv := vectorstore.SparseFromDense(dense)
+	if sv.Dim != 6 || !reflect.DeepEqual(sv.Indices, []uint32{1, 4}) {
+		t.Errorf("SparseFromDense = %+v", sv)
+	}
+	if !reflect.DeepEqual(sv.Dense(), dense) {
+		t.Errorf("Dense = %v, want %v", sv.Dense(), dense)
+	}
+
+	other := []float64{3, 2, 0, 0, 1, 0}
+	want := 0.0
+	for i := range dense {
+		want += dense[i] * other[i]
+	}
+	if got := sv.Dot(vectorstore.SparseFromDense(other)); got != want {
+		t.Errorf("Dot = %v, the dense dot product is %v", got, want)
+	}
+}
+
+func TestSparseSearch(t *testing.T) {
+	ctx := context.Background()
+	texts := numbered(40, 4)
+
+	dense := openStore(t, memOptions())
+	insertAll(t, dense, texts...)
+	sparse := openStore(t, memOptions().WithDType(vectorstore.DTypeSparse))
+	insertAll(t, sparse, texts...)
+
+	for _, m := range []vectorstore.DistanceMetric{vectorstore.CosineSimilarity{}, vectorstore.DotProduct{}, vectorstore.EuclideanDistance{}} {
+		want, err := dense.Search(ctx, "topic2 things", vectorstore.SearchParams{K: 10, Metric: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := sparse.Search(ctx, "topic2 things", vectorstore.SearchParams{K: 10, Metric: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%T: sparse search returned %d results, dense %d", m, len(got), len(want))
+		}
+		for i := range want {
+			if math.Abs(got[i].Score-want[i].Score) > 1e-6 {
+				t.Errorf("%T: sparse scores %v differ from dense", m, got[i].Score)
+				break
+			}
+		}
+	}
+}
+
+func TestInsertSparse(t *testing.T) {
+	ctx := context.Background()
+	s := openStoreWith(t, memOptions().WithNormalize(false), fixedEmbedder{dim: 6})
+
+	a, err := s.InsertSparse(ctx, "first", nil, vectorstore.SparseVector{Dim: 6, Indices: []uint32{0, 5}, Values: []float32{1, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.InsertSparse(ctx, "second", nil, vectorstore.SparseVector{Dim: 6, Indices: []uint32{2}, Values: []float32{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []vectorstore.DistanceMetric{vectorstore.CosineSimilarity{}, vectorstore.DotProduct{}, vectorstore.EuclideanDistance{}} {
+		results, err := s.SearchByVector(ctx, []float64{0, 0, 1, 0, 0, 0.1}, vectorstore.SearchParams{K: 2, Metric: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resultIDs(results); !reflect.DeepEqual(got, []uint64{b, a}) {
+			t.Errorf("%T: results = %v, want [%d %d]", m, got, b, a)
+		}
+	}
+
+	doc, err := s.Get(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc.Embedding, []float64{1, 0, 0, 0, 0, 1}) {
+		t.Errorf("sparse document's embedding = %v", doc.Embedding)
+	}
+
+	invalid := []vectorstore.SparseVector{
+		{Dim: 6, Indices: []uint32{0, 3}, Values: []float32{1}},
+		{Dim: 6, Indices: []uint32{6}, Values: []float32{1}},
+		{Dim: 6, Indices: []uint32{2, 1}, Values: []float32{1, 2}},
+		{Dim: 6, Indices: []uint32{1, 1}, Values: []float32{1, 2}},
+		{Dim: 5, Indices: []uint32{1}, Values: []float32{1}},
+	}
+	for _, sv := range invalid {
+		if _, err := s.InsertSparse(ctx, "bad", nil, sv); err == nil {
+			t.Errorf("InsertSparse(%+v) succeeded", sv)
+		}
+	}
+}
+
+// fixedEmbedder embeds every text as the same unit vector.
+type fixedEmbedder struct {
+	dim int
+}
+
+func (e fixedEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	vec := make([]float64, e.dim)
+	vec[0] = 1
+	return vec, nil
+}
+
+func (e fixedEmbedder) Dim() int { return e.dim }
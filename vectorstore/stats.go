@@ -0,0 +1,90 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// Stats describes the contents of a store.
+type Stats struct {
+	// Documents is the number of stored documents.
+	Documents int
+
+	// Dimension is the embedding dimension, or 0 if nothing has been
+	// inserted yet.
+	Dimension int
+
+	// DType is the type new embeddings are written with.
+	DType DType
+
+	// Index is the configured index type.
+	Index IndexType
+
+	// LSMSize and VLogSize are Badger's estimates of its on disk size in
+	// bytes. They are refreshed periodically, so may lag recent writes.
+	LSMSize  int64
+	VLogSize int64
+}
+
+// Count returns the number of stored documents. It only reads keys.
+func (s *VectorStore) Count(ctx context.Context) (int, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	var n int
+	err := s.db.View(func(txn *badger.Txn) (err error) {
+		n, err = s.countDocs(ctx, txn)
+		return
+	})
+
+	return n, err
+}
+
+func (s *VectorStore) countDocs(ctx context.Context, txn *badger.Txn) (int, error) {
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: s.keys.docPrefix()})
+	defer it.Close()
+
+	n := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		if n++; n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Stats returns the document count along with the index settings and size.
+func (s *VectorStore) Stats(ctx context.Context) (Stats, error) {
+	if err := s.begin(); err != nil {
+		return Stats{}, err
+	}
+	defer s.end()
+
+	st := Stats{DType: s.opts.DType, Index: s.opts.Index}
+
+	var err error
+	if st.Documents, err = s.Count(ctx); err != nil {
+		return st, err
+	}
+
+	if err := s.db.View(func(txn *badger.Txn) error {
+		val, err := getMeta(txn, s.keys.meta(metaDim))
+		if len(val) == 4 {
+			st.Dimension = int(binary.BigEndian.Uint32(val))
+		}
+		return err
+	}); err != nil {
+		return st, err
+	}
+
+	st.LSMSize, st.VLogSize = s.db.Size()
+
+	return st, nil
+}
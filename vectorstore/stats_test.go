@@ -0,0 +1,90 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+func TestCount(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithIndex(vectorstore.IndexHNSW))
+
+	// The graph's keys live alongside the documents but aren't counted.
+	const n = 300
+	ids, err := s.InsertBatch(ctx, numbered(n, 10), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Count(ctx); err != nil || got != n {
+		t.Errorf("Count = %d, %v, want %d", got, err, n)
+	}
+
+	if err := s.Delete(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Count(ctx); err != nil || got != n-1 {
+		t.Errorf("after Delete, Count = %d, %v, want %d", got, err, n-1)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithDType(vectorstore.DTypeFloat32).WithIndex(vectorstore.IndexHNSW))
+
+	st, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Documents != 0 || st.Dimension != 0 {
+		t.Errorf("empty store Stats = %+v", st)
+	}
+
+	insertAll(t, s, "red apples", "blue sky", "green grass")
+	st, err = s.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Documents != 3 || st.Dimension != testDim || st.DType != vectorstore.DTypeFloat32 || st.Index != vectorstore.IndexHNSW {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+// unsizedEmbedder is a HashEmbedder that doesn't report its dimension.
+type unsizedEmbedder struct {
+	*vectortest.HashEmbedder
+}
+
+func (unsizedEmbedder) Dim() int { return 0 }
+
+func TestDetectDimension(t *testing.T) {
+	ctx := context.Background()
+	h := newCaptureHandler()
+	opts := testOptions(t).WithGCInterval(0)
+
+	s, err := vectorstore.Open(opts.WithLogger(slog.New(h)), unsizedEmbedder{vectortest.NewHashEmbedder(8)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, "red apples", "blue sky")
+	st, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Dimension != 8 {
+		t.Errorf("Stats.Dimension = %d, want the detected 8", st.Dimension)
+	}
+	if attrs, ok := h.find(slog.LevelInfo, "Detected embedding dimension"); !ok || attrs["dim"] != "8" {
+		t.Errorf("the detection message was %v with %v", ok, attrs)
+	}
+	s.Close()
+
+	// The detected dimension is kept, so a model of another size is refused.
+	if _, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim)); !errors.Is(err, vectorstore.ErrDimensionMismatch) {
+		t.Errorf("Open with a %d dimension model returned %v, want ErrDimensionMismatch", testDim, err)
+	}
+}
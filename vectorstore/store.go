@@ -0,0 +1,1283 @@
+// Package vectorstore stores text alongside its embedding in Badger and
+// answers nearest neighbour queries over it.
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// VectorStore is a Badger backed index of text embeddings. It is safe for
+// concurrent use: the indexes are kept in the database alongside the
+// documents and updated in the same transactions, so searches read a
+// consistent snapshot while inserts go on. BuildIndex holds writes off
+// while it rebuilds an index; BulkLoad, FitPCA, Reindex and
+// RebuildPackedStore say what they don't guarantee.
+type VectorStore struct {
+	db    *badger.DB
+	e     Embedder
+	cache *embeddingCache
+	opts  Options
+	log   Logger
+	keys  keyspace
+
+	// queryCache holds the embeddings of search queries, keyed by
+	// queryCacheKey, when Options.QueryCacheSize is set.
+	queryCache *embeddingCache
+
+	// dim is the length of the embedder's vectors: e.Dim(), or if that is
+	// 0, that of the first one it returns. Embeddings of any other length
+	// are refused. Collections share their root's.
+	dim *atomic.Int64
+
+	// root is the store a collection was opened from, it is nil for the
+	// store returned by Open.
+	root *VectorStore
+
+	// pca is the projection fitted by FitPCA, or nil.
+	pca atomic.Pointer[pcaProjection]
+
+	closed chan struct{}
+	gcDone chan struct{}
+	close  sync.Once
+	life   *lifecycle
+}
+
+// lifecycle tracks the operations running against a database, so that
+// Shutdown can wait for them. Collections share their root's.
+type lifecycle struct {
+	mu      sync.Mutex
+	closing bool
+	ops     sync.WaitGroup
+
+	// writes is held for reading by write transactions and for writing by
+	// Compact, which excludes them.
+	writes sync.RWMutex
+
+	// turns is held for reading by a write transaction's first attempt and
+	// for writing by its retries, so that a transaction that has conflicted
+	// runs alone rather than being starved by new ones.
+	turns sync.RWMutex
+
+	closeDB  sync.Once
+	closeErr error
+}
+
+// Result is a single match returned by Nearest.
+type Result struct {
+	ID        uint64
+	Text      string
+	Metadata  map[string]string
+	Score     float64
+	Embedding []float64
+
+	// Relevance is Score mapped onto [0, 1], where 1 is the closest match,
+	// whatever the metric, for display. Cosine similarities s map to
+	// (s+1)/2, Euclidean and Manhattan distances d to 1/(1+d) and dot
+	// products through the logistic function. Hybrid results are scaled
+	// by the best fused score possible, and reranked ones pass through
+	// the logistic function.
+	Relevance float64
+
+	// Parent is the document the text was chunked from, or 0.
+	Parent uint64
+
+	// CreatedAt is when the document was inserted, in Unix nanoseconds, or
+	// 0 for documents stored before creation times were recorded.
+	CreatedAt int64
+
+	// Approximate is set when the search may have missed closer
+	// documents, because it sampled them or used an approximate index.
+	Approximate bool
+
+	// Explanation is how the result was scored, set when
+	// SearchParams.Explain is.
+	Explanation *Explanation
+}
+
+// Document is a stored document as returned by Get.
+type Document struct {
+	ID        uint64
+	Text      string
+	Metadata  map[string]string
+	Embedding []float64
+
+	// Parent is the document the text was chunked from, or 0.
+	Parent uint64
+
+	// CreatedAt is when the document was inserted, in Unix nanoseconds, or
+	// 0 for documents stored before creation times were recorded.
+	CreatedAt int64
+}
+
+// candidate is a scored document awaiting selection into the results.
+type candidate struct {
+	id    uint64
+	score float64
+	vec   storedVector
+}
+
+// maxGCBackoff bounds how far the GC interval stretches while there is
+// nothing to collect.
+const maxGCBackoff = 8
+
+// ctxCheckInterval is how many documents a scan reads between checks for
+// cancellation.
+const ctxCheckInterval = 256
+
+// Open opens (or creates) the index in opts.Dir and uses e to embed text.
+func Open(opts Options, e Embedder) (*VectorStore, error) {
+	if e == nil {
+		return nil, errors.New("an embedder is required")
+	}
+	if opts.DType.width() == 0 {
+		return nil, fmt.Errorf("unsupported dtype %v", opts.DType)
+	}
+	if opts.GCDiscardRatio <= 0 || opts.GCDiscardRatio >= 1 {
+		return nil, fmt.Errorf("GC discard ratio must be between 0 and 1, got %v", opts.GCDiscardRatio)
+	}
+	if opts.Index == IndexLSH {
+		if err := opts.LSH.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.NumGoroutines < 1 {
+		return nil, fmt.Errorf("number of goroutines must be at least 1, got %d", opts.NumGoroutines)
+	}
+	if opts.NumCompactors < 2 {
+		return nil, fmt.Errorf("number of compactors must be at least 2, got %d", opts.NumCompactors)
+	}
+	if len(opts.EncryptionKey) > 0 && opts.IndexCacheSize <= 0 {
+		return nil, errors.New("an index cache size is required with an encryption key")
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = nopMetrics{}
+	}
+	if opts.Logger == nil {
+		opts.Logger = nopLogger{}
+	}
+
+	dir := opts.Dir
+	if opts.InMemory {
+		dir = ""
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(dir).
+		WithInMemory(opts.InMemory).
+		WithReadOnly(opts.ReadOnly).
+		WithEncryptionKey(opts.EncryptionKey).
+		WithIndexCacheSize(opts.IndexCacheSize).
+		WithSyncWrites(opts.SyncWrites).
+		WithNumGoroutines(opts.NumGoroutines).
+		WithNumCompactors(opts.NumCompactors).
+		WithLogger(&badgerLogger{log: withLogger{log: opts.Logger, args: []any{"pkg", "badger"}}}))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &VectorStore{
+		db:     db,
+		e:      e,
+		dim:    new(atomic.Int64),
+		cache:  newEmbeddingCache(opts.CacheSize, 0),
+		opts:   opts,
+		log:    opts.Logger,
+		closed: make(chan struct{}),
+		gcDone: make(chan struct{}),
+		life:   &lifecycle{},
+
+		queryCache: newEmbeddingCache(opts.QueryCacheSize, opts.QueryCacheTTL),
+	}
+	s.dim.Store(int64(e.Dim()))
+
+	if opts.ReadOnly || opts.InMemory {
+		close(s.gcDone)
+	} else {
+		go s.runGCLoop()
+	}
+
+	if err := s.prepare(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Collection returns a handle on the named collection in the same
+// database, creating it on first insert. Its documents, IDs and index are
+// kept apart from every other collection's, and it uses the same options
+// as the store it was opened from. The default collection is the one
+// returned by Open.
+//
+// Collection handles share the database, so closing one does nothing;
+// close the store returned by Open instead.
+func (s *VectorStore) Collection(name string) (*VectorStore, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	if name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid collection name %q", name)
+	}
+
+	root := s
+	if s.root != nil {
+		root = s.root
+	}
+
+	c := &VectorStore{
+		db:     root.db,
+		e:      root.e,
+		dim:    root.dim,
+		cache:  root.cache,
+		opts:   root.opts,
+		log:    withLogger{log: root.log, args: []any{"collection", name}},
+		keys:   collectionKeyspace(name),
+		root:   root,
+		closed: root.closed,
+		gcDone: root.gcDone,
+		life:   root.life,
+
+		queryCache: root.queryCache,
+	}
+
+	if err := c.prepare(); err != nil {
+		return nil, fmt.Errorf("collection %q: %w", name, err)
+	}
+
+	return c, nil
+}
+
+// prepare checks the stored data against the options and brings it up to
+// date before the store is used.
+func (s *VectorStore) prepare() error {
+	if err := s.checkNormalized(); err != nil {
+		return err
+	}
+
+	if s.root == nil {
+		if err := s.migrateLegacy(); err != nil {
+			return fmt.Errorf("migrating legacy entries: %w", err)
+		}
+	}
+
+	if err := s.loadPCA(); err != nil {
+		return err
+	}
+
+	if err := s.checkSchema(); err != nil {
+		return err
+	}
+
+	if s.opts.VerifyOnOpen {
+		if err := s.verify(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	if err := s.buildIndexIfEmpty(); err != nil {
+		return fmt.Errorf("building %v index: %w", s.opts.Index, err)
+	}
+
+	if err := s.syncKeywords(); err != nil {
+		return fmt.Errorf("keyword index: %w", err)
+	}
+
+	return nil
+}
+
+// Close is Shutdown without a deadline.
+func (s *VectorStore) Close() error {
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown stops new operations, with ErrClosed, and background GC, waits
+// for the operations already running to finish and then closes the
+// database, returning any error from flushing it. SearchStreams still
+// running are stopped, with ErrClosed.
+//
+// If ctx is done first, its error is returned and the database is left
+// open, so Shutdown can be called again to finish closing it. Collections
+// share the database of the store they came from, so Shutdown does
+// nothing on them.
+func (s *VectorStore) Shutdown(ctx context.Context) error {
+	if s.root != nil {
+		return nil
+	}
+
+	s.life.mu.Lock()
+	s.life.closing = true
+	s.life.mu.Unlock()
+	s.close.Do(func() { close(s.closed) })
+
+	idle := make(chan struct{})
+	go func() {
+		s.life.ops.Wait()
+		<-s.gcDone
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.life.closeDB.Do(func() { s.life.closeErr = s.db.Close() })
+	return s.life.closeErr
+}
+
+// begin registers an operation with the lifecycle, failing with ErrClosed
+// once Shutdown has been called. Every successful begin must be matched by
+// an end.
+func (s *VectorStore) begin() error {
+	s.life.mu.Lock()
+	defer s.life.mu.Unlock()
+
+	if s.life.closing {
+		return ErrClosed
+	}
+	s.life.ops.Add(1)
+
+	return nil
+}
+
+func (s *VectorStore) end() {
+	s.life.ops.Done()
+}
+
+// readyProbe is the text Ready embeds to check the model works.
+const readyProbe = "ready"
+
+// Ready reports whether the store can serve requests: the database is open
+// and the embedder produces a vector of the dimension it advertises, if it
+// advertises one. The probe bypasses the embedding cache. Failures wrap
+// ErrNotReady.
+func (s *VectorStore) Ready(ctx context.Context) error {
+	if err := s.begin(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotReady, err)
+	}
+	defer s.end()
+
+	if s.db.IsClosed() {
+		return fmt.Errorf("%w: database is closed", ErrNotReady)
+	}
+
+	vec, err := s.e.Embed(ctx, readyProbe)
+	if err != nil {
+		return fmt.Errorf("%w: embedding probe: %w", ErrNotReady, err)
+	}
+	if len(vec) == 0 || (s.e.Dim() != 0 && len(vec) != s.e.Dim()) {
+		return fmt.Errorf("%w: embedder returned %d dimensions, expected %d", ErrNotReady, len(vec), s.e.Dim())
+	}
+
+	return nil
+}
+
+// RunGC rewrites value log files until none has more than
+// Options.GCDiscardRatio of stale data, which reclaims disk space after
+// large deletions or updates. It does nothing for an in-memory store.
+func (s *VectorStore) RunGC() error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	return s.runGC()
+}
+
+func (s *VectorStore) runGC() error {
+	if s.opts.InMemory {
+		return nil
+	}
+
+	for {
+		select {
+		case <-s.closed:
+			return nil
+		default:
+		}
+
+		switch err := s.db.RunValueLogGC(s.opts.GCDiscardRatio); err {
+		case nil:
+		case badger.ErrNoRewrite:
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// runGCLoop calls RunGC every Options.GCInterval until the store is closed.
+// While GC finds nothing to rewrite the interval doubles, up to
+// maxGCBackoff times the configured one.
+func (s *VectorStore) runGCLoop() {
+	defer close(s.gcDone)
+
+	if s.opts.GCInterval <= 0 {
+		<-s.closed
+		return
+	}
+
+	wait := s.opts.GCInterval
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-time.After(wait):
+		}
+
+		err := s.db.RunValueLogGC(s.opts.GCDiscardRatio)
+		rewrote := err == nil
+		if rewrote {
+			err = s.runGC()
+		} else if err == badger.ErrNoRewrite {
+			err = nil
+		}
+
+		switch {
+		case err != nil:
+			s.log.Warn("Value log GC", "error", err)
+		case rewrote:
+			wait = s.opts.GCInterval
+		default:
+			wait = min(2*wait, maxGCBackoff*s.opts.GCInterval)
+		}
+	}
+}
+
+// checkNormalized refuses to open a non-empty index whose stored vectors
+// were written with a different Normalize setting.
+func (s *VectorStore) checkNormalized() error {
+	return s.db.View(func(txn *badger.Txn) error {
+		normalized := false
+		item, err := txn.Get(s.keys.meta(metaNormalized))
+		if err == nil {
+			if err := item.Value(func(val []byte) error {
+				normalized = len(val) == 1 && val[0] == 1
+				return nil
+			}); err != nil {
+				return err
+			}
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		if normalized == s.opts.Normalize {
+			return nil
+		}
+
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: s.keys.prefix})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if key := it.Item().Key(); s.keys.owns(key) && !s.keys.isMeta(key) {
+				return fmt.Errorf("index was built with normalized=%t, cannot open it with normalized=%t", normalized, s.opts.Normalize)
+			}
+		}
+
+		return nil
+	})
+}
+
+// markNormalized records the Normalize setting the index is written with.
+func (s *VectorStore) markNormalized(txn *badger.Txn) error {
+	marker := []byte{0}
+	if s.opts.Normalize {
+		marker[0] = 1
+	}
+
+	return txn.Set(s.keys.meta(metaNormalized), marker)
+}
+
+// buildIndexIfEmpty builds the index from existing documents when it is
+// switched on for a store that was populated without it.
+func (s *VectorStore) buildIndexIfEmpty() error {
+	if s.opts.ReadOnly {
+		return nil
+	}
+
+	empty, err := s.indexEmpty()
+	if err != nil || !empty {
+		return err
+	}
+
+	// An unfinished Reindex dropped the index and rebuilds it itself.
+	var pending *reindexState
+	if err := s.db.View(func(txn *badger.Txn) (err error) {
+		pending, err = pendingReindex(txn, s.keys)
+		return
+	}); err != nil || pending != nil {
+		return err
+	}
+
+	hasDocs := false
+	if err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.IteratorOptions{Prefix: s.keys.docPrefix()}
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		it.Rewind()
+		hasDocs = it.Valid()
+		return nil
+	}); err != nil || !hasDocs {
+		return err
+	}
+
+	s.log.Info("Building index from existing documents", "index", s.opts.Index)
+	return s.BuildIndex(context.Background())
+}
+
+// effectiveMetric swaps cosine similarity for the cheaper dot product when
+// stored vectors are already unit length.
+func (s *VectorStore) effectiveMetric(m DistanceMetric) DistanceMetric {
+	if _, ok := m.(CosineSimilarity); ok && s.opts.Normalize {
+		return DotProduct{}
+	}
+
+	return m
+}
+
+// conflictAttempts is how many times update runs a transaction that keeps
+// conflicting with concurrent ones before giving up.
+const conflictAttempts = 10
+
+// conflictBackoff is the wait before update's first retry. It doubles after
+// each, with up to as much again added at random so that writers
+// conflicting with each other don't retry in step.
+const conflictBackoff = time.Millisecond
+
+// update runs fn in a read-write transaction, retrying it when it conflicts
+// with a concurrent one. fn may therefore run more than once. Retries run
+// with no other write transaction in progress: every insert writes the ID
+// counter, so long ones, such as inserts into an HNSW graph, would
+// otherwise keep losing to those that started while they waited. Once
+// conflictAttempts are used up, badger.ErrConflict is returned, and if ctx
+// is done first, its error.
+func (s *VectorStore) update(ctx context.Context, fn func(txn *badger.Txn) error) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	s.life.writes.RLock()
+	defer s.life.writes.RUnlock()
+
+	return s.commit(ctx, fn)
+}
+
+// commit is update for callers already holding life.writes, for reading
+// or writing.
+func (s *VectorStore) commit(ctx context.Context, fn func(txn *badger.Txn) error) error {
+	wait := conflictBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.attempt(fn, attempt > 1)
+		if err != badger.ErrConflict {
+			return err
+		}
+		if attempt == conflictAttempts {
+			return fmt.Errorf("%w after %d attempts", err, attempt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait + time.Duration(rand.Int63n(int64(wait)))):
+		}
+		wait *= 2
+	}
+}
+
+// attempt runs fn in a read-write transaction, alone if it is a retry.
+func (s *VectorStore) attempt(fn func(txn *badger.Txn) error, alone bool) error {
+	if alone {
+		s.life.turns.Lock()
+		defer s.life.turns.Unlock()
+	} else {
+		s.life.turns.RLock()
+		defer s.life.turns.RUnlock()
+	}
+
+	return s.db.Update(fn)
+}
+
+// dropPrefix deletes every key under the prefixes. Badger fails writes
+// made while it drops them, so write transactions are held off until it
+// is done instead.
+func (s *VectorStore) dropPrefix(prefixes ...[]byte) error {
+	s.life.writes.Lock()
+	defer s.life.writes.Unlock()
+
+	return s.db.DropPrefix(prefixes...)
+}
+
+// allocIDs reserves n consecutive document IDs and returns the first.
+func allocIDs(txn *badger.Txn, keys keyspace, n int) (uint64, error) {
+	next := uint64(1)
+
+	item, err := txn.Get(keys.meta(metaNextID))
+	if err == nil {
+		if err := item.Value(func(val []byte) error {
+			next = binary.BigEndian.Uint64(val)
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+	} else if err != badger.ErrKeyNotFound {
+		return 0, err
+	}
+
+	return next, txn.Set(keys.meta(metaNextID), binary.BigEndian.AppendUint64(nil, next+uint64(n)))
+}
+
+const migrateBatchSize = 1000
+
+// migrateLegacy rewrites entries from the original schema, where the
+// embedding was the key and the text the value, into ID keyed records.
+func (s *VectorStore) migrateLegacy() error {
+	for {
+		var keys [][]byte
+		var recs []record
+
+		if err := s.db.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+
+			for it.Rewind(); it.Valid() && len(keys) < migrateBatchSize; it.Next() {
+				item := it.Item()
+				if !isLegacyKey(item.Key()) {
+					continue
+				}
+
+				key := item.KeyCopy(nil)
+				embedding := make([]float64, len(key)/8)
+				if err := binary.Read(bytes.NewReader(key), binary.LittleEndian, embedding); err != nil {
+					return err
+				}
+
+				text, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+
+				keys = append(keys, key)
+				recs = append(recs, record{Text: string(text), Embedding: embedding})
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(keys) == 0 {
+			return nil
+		}
+
+		if err := s.update(context.Background(), func(txn *badger.Txn) error {
+			first, err := allocIDs(txn, s.keys, len(recs))
+			if err != nil {
+				return err
+			}
+
+			for i, rec := range recs {
+				if err := s.setRecord(txn, s.keys.doc(first+uint64(i)), rec); err != nil {
+					return err
+				}
+				if err := txn.Delete(keys[i]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		s.log.Info("Migrated legacy entries to ID keyed records", "count", len(keys))
+	}
+}
+
+// getEmbedding embeds text and projects it through the PCA fitted by
+// FitPCA, if any.
+func (s *VectorStore) getEmbedding(ctx context.Context, text string) ([]float64, error) {
+	vec, err := s.embedText(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	if p := s.pca.Load(); p != nil {
+		return p.project(vec)
+	}
+
+	return vec, nil
+}
+
+// embedQuery is getEmbedding for a search query, consulting the query
+// cache first when it is enabled. The cache holds embeddings before PCA, so
+// that refitting it doesn't leave stale projections behind.
+func (s *VectorStore) embedQuery(ctx context.Context, text string) ([]float64, error) {
+	if s.opts.QueryCacheSize <= 0 {
+		return s.getEmbedding(ctx, text)
+	}
+
+	key := queryCacheKey(text)
+	vec, ok := s.queryCache.get(key)
+	if !ok {
+		var err error
+		if vec, err = s.embedText(ctx, text); err != nil {
+			return nil, err
+		}
+		s.queryCache.put(key, vec)
+	}
+
+	if p := s.pca.Load(); p != nil {
+		return p.project(vec)
+	}
+
+	return vec, nil
+}
+
+// embedText embeds text after Options.Preprocessor, consulting the cache
+// first when it is enabled.
+func (s *VectorStore) embedText(ctx context.Context, text string) ([]float64, error) {
+	if s.opts.Preprocessor != nil {
+		text = s.opts.Preprocessor(text)
+	}
+
+	if s.opts.CacheSize > 0 {
+		if vec, ok := s.cache.get(text); ok {
+			return vec, nil
+		}
+	}
+
+	start := time.Now()
+	vec, err := s.embedWithRetry(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	s.opts.Metrics.Encoded(time.Since(start))
+
+	if s.dim.CompareAndSwap(0, int64(len(vec))) {
+		s.log.Info("Detected embedding dimension", "dim", len(vec))
+	} else if want := int(s.dim.Load()); len(vec) != want {
+		return nil, fmt.Errorf("%w: embedder returned %d dimensions, expected %d", ErrDimensionMismatch, len(vec), want)
+	}
+
+	if s.opts.CacheSize > 0 {
+		s.cache.put(text, vec)
+	}
+
+	return vec, nil
+}
+
+// checkWritable returns ErrReadOnly if the store was opened read-only.
+// Writes that do work before their first transaction check it up front.
+func (s *VectorStore) checkWritable() error {
+	if s.opts.ReadOnly {
+		return ErrReadOnly
+	}
+
+	return nil
+}
+
+// checkText rejects empty text unless Options.AllowEmptyText is set.
+func (s *VectorStore) checkText(text string) error {
+	if !s.opts.AllowEmptyText && strings.TrimSpace(text) == "" {
+		return ErrEmptyText
+	}
+
+	return nil
+}
+
+// embedForStorage embeds text the way it should be written to the index.
+func (s *VectorStore) embedForStorage(ctx context.Context, text string) ([]float64, error) {
+	if err := s.checkText(text); err != nil {
+		return nil, err
+	}
+
+	var embedding []float64
+	var err error
+	if _, _, long := s.tooLong(text); long && s.opts.LongText == LongTextAverage {
+		embedding, err = s.embedAveraged(ctx, text)
+	} else if err = s.checkLength(text); err == nil {
+		embedding, err = s.getEmbedding(ctx, text)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.opts.Normalize && !normalize(embedding) {
+		s.log.Warn("Embedding is a zero vector, storing it unnormalized", "text", text)
+	}
+
+	return embedding, nil
+}
+
+// Insert embeds and stores text, returning its assigned ID.
+func (s *VectorStore) Insert(ctx context.Context, text string) (uint64, error) {
+	return s.InsertWithMetadata(ctx, text, nil)
+}
+
+// InsertWithMetadata is Insert with key value pairs stored alongside the
+// text. They are returned with the document in search results. Under
+// LongTextChunk, text too long to embed is stored with InsertChunked and
+// the parent ID is returned.
+func (s *VectorStore) InsertWithMetadata(ctx context.Context, text string, metadata map[string]string) (uint64, error) {
+	if err := s.begin(); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	if _, _, long := s.tooLong(text); long && s.opts.LongText == LongTextChunk {
+		parent, _, err := s.insertChunks(ctx, []string{text}, metadata)
+		return parent, err
+	}
+
+	embedding, err := s.embedForStorage(ctx, text)
+	if err != nil {
+		return 0, err
+	}
+
+	ids, err := s.writeRecords(ctx, []record{{Text: text, Metadata: metadata, Embedding: embedding}})
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
+
+// writeRecords stores recs under newly allocated IDs, in one transaction
+// unless that would be too big for Badger, in which case recs are split in
+// halves until each fits. If a later transaction fails, the IDs of the
+// records already stored are returned with the error, and the rest are 0.
+func (s *VectorStore) writeRecords(ctx context.Context, recs []record) ([]uint64, error) {
+	ids, err := s.writeRecordsTxn(ctx, recs)
+	if !errors.Is(err, badger.ErrTxnTooBig) || len(recs) < 2 {
+		return ids, err
+	}
+
+	half := len(recs) / 2
+	ids, err = s.writeRecords(ctx, recs[:half])
+	if err != nil {
+		return append(ids, make([]uint64, len(recs)-half)...), err
+	}
+
+	rest, err := s.writeRecords(ctx, recs[half:])
+	return append(ids, rest...), err
+}
+
+// writeRecordsTxn stores recs under newly allocated IDs in one transaction,
+// returning zero IDs if it fails. With deduplication on, a record that
+// duplicates an existing document, or an earlier one in recs, isn't written
+// and gets that document's ID. Records without a creation time are given
+// the current one.
+func (s *VectorStore) writeRecordsTxn(ctx context.Context, recs []record) ([]uint64, error) {
+	ids := make([]uint64, len(recs))
+	var inserted int
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		inserted = 0
+
+		if err := s.markNormalized(txn); err != nil {
+			return err
+		}
+
+		for _, rec := range recs {
+			if err := s.checkDim(txn, len(rec.Embedding)); err != nil {
+				return err
+			}
+			for _, v := range rec.Vectors {
+				if err := s.checkDim(txn, len(v.Embedding)); err != nil {
+					return err
+				}
+			}
+		}
+
+		// dupOf[i] is the index in recs of the record recs[i] duplicates,
+		// or -1.
+		dupOf := make([]int, len(recs))
+		var keep []int
+		for i, rec := range recs {
+			ids[i], dupOf[i] = 0, -1
+			if s.opts.DedupThreshold <= 0 {
+				keep = append(keep, i)
+				continue
+			}
+
+			id, err := s.findDuplicate(ctx, txn, rec.Embedding)
+			if err != nil {
+				return err
+			}
+			if id != 0 {
+				ids[i] = id
+				continue
+			}
+
+			if dupOf[i] = duplicateIn(recs, keep, rec.Embedding, s.opts.DedupThreshold); dupOf[i] < 0 {
+				keep = append(keep, i)
+			}
+		}
+
+		if len(keep) == 0 {
+			return nil
+		}
+
+		first, err := allocIDs(txn, s.keys, len(keep))
+		if err != nil {
+			return err
+		}
+		inserted = len(keep)
+
+		now := time.Now().UnixNano()
+		for _, i := range keep {
+			if recs[i].CreatedAt == 0 {
+				recs[i].CreatedAt = now
+			}
+		}
+
+		kept := make([]uint64, len(keep))
+		vecs := make([][]float64, len(keep))
+		for j, i := range keep {
+			ids[i] = first + uint64(j)
+			kept[j], vecs[j] = ids[i], recs[i].Embedding
+			if err := s.setRecord(txn, s.keys.doc(ids[i]), recs[i]); err != nil {
+				return err
+			}
+			if err := s.keywordAdd(txn, ids[i], recs[i].Text); err != nil {
+				return err
+			}
+			if err := s.vectorsAdd(txn, ids[i], recs[i].Vectors); err != nil {
+				return err
+			}
+			if err := s.chunkAdd(txn, recs[i].Parent, ids[i]); err != nil {
+				return err
+			}
+		}
+
+		for i, j := range dupOf {
+			if j >= 0 {
+				ids[i] = ids[j]
+			}
+		}
+
+		return s.indexAdd(txn, kept, vecs)
+	}); err != nil {
+		return make([]uint64, len(recs)), err
+	}
+	s.opts.Metrics.Inserted(inserted)
+
+	return ids, nil
+}
+
+// Get returns the document with the given ID, or ErrNotFound.
+func (s *VectorStore) Get(ctx context.Context, id uint64) (Document, error) {
+	if err := s.begin(); err != nil {
+		return Document{}, err
+	}
+	defer s.end()
+
+	doc := Document{ID: id}
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.keys.doc(id))
+		if err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			rec, err := s.format().decodeRecord(val)
+			doc.Text, doc.Metadata, doc.Embedding, doc.Parent, doc.CreatedAt = rec.Text, rec.Metadata, rec.Embedding, rec.Parent, rec.CreatedAt
+			return err
+		})
+	})
+
+	return doc, err
+}
+
+// GetMany returns the documents with the given IDs, in the same order, read
+// in one transaction so they are consistent with each other. A document
+// that doesn't exist is left as the zero Document, with ID 0, which no
+// stored document has.
+func (s *VectorStore) GetMany(ctx context.Context, ids []uint64) ([]Document, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	docs := make([]Document, len(ids))
+	err := s.db.View(func(txn *badger.Txn) error {
+		for i, id := range ids {
+			if i%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			item, err := txn.Get(s.keys.doc(id))
+			if err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			if err := item.Value(func(val []byte) error {
+				rec, err := s.format().decodeRecord(val)
+				docs[i] = Document{ID: id, Text: rec.Text, Metadata: rec.Metadata, Embedding: rec.Embedding, Parent: rec.Parent, CreatedAt: rec.CreatedAt}
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// Delete removes the document with the given ID. Given the parent ID
+// returned by InsertChunked, it removes all the parent's chunks.
+func (s *VectorStore) Delete(ctx context.Context, id uint64) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	return s.update(ctx, func(txn *badger.Txn) error {
+		if err := s.removeDoc(txn, id); err != ErrNotFound {
+			return err
+		}
+
+		return s.removeChunks(txn, id)
+	})
+}
+
+// removeDoc deletes the document id with its keywords, named vectors, index
+// entries and chunk entry, or returns ErrNotFound.
+func (s *VectorStore) removeDoc(txn *badger.Txn, id uint64) error {
+	key := s.keys.doc(id)
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	// A record too damaged to read its parent from can still be deleted;
+	// removeChunks skips the chunk entry it leaves.
+	var rec record
+	if err := item.Value(func(val []byte) error {
+		return s.format().decodeFields(val, &rec)
+	}); err == nil && rec.Parent != 0 {
+		if err := txn.Delete(s.keys.chunk(rec.Parent, id)); err != nil {
+			return err
+		}
+	}
+
+	if err := txn.Delete(key); err != nil {
+		return err
+	}
+
+	if err := s.keywordRemove(txn, id); err != nil {
+		return err
+	}
+	if err := s.vectorsRemove(txn, id); err != nil {
+		return err
+	}
+
+	return s.indexRemove(txn, id)
+}
+
+// removeChunks deletes the chunks of parent, or returns ErrNotFound if it
+// has none.
+func (s *VectorStore) removeChunks(txn *badger.Txn, parent uint64) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = s.keys.parentChunkPrefix(parent)
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+
+	var keys [][]byte
+	for it.Rewind(); it.Valid(); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	it.Close()
+
+	if len(keys) == 0 {
+		return ErrNotFound
+	}
+
+	for _, key := range keys {
+		if err := s.removeDoc(txn, s.keys.docID(key)); err != nil && err != ErrNotFound {
+			return err
+		}
+		if err := txn.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chunkAdd records id as a chunk of parent, if it has one.
+func (s *VectorStore) chunkAdd(txn *badger.Txn, parent, id uint64) error {
+	if parent == 0 {
+		return nil
+	}
+
+	return txn.Set(s.keys.chunk(parent, id), nil)
+}
+
+// Update replaces the text of an existing document and recomputes its
+// embedding, keeping the same ID, metadata and parent. Nothing is re-embedded if
+// the text is unchanged. Under LongTextChunk, text too long to embed is
+// split as InsertChunked splits it: the first chunk replaces the document
+// and the rest are stored as new documents with its metadata, all with the
+// document's parent, or a newly allocated one if it had none.
+func (s *VectorStore) Update(ctx context.Context, id uint64, text string) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+
+	key := s.keys.doc(id)
+
+	var old string
+	if err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) (err error) {
+			old, err = s.format().decodeText(val)
+			return
+		})
+	}); err != nil {
+		return err
+	}
+
+	if old == text {
+		return nil
+	}
+
+	recs := []record{{Text: text}}
+	var err error
+	if _, _, long := s.tooLong(text); long && s.opts.LongText == LongTextChunk {
+		recs, err = s.embedParts(ctx, []string{text}, nil)
+	} else {
+		recs[0].Embedding, err = s.embedForStorage(ctx, text)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.update(ctx, func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		for _, rec := range recs {
+			if err := s.checkDim(txn, len(rec.Embedding)); err != nil {
+				return err
+			}
+		}
+
+		rec := recs[0]
+		if err := item.Value(func(val []byte) error {
+			return s.format().decodeFields(val, &rec)
+		}); err != nil {
+			return err
+		}
+
+		ids := []uint64{id}
+		vecs := [][]float64{rec.Embedding}
+		if len(recs) > 1 {
+			// The new parent, if the document needs one, comes first.
+			n := len(recs) - 1
+			if rec.Parent == 0 {
+				n++
+			}
+			next, err := allocIDs(txn, s.keys, n)
+			if err != nil {
+				return err
+			}
+			if rec.Parent == 0 {
+				rec.Parent = next
+				next++
+				if err := s.chunkAdd(txn, rec.Parent, id); err != nil {
+					return err
+				}
+			}
+
+			now := time.Now().UnixNano()
+			for _, part := range recs[1:] {
+				part.Metadata, part.Parent, part.CreatedAt = rec.Metadata, rec.Parent, now
+				if err := s.setRecord(txn, s.keys.doc(next), part); err != nil {
+					return err
+				}
+				if err := s.keywordAdd(txn, next, part.Text); err != nil {
+					return err
+				}
+				if err := s.chunkAdd(txn, part.Parent, next); err != nil {
+					return err
+				}
+
+				ids, vecs = append(ids, next), append(vecs, part.Embedding)
+				next++
+			}
+		}
+
+		if err := s.setRecord(txn, key, rec); err != nil {
+			return err
+		}
+
+		if err := s.keywordRemove(txn, id); err != nil {
+			return err
+		}
+		if err := s.keywordAdd(txn, id, rec.Text); err != nil {
+			return err
+		}
+
+		if err := s.indexRemove(txn, id); err != nil {
+			return err
+		}
+
+		return s.indexAdd(txn, ids, vecs)
+	}); err != nil {
+		return err
+	}
+	s.opts.Metrics.Inserted(len(recs) - 1)
+
+	return nil
+}
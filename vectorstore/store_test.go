@@ -0,0 +1,851 @@
+package vectorstore_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// testDim is the dimension of the HashEmbedder the tests use unless they
+// need another.
+const testDim = 16
+
+// memOptions returns the options for an in-memory store.
+func memOptions() vectorstore.Options {
+	return vectorstore.DefaultOptions("").WithInMemory(true)
+}
+
+// testOptions returns the options for a store in a directory removed when
+// the test ends, for tests that reopen it or need its files.
+func testOptions(t testing.TB) vectorstore.Options {
+	return vectorstore.DefaultOptions(t.TempDir())
+}
+
+// openStore opens a store with opts and a HashEmbedder of testDim, closed
+// when the test ends.
+func openStore(t testing.TB, opts vectorstore.Options) *vectorstore.VectorStore {
+	t.Helper()
+
+	return openStoreWith(t, opts, vectortest.NewHashEmbedder(testDim))
+}
+
+// openStoreWith is openStore with e to embed text.
+func openStoreWith(t testing.TB, opts vectorstore.Options, e vectorstore.Embedder) *vectorstore.VectorStore {
+	t.Helper()
+
+	s, err := vectorstore.Open(opts, e)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// insertAll inserts texts and returns their IDs.
+func insertAll(t testing.TB, s *vectorstore.VectorStore, texts ...string) []uint64 {
+	t.Helper()
+
+	ids := make([]uint64, len(texts))
+	for i, text := range texts {
+		id, err := s.Insert(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Insert(%q): %v", text, err)
+		}
+		ids[i] = id
+	}
+
+	return ids
+}
+
+// numbered returns n texts in topics groups that share words within a
+// group.
+func numbered(n, topics int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("document %d about topic%d things", i, i%topics)
+	}
+
+	return texts
+}
+
+func resultIDs(results []vectorstore.Result) []uint64 {
+	ids := make([]uint64, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+
+	return ids
+}
+
+func resultTexts(results []vectorstore.Result) []string {
+	texts := make([]string, len(results))
+	for i, r := range results {
+		texts[i] = r.Text
+	}
+
+	return texts
+}
+
+func TestNearestTopK(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	texts := []string{"red apples", "red apples and pears", "blue sky", "green grass"}
+	ids := insertAll(t, s, texts...)
+
+	results, err := s.Nearest(ctx, "red apples", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(results); len(got) != 2 || got[0] != ids[0] || got[1] != ids[1] {
+		t.Errorf("Nearest = %v, want [%d %d]", got, ids[0], ids[1])
+	}
+	if got := resultTexts(results); got[0] != texts[0] || got[1] != texts[1] {
+		t.Errorf("Nearest = %q, want %q", got, texts[:2])
+	}
+	if results[0].Score < results[1].Score {
+		t.Errorf("results aren't best first: %v then %v", results[0].Score, results[1].Score)
+	}
+	all, err := s.Nearest(ctx, "red apples", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(texts) {
+		t.Errorf("Nearest with k above the document count returned %d results, want %d", len(all), len(texts))
+	}
+
+	if _, err := s.Nearest(ctx, "red apples", 0); err == nil {
+		t.Error("Nearest with k 0 succeeded")
+	}
+}
+
+func TestIDsIncrease(t *testing.T) {
+	s := openStore(t, memOptions())
+
+	ids := insertAll(t, s, "one", "two", "three")
+	for i, id := range ids {
+		if id != uint64(i+1) {
+			t.Errorf("ids = %v, want 1, 2, 3", ids)
+			break
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	id, err := s.InsertWithMetadata(ctx, "red apples", map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != id || doc.Text != "red apples" || doc.Metadata["k"] != "v" || len(doc.Embedding) != testDim {
+		t.Errorf("Get(%d) = %+v", id, doc)
+	}
+
+	if _, err := s.Get(ctx, id+1); !errors.Is(err, vectorstore.ErrNotFound) {
+		t.Errorf("Get of an absent ID returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestInMemory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// Dir is ignored in memory, so nothing is written under it.
+	s, err := vectorstore.Open(vectorstore.DefaultOptions("data").WithInMemory(true).WithIndex(vectorstore.IndexHNSW), vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := numbered(50, 5)
+	insertAll(t, s, texts...)
+	if err := s.BuildIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.Nearest(ctx, texts[7], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Score < 0.999 {
+		t.Errorf("in-memory Nearest of a stored text = %+v", results)
+	}
+	if err := s.RunGC(); err != nil {
+		t.Errorf("RunGC in memory: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("an in-memory store created %d files in the working directory, starting with %s", len(entries), entries[0].Name())
+	}
+}
+
+func TestCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	var last int64
+	for i, id := range insertAll(t, s, numbered(10, 2)...) {
+		doc, err := s.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.CreatedAt == 0 || doc.CreatedAt < last {
+			t.Errorf("insert %d was created at %d, after %d", i, doc.CreatedAt, last)
+		}
+		last = doc.CreatedAt
+	}
+
+	results, err := s.Nearest(ctx, "document 9", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].CreatedAt != last {
+		t.Errorf("the result's CreatedAt is %d, want the last insert's %d", results[0].CreatedAt, last)
+	}
+}
+
+func TestEmptyText(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	for _, text := range []string{"", "   ", "\n\t"} {
+		if _, err := s.Insert(ctx, text); !errors.Is(err, vectorstore.ErrEmptyText) {
+			t.Errorf("Insert(%q) returned %v, want ErrEmptyText", text, err)
+		}
+		if _, _, err := s.InsertChunked(ctx, text, nil, vectorstore.ChunkOptions{Size: 10}); !errors.Is(err, vectorstore.ErrEmptyText) {
+			t.Errorf("InsertChunked(%q) returned %v, want ErrEmptyText", text, err)
+		}
+	}
+	if _, err := s.InsertBatch(ctx, []string{"fine", " "}, nil); !errors.Is(err, vectorstore.ErrEmptyText) {
+		t.Errorf("InsertBatch with a blank text returned %v, want ErrEmptyText", err)
+	}
+	// Only the batch's good text is stored.
+	if n, err := s.Count(ctx); err != nil || n != 1 {
+		t.Errorf("Count after the rejected inserts = %d, %v, want 1", n, err)
+	}
+
+	allowed := openStore(t, memOptions().WithAllowEmptyText(true))
+	if _, err := allowed.Insert(ctx, "  "); err != nil {
+		t.Errorf("Insert of blank text with AllowEmptyText: %v", err)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	insertAll(t, s, "one", "two", "three", "four")
+	if err := s.Delete(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := s.GetMany(ctx, []uint64{4, 99, 1, 2, 3, 1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"four", "", "one", "", "three", "one", ""}
+	if len(docs) != len(want) {
+		t.Fatalf("GetMany returned %d documents, want %d", len(docs), len(want))
+	}
+	for i, doc := range docs {
+		if doc.Text != want[i] {
+			t.Errorf("docs[%d].Text = %q, want %q", i, doc.Text, want[i])
+		}
+		if (want[i] == "") != (doc.ID == 0) {
+			t.Errorf("docs[%d].ID = %d, want 0 only for missing documents", i, doc.ID)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.GetMany(cancelled, []uint64{1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetMany with a cancelled context returned %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	ids := insertAll(t, s, "red apples", "red pears", "red cherries")
+	if err := s.Delete(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Nearest(ctx, "red pears", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("after Delete, Nearest returned %d results, want 2", len(results))
+	}
+	for _, r := range results {
+		if r.ID == ids[1] {
+			t.Errorf("deleted document %d was returned by Nearest", r.ID)
+		}
+	}
+
+	if err := s.Delete(ctx, ids[1]); !errors.Is(err, vectorstore.ErrNotFound) {
+		t.Errorf("second Delete returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	ids := insertAll(t, s, "red apples", "blue sky")
+	if err := s.Update(ctx, ids[0], "blue sky today"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Nearest(ctx, "today", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != ids[0] || results[0].Text != "blue sky today" {
+		t.Errorf("Nearest(today) = %+v, want the updated document %d", results, ids[0])
+	}
+
+	if err := s.Update(ctx, ids[1]+10, "nothing"); !errors.Is(err, vectorstore.ErrNotFound) {
+		t.Errorf("Update of a missing ID returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	want := map[string]string{"source": "orchard", "season": "autumn"}
+	id, err := s.InsertWithMetadata(ctx, "red apples", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, "blue sky")
+
+	results, err := s.Search(ctx, "red apples", vectorstore.SearchParams{K: 2, Fields: vectorstore.FieldMetadata})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].ID != id || !reflect.DeepEqual(results[0].Metadata, want) {
+		t.Errorf("Nearest returned %+v first, want %d with %v", results[0], id, want)
+	}
+	if results[1].Metadata != nil {
+		t.Errorf("a document inserted without metadata has %v", results[1].Metadata)
+	}
+}
+
+func TestIdenticalEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	// The HashEmbedder ignores case and punctuation, so these embed the same.
+	ids := insertAll(t, s, "red apples", "Red, apples!")
+	if ids[0] == ids[1] {
+		t.Fatalf("both texts got ID %d", ids[0])
+	}
+
+	results, err := s.Nearest(ctx, "red apples", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := resultTexts(results)
+	if len(got) != 2 || got[0] == got[1] {
+		t.Errorf("Nearest = %q, want both texts", got)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithDedupThreshold(0.99))
+
+	// The HashEmbedder ignores case and punctuation, so these are
+	// duplicates.
+	ids := insertAll(t, s, "red apples", "Red, apples!", "blue sky")
+	if ids[1] != ids[0] {
+		t.Errorf("the duplicate got ID %d, want the original's %d", ids[1], ids[0])
+	}
+
+	batch, err := s.InsertBatch(ctx, []string{"green grass", "GREEN grass", "red apples"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch[1] != batch[0] || batch[2] != ids[0] {
+		t.Errorf("InsertBatch ids = %v, want duplicates of %d and %d", batch, batch[0], ids[0])
+	}
+
+	if n, err := s.Count(ctx); err != nil || n != 3 {
+		t.Errorf("Count = %d, %v, want 3 after skipping the duplicates", n, err)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions(t)
+
+	// Write entries the way the original demo did, keyed by the embedding.
+	db, err := badger.Open(badger.DefaultOptions(opts.Dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{"red apples", "blue sky", "green grass"}
+	if err := db.Update(func(txn *badger.Txn) error {
+		for _, text := range texts {
+			vec, err := vectortest.NewHashEmbedder(testDim).Embed(ctx, text)
+			if err != nil {
+				return err
+			}
+
+			var key []byte
+			for _, x := range vec {
+				key = binary.LittleEndian.AppendUint64(key, math.Float64bits(x))
+			}
+			if err := txn.Set(key, []byte(text)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s := openStore(t, opts)
+	results, err := s.Nearest(ctx, "blue sky", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(texts) || results[0].Text != "blue sky" {
+		t.Errorf("after migrating, Nearest = %q", resultTexts(results))
+	}
+	for _, r := range results {
+		if r.ID < 1 || r.ID > uint64(len(texts)) {
+			t.Errorf("migrated entry %q has ID %d", r.Text, r.ID)
+		}
+	}
+
+	if ids := insertAll(t, s, "new"); ids[0] != uint64(len(texts)+1) {
+		t.Errorf("the first ID after migrating is %d, want %d", ids[0], len(texts)+1)
+	}
+}
+
+// blockingEmbedder is a HashEmbedder that signals started and waits for
+// release before embedding.
+type blockingEmbedder struct {
+	*vectortest.HashEmbedder
+	started, release chan struct{}
+}
+
+func (e blockingEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	close(e.started)
+	<-e.release
+
+	return e.HashEmbedder.Embed(ctx, text)
+}
+
+func TestShutdown(t *testing.T) {
+	ctx := context.Background()
+	goroutines := runtime.NumGoroutine()
+
+	e := blockingEmbedder{vectortest.NewHashEmbedder(testDim), make(chan struct{}), make(chan struct{})}
+	s, err := vectorstore.Open(testOptions(t).WithGCInterval(time.Millisecond), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inserted := make(chan error)
+	go func() {
+		_, err := s.Insert(ctx, "red apples")
+		inserted <- err
+	}()
+	<-e.started
+
+	// Shutdown waits for the insert in flight.
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown during an insert returned %v, want DeadlineExceeded", err)
+	}
+	if _, err := s.Get(ctx, 1); !errors.Is(err, vectorstore.ErrClosed) {
+		t.Errorf("Get after Shutdown returned %v, want ErrClosed", err)
+	}
+
+	close(e.release)
+	if err := <-inserted; err != nil {
+		t.Errorf("the insert in flight during Shutdown failed: %v", err)
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+
+	// The GC loop and Badger's own goroutines have all exited.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("%d goroutines are left running after Shutdown, up from %d", n, goroutines)
+	}
+}
+
+func TestReady(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, testOptions(t))
+	if err := s.Ready(ctx); err != nil {
+		t.Errorf("Ready: %v", err)
+	}
+
+	unloaded := openStoreWith(t, testOptions(t), vectorstore.NewCybertronEmbedder(nil))
+	if err := unloaded.Ready(ctx); !errors.Is(err, vectorstore.ErrNotReady) {
+		t.Errorf("Ready before the model is loaded returned %v, want ErrNotReady", err)
+	}
+
+	broken := openStoreWith(t, testOptions(t), misreportingEmbedder{vectortest.NewHashEmbedder(testDim)})
+	if err := broken.Ready(ctx); !errors.Is(err, vectorstore.ErrNotReady) {
+		t.Errorf("Ready with an embedder of the wrong dimension returned %v, want ErrNotReady", err)
+	}
+
+	s.Close()
+	if err := s.Ready(ctx); !errors.Is(err, vectorstore.ErrNotReady) {
+		t.Errorf("Ready after Close returned %v, want ErrNotReady", err)
+	}
+}
+
+// misreportingEmbedder advertises half the dimension of its vectors.
+type misreportingEmbedder struct {
+	*vectortest.HashEmbedder
+}
+
+func (e misreportingEmbedder) Dim() int { return e.HashEmbedder.Dim() / 2 }
+
+// unknownDim is an Embedder that doesn't report its dimension until it
+// embeds a text.
+type unknownDim struct {
+	*vectortest.HashEmbedder
+}
+
+func (unknownDim) Dim() int {
+	return 0
+}
+
+func TestDimensionMismatch(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions(t).WithModelName("model-a")
+
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(768))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, "red apples")
+	s.Close()
+
+	if _, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(384)); !errors.Is(err, vectorstore.ErrDimensionMismatch) {
+		t.Errorf("Open of a 768 dimension index with a 384 dimension embedder returned %v, want ErrDimensionMismatch", err)
+	}
+
+	s, err = vectorstore.Open(opts, unknownDim{vectortest.NewHashEmbedder(384)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Insert(ctx, "blue sky"); !errors.Is(err, vectorstore.ErrDimensionMismatch) {
+		t.Errorf("inserting a 384 dimension embedding into a 768 dimension index returned %v, want ErrDimensionMismatch", err)
+	}
+	s.Close()
+
+	if _, err := vectorstore.Open(opts.WithModelName("model-b"), vectortest.NewHashEmbedder(768)); !errors.Is(err, vectorstore.ErrModelMismatch) {
+		t.Errorf("Open with another model returned %v, want ErrModelMismatch", err)
+	}
+}
+
+// shortOnceEmbedder is a HashEmbedder whose first embedding is a
+// dimension short.
+type shortOnceEmbedder struct {
+	*vectortest.HashEmbedder
+	done *atomic.Bool
+}
+
+func (e shortOnceEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	vec, err := e.HashEmbedder.Embed(ctx, text)
+	if err == nil && e.done.CompareAndSwap(false, true) {
+		vec = vec[:len(vec)-1]
+	}
+
+	return vec, err
+}
+
+func TestWrongLengthEmbedding(t *testing.T) {
+	ctx := context.Background()
+	s := openStoreWith(t, memOptions(), shortOnceEmbedder{vectortest.NewHashEmbedder(testDim), &atomic.Bool{}})
+
+	if _, err := s.Insert(ctx, "red apples"); !errors.Is(err, vectorstore.ErrDimensionMismatch) {
+		t.Errorf("Insert of a short embedding returned %v, want ErrDimensionMismatch", err)
+	}
+	if n, err := s.Count(ctx); err != nil || n != 0 {
+		t.Errorf("after the refused insert, Count = %d, %v, want 0", n, err)
+	}
+
+	insertAll(t, s, "red apples")
+	results, err := s.Nearest(ctx, "red apples", 1)
+	if err != nil || len(results) != 1 || results[0].Score < 0.999 {
+		t.Errorf("after a good insert, Nearest = %+v, %v", results, err)
+	}
+}
+
+func TestOpenNilEmbedder(t *testing.T) {
+	if _, err := vectorstore.Open(memOptions(), nil); err == nil {
+		t.Error("Open without an embedder succeeded")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	opts := vectorstore.DefaultOptions(t.TempDir()).WithGCInterval(0)
+
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := insertAll(t, s, "red apples", "blue sky")
+	s.Close()
+
+	ro := openStore(t, opts.WithReadOnly(true))
+	results, err := ro.Nearest(ctx, "blue sky", 1)
+	if err != nil || len(results) != 1 || results[0].ID != ids[1] {
+		t.Errorf("read-only Nearest = %v, %v", resultIDs(results), err)
+	}
+
+	if _, err := ro.Insert(ctx, "more"); !errors.Is(err, vectorstore.ErrReadOnly) {
+		t.Errorf("read-only Insert returned %v, want ErrReadOnly", err)
+	}
+	if err := ro.Delete(ctx, ids[0]); !errors.Is(err, vectorstore.ErrReadOnly) {
+		t.Errorf("read-only Delete returned %v, want ErrReadOnly", err)
+	}
+	if err := ro.Update(ctx, ids[0], "changed"); !errors.Is(err, vectorstore.ErrReadOnly) {
+		t.Errorf("read-only Update returned %v, want ErrReadOnly", err)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("0123456789abcdef")
+	opts := vectorstore.DefaultOptions(t.TempDir()).WithGCInterval(0).WithEncryptionKey(key).WithIndexCacheSize(1 << 20)
+
+	if _, err := vectorstore.Open(opts.WithIndexCacheSize(0), vectortest.NewHashEmbedder(testDim)); err == nil {
+		t.Error("Open with a key and no index cache succeeded")
+	}
+
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, "secret text")
+	s.Close()
+
+	if _, err := vectorstore.Open(opts.WithEncryptionKey([]byte("fedcba9876543210")), vectortest.NewHashEmbedder(testDim)); err == nil {
+		t.Error("Open with the wrong key succeeded")
+	}
+
+	s = openStore(t, opts)
+	if doc, err := s.Get(ctx, 1); err != nil || doc.Text != "secret text" {
+		t.Errorf("Get with the right key = %+v, %v", doc, err)
+	}
+}
+func TestCollections(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+
+	a, err := s.Collection("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Collection("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insertAll(t, s, "in the default collection")
+	idA := insertAll(t, a, "red apples")[0]
+	idB := insertAll(t, b, "blue sky", "green grass")[0]
+	if idA != 1 || idB != 1 {
+		t.Errorf("first IDs are %d and %d, want 1 in each collection", idA, idB)
+	}
+
+	for name, want := range map[*vectorstore.VectorStore]int{s: 1, a: 1, b: 2} {
+		if n, _ := name.Count(ctx); n != want {
+			t.Errorf("a collection counts %d documents, want %d", n, want)
+		}
+	}
+
+	results, err := a.Nearest(ctx, "blue sky", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Text != "red apples" {
+		t.Errorf("collection a searched %+v, want its own document alone", results)
+	}
+
+	for _, name := range []string{"", "a/b"} {
+		if _, err := s.Collection(name); err == nil {
+			t.Errorf("Collection(%q) succeeded", name)
+		}
+	}
+}
+
+func TestRunGC(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, testOptions(t).WithGCInterval(time.Millisecond))
+
+	ids := insertAll(t, s, numbered(100, 5)...)
+	for _, id := range ids[:90] {
+		if err := s.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.RunGC(); err != nil {
+		t.Errorf("RunGC = %v", err)
+	}
+
+	if _, err := vectorstore.Open(testOptions(t).WithGCDiscardRatio(1), vectortest.NewHashEmbedder(testDim)); err == nil {
+		t.Error("Open with a GC discard ratio of 1 succeeded")
+	}
+}
+
+// benchTexts returns n texts of ten words drawn from a vocabulary of a
+// thousand, the same every run.
+func benchTexts(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	texts := make([]string, n)
+	for i := range texts {
+		words := make([]string, 10)
+		for j := range words {
+			words[j] = fmt.Sprintf("w%d", r.Intn(1000))
+		}
+		texts[i] = strings.Join(words, " ")
+	}
+
+	return texts
+}
+
+// openBench opens a store with opts that embeds texts in 384 dimensions,
+// the default model's, and fills it with n of benchTexts.
+func openBench(b *testing.B, opts vectorstore.Options, n int) *vectorstore.VectorStore {
+	b.Helper()
+
+	s, err := vectorstore.Open(opts, vectortest.NewHashEmbedder(384))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { s.Close() })
+
+	texts := benchTexts(n)
+	for len(texts) > 0 {
+		batch := texts[:min(len(texts), 1000)]
+		if _, err := s.InsertBatch(context.Background(), batch, nil); err != nil {
+			b.Fatal(err)
+		}
+		texts = texts[len(batch):]
+	}
+
+	return s
+}
+
+func BenchmarkNearestDType(b *testing.B) {
+	ctx := context.Background()
+	for _, dtype := range []vectorstore.DType{vectorstore.DTypeFloat64, vectorstore.DTypeFloat32, vectorstore.DTypeInt8} {
+		b.Run(dtype.String(), func(b *testing.B) {
+			s := openBench(b, memOptions().WithDType(dtype), 10000)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := s.Nearest(ctx, "w1 w2 w3", 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkStoreNorms compares cosine scans computing each document's norm
+// with scans reading the norm stored with it.
+func BenchmarkStoreNorms(b *testing.B) {
+	ctx := context.Background()
+	for _, stored := range []bool{false, true} {
+		name := "recomputed"
+		if stored {
+			name = "stored"
+		}
+		b.Run(name, func(b *testing.B) {
+			s := openBench(b, memOptions().WithStoreNorms(stored), 10000)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := s.Nearest(ctx, "w1 w2 w3", 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPrefetch compares scanning a store on disk reading only keys
+// ahead, the default, with reading records ahead in batches of each size.
+func BenchmarkPrefetch(b *testing.B) {
+	ctx := context.Background()
+	s := openBench(b, testOptions(b).WithGCInterval(0), 20000)
+
+	for _, size := range []int{0, 16, 100, 1000} {
+		p := vectorstore.SearchParams{K: 10, PrefetchValues: size > 0, PrefetchSize: size}
+		name := "off"
+		if size > 0 {
+			name = fmt.Sprint(size)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.Search(ctx, "w1 w2 w3", p); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// errStreamClosed is the cause a ResultStream's context is cancelled
+// with by Close, which isn't reported as an error.
+var errStreamClosed = errors.New("stream closed")
+
+// ResultStream is a SearchStream in progress. Read Results until it is
+// closed, then check Err. A caller that stops reading early must Close the
+// stream, or cancel the context it was started with, so that the search
+// releases its transaction; Shutdown stops it too.
+type ResultStream struct {
+	results chan Result
+	cancel  context.CancelCauseFunc
+	done    chan struct{}
+	err     error
+}
+
+// Results returns the channel the results are sent on. It is closed when
+// the search finishes, fails or is stopped.
+func (st *ResultStream) Results() <-chan Result {
+	return st.results
+}
+
+// Err returns the error the search stopped with once Results is closed,
+// such as the context's error if it was cancelled or ErrClosed if the
+// store was shut down. It returns nil while the search is running, if it
+// finished and if Close stopped it.
+func (st *ResultStream) Err() error {
+	select {
+	case <-st.done:
+		return st.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the search, if it is still running, and waits for it to
+// release its transaction. It returns Err.
+func (st *ResultStream) Close() error {
+	st.cancel(errStreamClosed)
+	<-st.done
+
+	return st.err
+}
+
+// SearchStream is Search with the results sent on a ResultStream as they
+// are found. Under a flat index, it scans every stored document and sends
+// a Result each time one ranks among the best p.K seen so far. Results
+// therefore arrive in scan order rather than best first, but every
+// document Search would return is among them. p.K bounds how many are
+// held, not how many are sent.
+//
+// Searches that rank before they fetch, those of an HNSW, IVF or LSH
+// index, of named vectors, hybrid searches and those with a Reranker, send
+// Search's results, best first, once ranked.
+func (s *VectorStore) SearchStream(ctx context.Context, text string, p SearchParams) (*ResultStream, error) {
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	q, err := s.newSearch(ctx, text, p)
+	if err != nil {
+		s.end()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	st := &ResultStream{results: make(chan Result), cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		select {
+		case <-s.closed:
+			cancel(ErrClosed)
+		case <-st.done:
+		}
+	}()
+
+	go func() {
+		defer s.end()
+		defer close(st.results)
+		defer close(st.done)
+		defer s.observeSearch(start)
+		defer cancel(nil)
+
+		err := s.stream(ctx, text, q, p, st.results)
+		if err != nil && ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		if !errors.Is(err, errStreamClosed) {
+			st.err = err
+		}
+	}()
+
+	return st, nil
+}
+
+// streamsFlat reports whether p's results can be sent as the documents are
+// scanned, rather than once they are ranked.
+func (s *VectorStore) streamsFlat(p SearchParams) bool {
+	return s.opts.Index == IndexFlat && p.Vector == "" && p.MultiVector == MultiVectorNone &&
+		p.KeywordWeight == 0 && p.Reranker == nil
+}
+
+func (s *VectorStore) stream(ctx context.Context, text string, q *query, p SearchParams, results chan<- Result) error {
+	if !s.streamsFlat(p) {
+		ranked, err := s.search(ctx, text, q, p)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range ranked {
+			select {
+			case results <- r:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		return nil
+	}
+
+	return s.db.View(func(txn *badger.Txn) error {
+		return s.streamFlat(ctx, txn, q, p, results)
+	})
+}
+
+func (s *VectorStore) streamFlat(ctx context.Context, txn *badger.Txn, q *query, p SearchParams, results chan<- Result) error {
+	best := newTopK(q.metric, q.k)
+
+	it := txn.NewIterator(q.iteratorOptions(s.keys.docPrefix()))
+	defer it.Close()
+
+	n := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		if n++; n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		item := it.Item()
+		id := s.keys.docID(item.Key())
+		if q.skip(id) {
+			continue
+		}
+
+		var rec record
+		var vec storedVector
+		if err := item.Value(func(val []byte) (err error) {
+			vec, err = s.format().decodeDocument(val, &rec)
+			return
+		}); err != nil {
+			return err
+		}
+
+		if q.filter != nil && !q.filter(rec.Metadata) {
+			continue
+		}
+
+		if vec.dim() != len(q.target) {
+			return fmt.Errorf("%w: stored embedding has dimension %d, query has %d", ErrDimensionMismatch, vec.dim(), len(q.target))
+		}
+
+		score := q.score(vec)
+		if p.MinScore != nil && better(q.metric, *p.MinScore, score) {
+			continue
+		}
+		if !best.push(candidate{id: id, score: score}) {
+			continue
+		}
+
+		r := Result{ID: id, Text: rec.Text, Metadata: rec.Metadata, Score: score, Relevance: relevance(q.relevance, score), Embedding: vec.float64s(), Approximate: q.approximate, Parent: rec.Parent, CreatedAt: rec.CreatedAt}
+		if p.Explain {
+			explained := []Result{r}
+			q.explain(explained)
+			r = explained[0]
+		}
+		p.Fields.project(&r)
+
+		select {
+		case results <- r:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package vectorstore_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+	"github.com/richiejp/badger-cybertron-vector/vectorstore/vectortest"
+)
+
+// drain reads st until it is closed and returns what it sent.
+func drain(t *testing.T, st *vectorstore.ResultStream) []vectorstore.Result {
+	t.Helper()
+
+	var results []vectorstore.Result
+	for r := range st.Results() {
+		results = append(results, r)
+	}
+
+	return results
+}
+
+func TestSearchStream(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(50, 5)...)
+
+	p := vectorstore.SearchParams{K: 5}
+	want, err := s.Search(ctx, "topic2 things", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := s.SearchStream(ctx, "topic2 things", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := drain(t, st)
+	if err := st.Err(); err != nil {
+		t.Errorf("Err after a finished stream = %v", err)
+	}
+
+	// Results arrive in scan order and ties may be left out, so compare the
+	// best scores sent with those Search returns.
+	sort.Slice(sent, func(i, j int) bool { return sent[i].Score > sent[j].Score })
+	if len(sent) < len(want) {
+		t.Fatalf("the stream sent %d results, want at least %d", len(sent), len(want))
+	}
+	for i, r := range want {
+		if math.Abs(sent[i].Score-r.Score) > 1e-9 {
+			t.Errorf("the stream's result %d scores %v, Search's %v", i, sent[i].Score, r.Score)
+		}
+	}
+
+	if _, err := s.SearchStream(ctx, "topic2 things", vectorstore.SearchParams{}); err == nil {
+		t.Error("SearchStream with k 0 succeeded")
+	}
+}
+
+func TestSearchStreamCancel(t *testing.T) {
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(50, 5)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	st, err := s.SearchStream(ctx, "topic1 things", vectorstore.SearchParams{K: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-st.Results()
+	cancel()
+
+	// Once cancelled, the scan stops and closes the channel, releasing its
+	// iterator, after at most the send it was blocked on.
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-st.Results():
+			if !ok {
+				if err := st.Err(); !errors.Is(err, context.Canceled) {
+					t.Errorf("Err after a cancel = %v, want context.Canceled", err)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("the stream wasn't closed after its context was cancelled")
+		}
+	}
+}
+
+func TestSearchStreamFilter(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	for i, text := range numbered(20, 2) {
+		parity := "even"
+		if i%2 == 1 {
+			parity = "odd"
+		}
+		if _, err := s.InsertWithMetadata(ctx, text, map[string]string{"parity": parity}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	odd := func(md map[string]string) bool { return md["parity"] == "odd" }
+	st, err := s.SearchStream(ctx, "topic0 things", vectorstore.SearchParams{K: 3, Filter: odd, Fields: vectorstore.FieldMetadata})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := drain(t, st)
+	if len(results) == 0 {
+		t.Fatal("the filtered stream sent nothing")
+	}
+	for _, r := range results {
+		if !odd(r.Metadata) || r.Text != "" {
+			t.Errorf("the stream sent %+v, outside the filter or with its text", r)
+		}
+	}
+}
+
+func TestSearchStreamIndexed(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithIndex(vectorstore.IndexHNSW))
+	insertAll(t, s, numbered(50, 5)...)
+
+	p := vectorstore.SearchParams{K: 5}
+	want, err := s.Search(ctx, "topic3 things", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := s.SearchStream(ctx, "topic3 things", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := drain(t, st); !reflect.DeepEqual(resultIDs(got), resultIDs(want)) {
+		t.Errorf("the indexed stream sent %v, want Search's %v", resultIDs(got), resultIDs(want))
+	}
+}
+
+func TestSearchStreamError(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions().WithCodec(brittleCodec{}))
+	insertAll(t, s, "fine", "corrupt one")
+
+	st, err := s.SearchStream(ctx, "fine", vectorstore.SearchParams{K: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t, st)
+	if st.Err() == nil {
+		t.Error("a stream over a damaged record reported no error")
+	}
+}
+
+func TestSearchStreamClose(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, memOptions())
+	insertAll(t, s, numbered(50, 5)...)
+
+	st, err := s.SearchStream(ctx, "topic1 things", vectorstore.SearchParams{K: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-st.Results()
+	if err := st.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+	drain(t, st)
+	if err := st.Err(); err != nil {
+		t.Errorf("Err after Close = %v", err)
+	}
+}
+
+func TestSearchStreamShutdown(t *testing.T) {
+	ctx := context.Background()
+	s, err := vectorstore.Open(memOptions(), vectortest.NewHashEmbedder(testDim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertAll(t, s, numbered(50, 5)...)
+
+	st, err := s.SearchStream(ctx, "topic1 things", vectorstore.SearchParams{K: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-st.Results()
+
+	// The stream is abandoned without a cancel; Shutdown mustn't wait on it.
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(timeout); err != nil {
+		t.Fatalf("Shutdown with a stream open: %v", err)
+	}
+	drain(t, st)
+	if err := st.Err(); !errors.Is(err, vectorstore.ErrClosed) {
+		t.Errorf("Err after Shutdown = %v, want ErrClosed", err)
+	}
+}
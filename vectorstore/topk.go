@@ -0,0 +1,72 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// topK keeps the best k candidates pushed to it, breaking ties in score by
+// ID, lowest first, so the same candidates always rank the same way. It is
+// a heap with the worst kept candidate at the root, so a better one
+// replaces it in O(log k).
+type topK struct {
+	metric DistanceMetric
+	k      int
+	items  []candidate
+}
+
+func newTopK(metric DistanceMetric, k int) *topK {
+	return &topK{metric: metric, k: k, items: make([]candidate, 0, k)}
+}
+
+func (t *topK) Len() int           { return len(t.items) }
+func (t *topK) Less(i, j int) bool { return ranksAhead(t.metric, t.items[j], t.items[i]) }
+func (t *topK) Swap(i, j int)      { t.items[i], t.items[j] = t.items[j], t.items[i] }
+func (t *topK) Push(x interface{}) { t.items = append(t.items, x.(candidate)) }
+func (t *topK) Pop() interface{} {
+	c := t.items[len(t.items)-1]
+	t.items = t.items[:len(t.items)-1]
+	return c
+}
+
+// ranksAhead reports whether a ranks ahead of b under m.
+func ranksAhead(m DistanceMetric, a, b candidate) bool {
+	if a.score != b.score {
+		return better(m, a.score, b.score)
+	}
+
+	return a.id < b.id
+}
+
+// accepts reports whether a candidate with score could be kept, depending
+// on its ID if it ties with the worst kept candidate.
+func (t *topK) accepts(score float64) bool {
+	return len(t.items) < t.k || !better(t.metric, t.items[0].score, score)
+}
+
+// push offers c, evicting the worst kept candidate if c is better. It
+// reports whether c was kept.
+func (t *topK) push(c candidate) bool {
+	if len(t.items) < t.k {
+		heap.Push(t, c)
+		return true
+	}
+
+	if !ranksAhead(t.metric, c, t.items[0]) {
+		return false
+	}
+
+	t.items[0] = c
+	heap.Fix(t, 0)
+
+	return true
+}
+
+// sorted returns the kept candidates best first.
+func (t *topK) sorted() []candidate {
+	sort.Slice(t.items, func(i, j int) bool {
+		return ranksAhead(t.metric, t.items[i], t.items[j])
+	})
+
+	return t.items
+}
@@ -0,0 +1,93 @@
+package vectorstore
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	tests := []struct {
+		metric DistanceMetric
+		want   []uint64
+	}{
+		{CosineSimilarity{}, []uint64{4, 2, 5}},
+		{EuclideanDistance{}, []uint64{1, 3, 6}},
+	}
+
+	scores := []float64{0.1, 0.8, 0.3, 0.9, 0.7, 0.4}
+	for _, tt := range tests {
+		h := newTopK(tt.metric, 3)
+		for i, score := range scores {
+			h.push(candidate{id: uint64(i + 1), score: score})
+		}
+
+		got := h.sorted()
+		if len(got) != len(tt.want) {
+			t.Fatalf("%T: kept %d candidates, want %d", tt.metric, len(got), len(tt.want))
+		}
+		for i, c := range got {
+			if c.id != tt.want[i] {
+				t.Errorf("%T: sorted = %v, want IDs %v", tt.metric, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTopKTies(t *testing.T) {
+	h := newTopK(CosineSimilarity{}, 2)
+	for _, id := range []uint64{9, 3, 7, 1} {
+		h.push(candidate{id: id, score: 0.5})
+	}
+
+	got := h.sorted()
+	if got[0].id != 1 || got[1].id != 3 {
+		t.Errorf("ties kept %v, want the lowest IDs 1 and 3", got)
+	}
+	if h.accepts(0.4) {
+		t.Error("a full heap accepts a worse score")
+	}
+	if !h.accepts(0.5) || !h.accepts(0.6) {
+		t.Error("a full heap rejects a tying or better score")
+	}
+}
+
+// benchCandidates returns n candidates with random scores.
+func benchCandidates(n int) []candidate {
+	r := rand.New(rand.NewSource(1))
+	cands := make([]candidate, n)
+	for i := range cands {
+		cands[i] = candidate{id: uint64(i), score: r.Float64()}
+	}
+
+	return cands
+}
+
+// BenchmarkTopK compares keeping the best 10 of 100k candidates in a heap
+// with sorting them all and truncating, as the scans did before.
+func BenchmarkTopK(b *testing.B) {
+	cands := benchCandidates(100000)
+	metric := CosineSimilarity{}
+
+	b.Run("heap", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h := newTopK(metric, 10)
+			for _, c := range cands {
+				h.push(c)
+			}
+			h.sorted()
+		}
+	})
+
+	b.Run("sort", func(b *testing.B) {
+		ranked := make([]candidate, len(cands))
+		for i := 0; i < b.N; i++ {
+			ranked = append(ranked[:0], cands...)
+			sort.Slice(ranked, func(i, j int) bool {
+				return better(metric, ranked[i].score, ranked[j].score)
+			})
+			_ = ranked[:10]
+		}
+	})
+}
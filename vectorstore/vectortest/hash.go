@@ -0,0 +1,49 @@
+// Package vectortest provides helpers for exercising a VectorStore without
+// loading a model.
+package vectortest
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/richiejp/badger-cybertron-vector/vectorstore"
+)
+
+// HashEmbedder is a vectorstore.Embedder that hashes each lower cased word
+// of a text into one of Dim buckets, the feature hashing trick. Texts that
+// share words get similar vectors, and the same text always gets the same
+// vector, which is enough for testing ranking without a model. Any text
+// with a word gets a non-zero vector.
+type HashEmbedder struct {
+	dim int
+}
+
+var _ vectorstore.Embedder = (*HashEmbedder)(nil)
+
+func NewHashEmbedder(dim int) *HashEmbedder {
+	return &HashEmbedder{dim: dim}
+}
+
+func (h *HashEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	vec := make([]float64, h.dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, w := range words {
+		f := fnv.New64a()
+		f.Write([]byte(w))
+		vec[f.Sum64()%uint64(h.dim)]++
+	}
+
+	return vec, nil
+}
+
+func (h *HashEmbedder) Dim() int {
+	return h.dim
+}
@@ -0,0 +1,47 @@
+package vectortest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	ctx := context.Background()
+	h := NewHashEmbedder(8)
+
+	a, err := h.Embed(ctx, "Red apples, red!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != h.Dim() {
+		t.Fatalf("embedding has %d dimensions, want %d", len(a), h.Dim())
+	}
+
+	b, _ := h.Embed(ctx, "red APPLES red")
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("texts with the same words embed differently: %v, %v", a, b)
+	}
+
+	sum := 0.0
+	for _, x := range a {
+		sum += x
+	}
+	if sum != 3 {
+		t.Errorf("embedding counts %v words, want 3", sum)
+	}
+
+	empty, _ := h.Embed(ctx, " ,. ")
+	for _, x := range empty {
+		if x != 0 {
+			t.Errorf("text without words embeds to %v, want zeros", empty)
+			break
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := h.Embed(cancelled, "red"); err == nil {
+		t.Error("Embed with a cancelled context succeeded")
+	}
+}
@@ -0,0 +1,119 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// VerifyFailure is a document record that Verify found to be damaged.
+type VerifyFailure struct {
+	ID  uint64
+	Err error
+}
+
+// VerifyError is returned by Verify when some document records are damaged.
+type VerifyError struct {
+	Failed []VerifyFailure
+}
+
+func (e *VerifyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d documents are damaged", len(e.Failed))
+	for i, f := range e.Failed {
+		if i == 3 {
+			fmt.Fprintf(&b, "; and %d more", len(e.Failed)-i)
+			break
+		}
+		fmt.Fprintf(&b, "; document %d: %v", f.ID, f.Err)
+	}
+
+	return b.String()
+}
+
+func (e *VerifyError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Err
+	}
+
+	return errs
+}
+
+// Verify decodes every document record and checks that its embedding has
+// the index's dimension, for instance after a crash. It returns a
+// *VerifyError listing the records that are truncated, corrupt or of the
+// wrong dimension, whose errors wrap ErrCorruptRecord or
+// ErrDimensionMismatch. Options.VerifyOnOpen runs it from Open.
+func (s *VectorStore) Verify(ctx context.Context) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	defer s.end()
+
+	return s.verify(ctx)
+}
+
+func (s *VectorStore) verify(ctx context.Context) error {
+	var failed []VerifyFailure
+
+	if err := s.db.View(func(txn *badger.Txn) error {
+		dim, err := storedDim(txn, s.keys)
+		if err != nil {
+			return err
+		}
+
+		prefix := s.keys.docPrefix()
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		n := 0
+		for it.Rewind(); it.Valid(); it.Next() {
+			if n++; n%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			item := it.Item()
+			if len(item.Key()) != len(prefix)+8 {
+				failed = append(failed, VerifyFailure{Err: fmt.Errorf("%w: malformed key %q", ErrCorruptRecord, item.Key())})
+				continue
+			}
+			id := s.keys.docID(item.Key())
+
+			var vec storedVector
+			if err := item.Value(func(val []byte) (err error) {
+				vec, err = s.format().decodeDocument(val, &record{})
+				return
+			}); err != nil {
+				if !errors.Is(err, ErrCorruptRecord) {
+					err = fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+				}
+				failed = append(failed, VerifyFailure{ID: id, Err: err})
+				continue
+			}
+
+			if dim == 0 {
+				dim = vec.dim()
+			} else if vec.dim() != dim {
+				failed = append(failed, VerifyFailure{ID: id, Err: fmt.Errorf("%w: embedding has dimension %d, index has %d", ErrDimensionMismatch, vec.dim(), dim)})
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return &VerifyError{Failed: failed}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// writeRaw stores vals under the document keys 1, 2, 3 and so on, as if
+// they had been written by a crashed process.
+func writeRaw(t *testing.T, s *VectorStore, vals ...[]byte) {
+	t.Helper()
+
+	if err := s.db.Update(func(txn *badger.Txn) error {
+		for i, val := range vals {
+			if err := txn.Set(s.keys.doc(uint64(i+1)), val); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	opts := DefaultOptions(t.TempDir()).WithGCInterval(0)
+
+	s, err := Open(opts, NewCybertronEmbedder(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := encodeRecord(record{Text: "fine", Embedding: []float64{1, 0, 0, 0}}, DTypeFloat64, false)
+	truncated := good[:len(good)-3]
+	short := encodeRecord(record{Text: "short", Embedding: []float64{1, 0, 0}}, DTypeFloat64, false)
+	writeRaw(t, s, good, truncated, short, good)
+
+	err = s.Verify(ctx)
+	var ve *VerifyError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Verify returned %v, want a VerifyError", err)
+	}
+	if len(ve.Failed) != 2 || ve.Failed[0].ID != 2 || ve.Failed[1].ID != 3 {
+		t.Fatalf("failures = %+v, want documents 2 and 3", ve.Failed)
+	}
+	if !errors.Is(ve.Failed[0].Err, ErrCorruptRecord) {
+		t.Errorf("the truncated record failed with %v, want ErrCorruptRecord", ve.Failed[0].Err)
+	}
+	if !errors.Is(ve.Failed[1].Err, ErrDimensionMismatch) {
+		t.Errorf("the short embedding failed with %v, want ErrDimensionMismatch", ve.Failed[1].Err)
+	}
+	s.Close()
+
+	if _, err := Open(opts.WithVerifyOnOpen(true), NewCybertronEmbedder(nil)); !errors.Is(err, ErrCorruptRecord) {
+		t.Errorf("Open with VerifyOnOpen returned %v, want ErrCorruptRecord", err)
+	}
+
+	clean, err := Open(DefaultOptions("").WithInMemory(true), NewCybertronEmbedder(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean.Close()
+	writeRaw(t, clean, good, good)
+	if err := clean.Verify(ctx); err != nil {
+		t.Errorf("Verify of a clean store: %v", err)
+	}
+}